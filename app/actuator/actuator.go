@@ -1,15 +1,33 @@
 package actuator
 
 import (
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/tagset"
 )
 
 // status constants matching Spring Boot Actuator format
 const (
-	StatusUp   = "UP"
-	StatusDown = "DOWN"
+	StatusUp           = "UP"
+	StatusDown         = "DOWN"
+	StatusWarn         = "WARN"           // custom status, component works but crossed the warning level
+	StatusUnknown      = "UNKNOWN"        // component state can't be determined
+	StatusOutOfService = "OUT_OF_SERVICE" // component is taken out of service intentionally
 )
 
+// DefaultStatusOrder lists statuses from the most to the least severe, same as Spring Boot with WARN added before UP
+var DefaultStatusOrder = []string{StatusDown, StatusOutOfService, StatusWarn, StatusUp, StatusUnknown}
+
+// DefaultHTTPMapping defines http codes of statuses, the rest of statuses are reported with 200
+var DefaultHTTPMapping = map[string]int{StatusDown: http.StatusServiceUnavailable, StatusOutOfService: http.StatusServiceUnavailable}
+
 // HealthResponse represents Spring Boot Actuator compatible health response
 type HealthResponse struct {
 	Status     string               `json:"status"`
@@ -20,95 +38,319 @@ type HealthResponse struct {
 type Component struct {
 	Status  string         `json:"status"`
 	Details map[string]any `json:"details,omitempty"`
+	Tags    []string       `json:"-"` // tags of the service or volume, with labels as "name:value", used by groups
+}
+
+// DefaultDown is the default down level for cpu, memory and disk usage, percent
+const DefaultDown = 90
+
+// Thresholds defines levels used to determine the health of cpu, memory, disk and load average components.
+// Zero down levels of cpu, memory and disk are replaced by the default of 90%, zero warn levels are not checked.
+type Thresholds struct {
+	CPU         Level
+	Memory      Level
+	Disk        Level            // default for all volumes
+	Volumes     map[string]Level // per-volume overrides of Disk, keyed by volume name
+	LoadPerCore Level            // load average per cpu core, zero level keeps load average informational
+}
+
+// Level defines warn and down levels for a value, zero level is not checked
+type Level struct {
+	Warn float64
+	Down float64
+}
+
+// status returns DOWN if value reached down level, WARN if value reached warn level, UP otherwise
+func (l Level) status(v float64) string {
+	switch {
+	case l.Down > 0 && v >= l.Down:
+		return StatusDown
+	case l.Warn > 0 && v >= l.Warn:
+		return StatusWarn
+	default:
+		return StatusUp
+	}
 }
 
-// thresholds for determining component health
-const threshold = 90
+// withDefault returns level with zero fields replaced by fields of the default level
+func (l Level) withDefault(def Level) Level {
+	if l.Warn == 0 {
+		l.Warn = def.Warn
+	}
+	if l.Down == 0 {
+		l.Down = def.Down
+	}
+	return l
+}
 
 // FromStatusInfo converts status.Info to actuator HealthResponse
-func FromStatusInfo(info *status.Info) *HealthResponse {
+func FromStatusInfo(info *status.Info, th Thresholds) *HealthResponse {
 	if info == nil {
 		return nil
 	}
 
+	th.CPU = th.CPU.withDefault(Level{Down: DefaultDown})
+	th.Memory = th.Memory.withDefault(Level{Down: DefaultDown})
+	th.Disk = th.Disk.withDefault(Level{Down: DefaultDown})
+
 	resp := &HealthResponse{
 		Status:     StatusUp,
 		Components: make(map[string]Component),
 	}
 
 	// cpu component
-	cpuStatus := StatusUp
-	if info.CPUPercent >= threshold {
-		cpuStatus = StatusDown
-	}
 	resp.Components["cpu"] = Component{
-		Status:  cpuStatus,
+		Status:  th.CPU.status(float64(info.CPUPercent)),
 		Details: map[string]any{"percent": info.CPUPercent},
 	}
 
 	// memory component
-	memStatus := StatusUp
-	if info.MemPercent >= threshold {
-		memStatus = StatusDown
-	}
 	resp.Components["memory"] = Component{
-		Status:  memStatus,
+		Status:  th.Memory.status(float64(info.MemPercent)),
 		Details: map[string]any{"percent": info.MemPercent},
 	}
 
-	// disk components
+	// disk components, per-volume levels override the default disk levels
 	for name, vol := range info.Volumes {
-		diskStatus := StatusUp
-		if vol.UsagePercent >= threshold {
-			diskStatus = StatusDown
-		}
 		resp.Components["diskSpace:"+name] = Component{
-			Status: diskStatus,
+			Status: th.Volumes[name].withDefault(th.Disk).status(float64(vol.UsagePercent)),
 			Details: map[string]any{
 				"path":    vol.Path,
 				"percent": vol.UsagePercent,
 			},
+			Tags: tagset.Join(vol.Tags, vol.Labels),
 		}
 	}
 
 	// external service components - prefixed with "service:" to avoid collision with reserved keys
 	for name, svc := range info.ExtServices {
-		svcStatus := StatusUp
+		resp.Components["service:"+name] = ServiceComponent(svc)
+	}
+
+	// load average component, checks 5 minutes load average per cpu core.
+	// informational and always UP if levels are not set or number of cores is unknown
+	loadStatus := StatusUp
+	loadDetails := map[string]any{
+		"one":     info.Loads.One,
+		"five":    info.Loads.Five,
+		"fifteen": info.Loads.Fifteen,
+	}
+	if info.CPUCores > 0 {
+		perCore := info.Loads.Five / float64(info.CPUCores)
+		loadStatus = th.LoadPerCore.status(perCore)
+		loadDetails["cores"] = info.CPUCores
+		loadDetails["per_core"] = perCore
+	}
+	resp.Components["loadAverage"] = Component{Status: loadStatus, Details: loadDetails}
+
+	resp.Status = overallStatus(resp.Components)
+	return resp
+}
+
+// ServiceComponent makes health component of the service response. Status is the health verdict made by provider,
+// status code is used for responses without verdict
+func ServiceComponent(svc external.Response) Component {
+	svcStatus := svc.Health.Status
+	if svcStatus == "" {
+		svcStatus = StatusUp
 		if svc.StatusCode < 200 || svc.StatusCode >= 300 {
 			svcStatus = StatusDown
 		}
-		details := map[string]any{
-			"status_code":   svc.StatusCode,
-			"response_time": svc.ResponseTime,
+	}
+	details := map[string]any{
+		"status_code":   svc.StatusCode,
+		"response_time": svc.ResponseTime,
+	}
+	if svc.Health.Reason != "" {
+		details["reason"] = svc.Health.Reason
+	}
+	if svc.Error != "" {
+		details["error"] = svc.Error
+		details["error_category"] = svc.ErrorCategory
+	}
+	if svc.Body != nil {
+		details["body"] = svc.Body
+	}
+	return Component{Status: svcStatus, Details: details, Tags: tagset.Join(svc.Tags, svc.Labels)}
+}
+
+// MarkFlapping adds "flapping" detail to flapping components, keeping their status.
+// flapping is a set of component names, as reported by status.History
+func (h *HealthResponse) MarkFlapping(flapping map[string]bool) {
+	for name, comp := range h.Components {
+		if !flapping[name] {
+			continue
 		}
-		if svc.Body != nil {
-			details["body"] = svc.Body
+		if comp.Status == StatusOutOfService || comp.Status == StatusUnknown {
+			continue // reported as is, i.e. out of service during maintenance
 		}
-		resp.Components["service:"+name] = Component{
-			Status:  svcStatus,
-			Details: details,
+		if comp.Details == nil {
+			comp.Details = map[string]any{}
 		}
+		comp.Details["flapping"] = true
+		h.Components[name] = comp
 	}
+}
 
-	// load average component (informational, always UP)
-	resp.Components["loadAverage"] = Component{
-		Status: StatusUp,
-		Details: map[string]any{
-			"one":     info.Loads.One,
-			"five":    info.Loads.Five,
-			"fifteen": info.Loads.Fifteen,
-		},
+// IsFlapping checks if the component is marked as flapping
+func (c Component) IsFlapping() bool {
+	flapping, _ := c.Details["flapping"].(bool)
+	return flapping
+}
+
+// MarkMaintenance reports components in maintenance as OUT_OF_SERVICE, with the name of the window and the actual status
+// in details, and updates the overall status. window returns the name of active maintenance window of the component.
+func (h *HealthResponse) MarkMaintenance(window func(name string, tags []string) (string, bool)) {
+	for name, comp := range h.Components {
+		w, ok := window(name, comp.Tags)
+		if !ok {
+			continue
+		}
+		details := map[string]any{"maintenance": w, "actual_status": comp.Status}
+		maps.Copy(details, comp.Details)
+		comp.Status, comp.Details = StatusOutOfService, details
+		h.Components[name] = comp
 	}
+	h.Status = overallStatus(h.Components)
+}
 
-	// determine overall status - DOWN if any component is DOWN
-	for _, comp := range resp.Components {
-		if comp.Status == StatusDown {
-			resp.Status = StatusDown
-			break
+// Records makes history records of all health components. System components are recorded at the given time,
+// services at the time of their last check. Value of the record is the main numeric detail of the component.
+func Records(info *status.Info, th Thresholds, now time.Time) map[string]status.Record {
+	health := FromStatusInfo(info, th)
+	if health == nil {
+		return nil
+	}
+	res := make(map[string]status.Record, len(health.Components))
+	for name, comp := range health.Components {
+		rec := status.Record{Time: now, Status: comp.Status}
+		rec.Reason, _ = comp.Details["reason"].(string)
+		for _, key := range []string{"percent", "per_core", "response_time"} {
+			if v, ok := toFloat(comp.Details[key]); ok {
+				rec.Value = v
+				break
+			}
+		}
+		if svcName, ok := strings.CutPrefix(name, "service:"); ok {
+			rec.Time = info.ExtServices[svcName].CheckedAt
 		}
+		res[name] = rec
 	}
+	return res
+}
 
-	return resp
+// show-details modes of health, same as management.endpoint.health.show-details of Spring Boot
+const (
+	ShowDetailsNever          = "never"           // overall status only
+	ShowDetailsWhenAuthorized = "when-authorized" // components with details for authenticated requests only
+	ShowDetailsAlways         = "always"          // components with details, default
+)
+
+// HealthOptions defines details reported by health, groups of components, aggregation of statuses and their http codes
+type HealthOptions struct {
+	ShowDetails string              // one of ShowDetails* modes, empty is "always"
+	Groups      map[string][]string // components by group name, i.e. liveness, as names or glob patterns like "service:*"
+	StatusOrder []string            // statuses from the most to the least severe, DefaultStatusOrder if empty
+	HTTPMapping map[string]int      // http codes by status, override DefaultHTTPMapping
+}
+
+// Aggregate sets the overall status of health to the most severe status of components, by StatusOrder.
+// Statuses missing in the order are less severe than any listed one.
+func (o HealthOptions) Aggregate(h *HealthResponse) {
+	h.Status = aggregate(h.Components, o.StatusOrder)
+}
+
+// HTTPStatus returns http code for the status, by HTTPMapping, DefaultHTTPMapping or 200
+func (o HealthOptions) HTTPStatus(status string) int {
+	if code, ok := o.HTTPMapping[status]; ok {
+		return code
+	}
+	if code, ok := DefaultHTTPMapping[status]; ok {
+		return code
+	}
+	return http.StatusOK
+}
+
+// Details returns true if components with details should be reported, for authenticated request or not
+func (o HealthOptions) Details(authorized bool) bool {
+	switch o.ShowDetails {
+	case ShowDetailsNever:
+		return false
+	case ShowDetailsWhenAuthorized:
+		return authorized
+	default:
+		return true
+	}
+}
+
+// Group returns health of the components matching any of members, with the overall status of these components
+// aggregated by the order of statuses, DefaultStatusOrder if empty. Members are component names or glob patterns, see path.Match,
+// or "tag:" prefixed tags and labels of services and volumes, i.e. "tag:payments" or "tag:team:billing".
+func (h *HealthResponse) Group(members, order []string) *HealthResponse {
+	res := &HealthResponse{Components: map[string]Component{}}
+	for name, comp := range h.Components {
+		if slices.ContainsFunc(members, func(m string) bool { return Match(m, name, comp.Tags) }) {
+			res.Components[name] = comp
+		}
+	}
+	res.Status = aggregate(res.Components, order)
+	return res
+}
+
+// Match returns true if the component with the name and tags matches the pattern, a component name or glob pattern,
+// or "tag:" prefixed tag pattern, i.e. "service:*" or "tag:payments"
+func Match(pattern, name string, tags []string) bool {
+	if tag, ok := strings.CutPrefix(pattern, "tag:"); ok {
+		return slices.ContainsFunc(tags, func(t string) bool { ok, err := path.Match(tag, t); return err == nil && ok })
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// Summary returns health with the overall status only, without components
+func (h *HealthResponse) Summary() *HealthResponse {
+	return &HealthResponse{Status: h.Status}
+}
+
+// overallStatus is the most severe status of components by DefaultStatusOrder
+func overallStatus(components map[string]Component) string {
+	return aggregate(components, DefaultStatusOrder)
+}
+
+// aggregate returns the most severe status of components by the order, DefaultStatusOrder if empty.
+// Statuses missing in the order are less severe than any listed one. No components are UP.
+func aggregate(components map[string]Component, order []string) string {
+	if len(order) == 0 {
+		order = DefaultStatusOrder
+	}
+	res, resIdx := "", -1
+	for _, comp := range components {
+		idx := slices.Index(order, comp.Status)
+		if idx < 0 {
+			idx = len(order)
+		}
+		if resIdx < 0 || idx < resIdx || (idx == resIdx && comp.Status < res) {
+			res, resIdx = comp.Status, idx
+		}
+	}
+	if res == "" {
+		return StatusUp
+	}
+	return res
+}
+
+// toFloat converts numeric detail value to float64
+func toFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case float64:
+		return val, true
+	default:
+		return 0, false
+	}
 }
 
 // DiscoveryResponse represents the actuator discovery endpoint response with links to available endpoints
@@ -116,17 +358,23 @@ type DiscoveryResponse struct {
 	Links map[string]Link `json:"_links"`
 }
 
-// Link represents a single HAL-style link
+// Link represents a single HAL-style link, templated links have path variables like {name}
 type Link struct {
-	Href string `json:"href"`
+	Href      string `json:"href"`
+	Templated bool   `json:"templated"`
 }
 
 // Discovery returns the actuator discovery response listing available endpoints
 func Discovery() *DiscoveryResponse {
 	return &DiscoveryResponse{
 		Links: map[string]Link{
-			"self":   {Href: "/actuator"},
-			"health": {Href: "/actuator/health"},
+			"self":                       {Href: "/actuator"},
+			"health":                     {Href: "/actuator/health"},
+			"health-path":                {Href: "/actuator/health/{*path}", Templated: true},
+			"info":                       {Href: "/actuator/info"},
+			"metrics":                    {Href: "/actuator/metrics"},
+			"metrics-requiredMetricName": {Href: "/actuator/metrics/{requiredMetricName}", Templated: true},
+			"prometheus":                 {Href: "/metrics"},
 		},
 	}
 }
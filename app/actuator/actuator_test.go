@@ -1,7 +1,11 @@
 package actuator
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -112,7 +116,7 @@ func TestFromStatusInfo(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result := FromStatusInfo(tc.info)
+			result := FromStatusInfo(tc.info, Thresholds{})
 
 			if tc.wantNil {
 				assert.Nil(t, result)
@@ -143,7 +147,7 @@ func TestFromStatusInfo_ComponentDetails(t *testing.T) {
 		}{One: 1.5, Five: 1.2, Fifteen: 1.0},
 	}
 
-	result := FromStatusInfo(info)
+	result := FromStatusInfo(info, Thresholds{})
 	require.NotNil(t, result)
 	require.NotNil(t, result.Components)
 
@@ -206,7 +210,7 @@ func TestFromStatusInfo_ComponentStatusDown(t *testing.T) {
 		}{One: 10.0, Five: 8.0, Fifteen: 6.0},
 	}
 
-	result := FromStatusInfo(info)
+	result := FromStatusInfo(info, Thresholds{})
 	require.NotNil(t, result)
 	assert.Equal(t, StatusDown, result.Status)
 
@@ -220,6 +224,307 @@ func TestFromStatusInfo_ComponentStatusDown(t *testing.T) {
 	assert.Equal(t, StatusUp, result.Components["loadAverage"].Status)
 }
 
+func TestFromStatusInfo_Thresholds(t *testing.T) {
+	th := Thresholds{
+		CPU:         Level{Warn: 70, Down: 95},
+		Memory:      Level{Warn: 80},
+		Disk:        Level{Warn: 75, Down: 85},
+		Volumes:     map[string]Level{"archive": {Warn: 97, Down: 99}, "logs": {Warn: 50}},
+		LoadPerCore: Level{Warn: 1, Down: 2},
+	}
+
+	tests := []struct {
+		name      string
+		info      *status.Info
+		component string
+		want      string
+	}{
+		{"cpu below warn", &status.Info{CPUPercent: 69}, "cpu", StatusUp},
+		{"cpu at warn", &status.Info{CPUPercent: 70}, "cpu", StatusWarn},
+		{"cpu above default down but below custom down", &status.Info{CPUPercent: 92}, "cpu", StatusWarn},
+		{"cpu at down", &status.Info{CPUPercent: 95}, "cpu", StatusDown},
+		{"memory warn", &status.Info{MemPercent: 85}, "memory", StatusWarn},
+		{"memory uses default down", &status.Info{MemPercent: 90}, "memory", StatusDown},
+		{"disk uses default levels", &status.Info{Volumes: map[string]status.Volume{"root": {UsagePercent: 80}}},
+			"diskSpace:root", StatusWarn},
+		{"disk above default down", &status.Info{Volumes: map[string]status.Volume{"root": {UsagePercent: 85}}},
+			"diskSpace:root", StatusDown},
+		{"volume override keeps full volume up", &status.Info{Volumes: map[string]status.Volume{"archive": {UsagePercent: 95}}},
+			"diskSpace:archive", StatusUp},
+		{"volume override warn", &status.Info{Volumes: map[string]status.Volume{"archive": {UsagePercent: 98}}},
+			"diskSpace:archive", StatusWarn},
+		{"volume override down", &status.Info{Volumes: map[string]status.Volume{"archive": {UsagePercent: 99}}},
+			"diskSpace:archive", StatusDown},
+		{"partial volume override falls back to disk down", &status.Info{Volumes: map[string]status.Volume{"logs": {UsagePercent: 86}}},
+			"diskSpace:logs", StatusDown},
+		{"load per core below warn", loadInfo(3.9, 4), "loadAverage", StatusUp},
+		{"load per core warn", loadInfo(4, 4), "loadAverage", StatusWarn},
+		{"load per core down", loadInfo(8, 4), "loadAverage", StatusDown},
+		{"load without cores is informational", loadInfo(100, 0), "loadAverage", StatusUp},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := FromStatusInfo(tc.info, th)
+			require.NotNil(t, result)
+			comp, ok := result.Components[tc.component]
+			require.True(t, ok, "component %s should exist", tc.component)
+			assert.Equal(t, tc.want, comp.Status)
+			assert.Equal(t, tc.want, result.Status, "overall status follows the only non-UP component")
+		})
+	}
+
+	t.Run("load average details with cores", func(t *testing.T) {
+		result := FromStatusInfo(loadInfo(6, 4), th)
+		require.NotNil(t, result)
+		assert.Equal(t, 4, result.Components["loadAverage"].Details["cores"])
+		assert.InDelta(t, 1.5, result.Components["loadAverage"].Details["per_core"], 0.001)
+	})
+
+	t.Run("load average without levels is always up", func(t *testing.T) {
+		result := FromStatusInfo(loadInfo(100, 4), Thresholds{})
+		require.NotNil(t, result)
+		assert.Equal(t, StatusUp, result.Components["loadAverage"].Status)
+	})
+
+	t.Run("down has priority over warn in overall status", func(t *testing.T) {
+		result := FromStatusInfo(&status.Info{CPUPercent: 75, MemPercent: 95}, th)
+		require.NotNil(t, result)
+		assert.Equal(t, StatusWarn, result.Components["cpu"].Status)
+		assert.Equal(t, StatusDown, result.Components["memory"].Status)
+		assert.Equal(t, StatusDown, result.Status)
+	})
+}
+
+func TestFromStatusInfo_ProviderHealth(t *testing.T) {
+	info := &status.Info{
+		ExtServices: map[string]external.Response{
+			"docker": {Name: "docker", StatusCode: 200, Body: map[string]any{"required": "failed: nginx"},
+				Health: external.Health{Status: external.HealthDown, Reason: "required containers failed: nginx"}},
+			"cert": {Name: "cert", StatusCode: 200, Body: map[string]any{"status": "expiring soon, in 3 days"},
+				Health: external.Health{Status: external.HealthWarn, Reason: "certificate expiring soon, in 3 days"}},
+			"web":    {Name: "web", StatusCode: 200, Health: external.Health{Status: external.HealthUp}},
+			"legacy": {Name: "legacy", StatusCode: 503}, // no verdict, status code used
+			"mongo": {Name: "mongo", StatusCode: 500, Error: "mongo connect failed: connection refused",
+				ErrorCategory: external.ErrorConnect, Health: external.Health{Status: external.HealthDown, Reason: "mongo connect failed"}},
+		},
+	}
+
+	result := FromStatusInfo(info, Thresholds{})
+	require.NotNil(t, result)
+	assert.Equal(t, StatusDown, result.Status)
+
+	docker := result.Components["service:docker"]
+	assert.Equal(t, StatusDown, docker.Status, "DOWN verdict with 200 status code")
+	assert.Equal(t, "required containers failed: nginx", docker.Details["reason"])
+
+	cert := result.Components["service:cert"]
+	assert.Equal(t, StatusWarn, cert.Status)
+	assert.Equal(t, "certificate expiring soon, in 3 days", cert.Details["reason"])
+
+	web := result.Components["service:web"]
+	assert.Equal(t, StatusUp, web.Status)
+	assert.NotContains(t, web.Details, "reason")
+	assert.NotContains(t, web.Details, "error")
+
+	mongo := result.Components["service:mongo"]
+	assert.Equal(t, StatusDown, mongo.Status)
+	assert.Equal(t, "mongo connect failed: connection refused", mongo.Details["error"])
+	assert.Equal(t, "connect", mongo.Details["error_category"])
+
+	assert.Equal(t, StatusDown, result.Components["service:legacy"].Status)
+}
+
+func TestHealthResponse_MarkFlapping(t *testing.T) {
+	info := &status.Info{CPUPercent: 95, ExtServices: map[string]external.Response{
+		"docker": {Name: "docker", StatusCode: 500, Health: external.Health{Status: external.HealthDown}},
+	}}
+
+	health := FromStatusInfo(info, Thresholds{})
+	health.MarkFlapping(map[string]bool{"service:docker": true})
+	assert.Equal(t, StatusDown, health.Components["service:docker"].Status, "status is kept")
+	assert.Equal(t, true, health.Components["service:docker"].Details["flapping"])
+	assert.True(t, health.Components["service:docker"].IsFlapping())
+	assert.Equal(t, StatusDown, health.Status)
+
+	health.MarkFlapping(map[string]bool{"cpu": true})
+	assert.Equal(t, StatusDown, health.Components["cpu"].Status)
+	assert.True(t, health.Components["cpu"].IsFlapping())
+	assert.NotContains(t, health.Components["memory"].Details, "flapping")
+	assert.False(t, health.Components["memory"].IsFlapping())
+
+	health.Components["memory"] = Component{Status: StatusOutOfService}
+	health.MarkFlapping(map[string]bool{"memory": true})
+	assert.Equal(t, Component{Status: StatusOutOfService}, health.Components["memory"], "out of service is reported as is")
+}
+
+func TestHealthResponse_MarkMaintenance(t *testing.T) {
+	info := &status.Info{CPUPercent: 10, ExtServices: map[string]external.Response{
+		"mongo": {Name: "mongo", StatusCode: 500, Tags: []string{"db"},
+			Health: external.Health{Status: external.HealthDown, Reason: "status code 500"}},
+		"web": {Name: "web", StatusCode: 200, Health: external.Health{Status: external.HealthUp}},
+	}}
+	health := FromStatusInfo(info, Thresholds{})
+	require.Equal(t, StatusDown, health.Status)
+
+	health.MarkMaintenance(func(name string, tags []string) (string, bool) {
+		return "upgrade", Match("tag:db", name, tags)
+	})
+	mongo := health.Components["service:mongo"]
+	assert.Equal(t, StatusOutOfService, mongo.Status)
+	assert.Equal(t, "upgrade", mongo.Details["maintenance"])
+	assert.Equal(t, StatusDown, mongo.Details["actual_status"])
+	assert.Equal(t, "status code 500", mongo.Details["reason"])
+	assert.Equal(t, StatusUp, health.Components["service:web"].Status)
+	assert.NotContains(t, health.Components["service:web"].Details, "maintenance")
+	assert.Equal(t, StatusOutOfService, health.Status, "not DOWN anymore, the worst is out of service by default order")
+}
+
+func TestHealthResponse_Group(t *testing.T) {
+	info := &status.Info{CPUPercent: 95, MemPercent: 40, ExtServices: map[string]external.Response{
+		"docker": {Name: "docker", StatusCode: 200, Health: external.Health{Status: external.HealthUp}},
+		"mongo":  {Name: "mongo", StatusCode: 200, Health: external.Health{Status: external.HealthWarn}},
+	}}
+	health := FromStatusInfo(info, Thresholds{})
+
+	readiness := health.Group([]string{"service:*"}, nil)
+	assert.Equal(t, StatusWarn, readiness.Status)
+	assert.Len(t, readiness.Components, 2)
+	assert.Contains(t, readiness.Components, "service:docker")
+	assert.Contains(t, readiness.Components, "service:mongo")
+
+	liveness := health.Group([]string{"cpu", "memory"}, nil)
+	assert.Equal(t, StatusDown, liveness.Status)
+	assert.Len(t, liveness.Components, 2)
+
+	assert.Equal(t, StatusUp, health.Group([]string{"memory"}, nil).Status)
+	assert.Equal(t, StatusUp, health.Group([]string{"unknown"}, nil).Status, "empty group is UP")
+	assert.Empty(t, health.Group([]string{"unknown"}, nil).Components)
+
+	summary := health.Summary()
+	assert.Equal(t, &HealthResponse{Status: StatusDown}, summary)
+	assert.Len(t, health.Components, 5, "original health is not changed")
+}
+
+func TestHealthResponse_GroupByTags(t *testing.T) {
+	info := &status.Info{
+		Volumes: map[string]status.Volume{
+			"root": {Name: "root", Path: "/", UsagePercent: 95, Tags: []string{"payments"}},
+			"data": {Name: "data", Path: "/data", UsagePercent: 10, Labels: map[string]string{"team": "billing"}},
+		},
+		ExtServices: map[string]external.Response{
+			"pay":    {Name: "pay", StatusCode: 200, Tags: []string{"payments", "critical"}},
+			"mongo":  {Name: "mongo", StatusCode: 200, Labels: map[string]string{"team": "billing"}},
+			"docker": {Name: "docker", StatusCode: 200},
+		}}
+	health := FromStatusInfo(info, Thresholds{})
+
+	payments := health.Group([]string{"tag:payments"}, nil)
+	assert.Equal(t, StatusDown, payments.Status)
+	assert.Equal(t, []string{"diskSpace:root", "service:pay"}, slices.Sorted(maps.Keys(payments.Components)))
+
+	billing := health.Group([]string{"tag:team:billing", "service:docker"}, nil)
+	assert.Equal(t, StatusUp, billing.Status)
+	assert.Equal(t, []string{"diskSpace:data", "service:docker", "service:mongo"}, slices.Sorted(maps.Keys(billing.Components)))
+
+	assert.Len(t, health.Group([]string{"tag:team:*"}, nil).Components, 2)
+	assert.Empty(t, health.Group([]string{"tag:cpu"}, nil).Components, "names are not tags")
+}
+
+func TestHealthOptions_Aggregate(t *testing.T) {
+	comps := func(statuses ...string) *HealthResponse {
+		res := &HealthResponse{Components: map[string]Component{}}
+		for i, st := range statuses {
+			res.Components[fmt.Sprintf("c%d", i)] = Component{Status: st}
+		}
+		return res
+	}
+	tbl := []struct {
+		name     string
+		order    []string
+		statuses []string
+		exp      string
+	}{
+		{"no components", nil, nil, StatusUp},
+		{"all up", nil, []string{StatusUp, StatusUp}, StatusUp},
+		{"down", nil, []string{StatusUp, StatusWarn, StatusDown, StatusOutOfService}, StatusDown},
+		{"out of service", nil, []string{StatusUp, StatusWarn, StatusOutOfService}, StatusOutOfService},
+		{"warn", nil, []string{StatusUp, StatusWarn, StatusUnknown}, StatusWarn},
+		{"unknown is least severe", nil, []string{StatusUp, StatusUnknown}, StatusUp},
+		{"only unknown", nil, []string{StatusUnknown}, StatusUnknown},
+		{"custom order", []string{StatusDown, StatusUnknown, StatusUp}, []string{StatusUp, StatusUnknown}, StatusUnknown},
+		{"status missing in order", []string{StatusDown, StatusUp}, []string{StatusUp, StatusWarn}, StatusUp},
+		{"only missing statuses", []string{StatusDown}, []string{"CUSTOM", StatusWarn}, "CUSTOM"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			h := comps(tt.statuses...)
+			HealthOptions{StatusOrder: tt.order}.Aggregate(h)
+			assert.Equal(t, tt.exp, h.Status)
+		})
+	}
+}
+
+func TestHealthOptions_HTTPStatus(t *testing.T) {
+	opts := HealthOptions{}
+	assert.Equal(t, 200, opts.HTTPStatus(StatusUp))
+	assert.Equal(t, 200, opts.HTTPStatus(StatusWarn))
+	assert.Equal(t, 200, opts.HTTPStatus(StatusUnknown))
+	assert.Equal(t, 503, opts.HTTPStatus(StatusDown))
+	assert.Equal(t, 503, opts.HTTPStatus(StatusOutOfService))
+
+	opts = HealthOptions{HTTPMapping: map[string]int{StatusWarn: 207, StatusOutOfService: 200}}
+	assert.Equal(t, 207, opts.HTTPStatus(StatusWarn))
+	assert.Equal(t, 200, opts.HTTPStatus(StatusOutOfService))
+	assert.Equal(t, 503, opts.HTTPStatus(StatusDown))
+}
+
+func TestHealthOptions_Details(t *testing.T) {
+	tbl := []struct {
+		mode       string
+		authorized bool
+		exp        bool
+	}{
+		{"", false, true},
+		{ShowDetailsAlways, false, true},
+		{ShowDetailsNever, true, false},
+		{ShowDetailsWhenAuthorized, false, false},
+		{ShowDetailsWhenAuthorized, true, true},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.exp, HealthOptions{ShowDetails: tt.mode}.Details(tt.authorized), "%q %v", tt.mode, tt.authorized)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	checked := now.Add(-10 * time.Second)
+	info := &status.Info{CPUPercent: 95, MemPercent: 40, CPUCores: 2,
+		Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 50}},
+		ExtServices: map[string]external.Response{
+			"docker": {Name: "docker", StatusCode: 200, ResponseTime: 15, CheckedAt: checked,
+				Health: external.Health{Status: external.HealthDown, Reason: "required containers failed: nginx"}},
+		}}
+	info.Loads.Five = 3
+
+	res := Records(info, Thresholds{}, now)
+	assert.Equal(t, map[string]status.Record{
+		"cpu":            {Time: now, Status: StatusDown, Value: 95},
+		"memory":         {Time: now, Status: StatusUp, Value: 40},
+		"diskSpace:root": {Time: now, Status: StatusUp, Value: 50},
+		"loadAverage":    {Time: now, Status: StatusUp, Value: 1.5},
+		"service:docker": {Time: checked, Status: StatusDown, Value: 15, Reason: "required containers failed: nginx"},
+	}, res)
+
+	assert.Nil(t, Records(nil, Thresholds{}, now))
+}
+
+func loadInfo(five float64, cores int) *status.Info {
+	info := &status.Info{CPUCores: cores}
+	info.Loads.Five = five
+	return info
+}
+
 func TestDiscovery(t *testing.T) {
 	result := Discovery()
 	require.NotNil(t, result)
@@ -227,5 +532,11 @@ func TestDiscovery(t *testing.T) {
 
 	assert.Equal(t, "/actuator", result.Links["self"].Href)
 	assert.Equal(t, "/actuator/health", result.Links["health"].Href)
-	assert.Len(t, result.Links, 2)
+	assert.Equal(t, "/metrics", result.Links["prometheus"].Href)
+	assert.Equal(t, "/actuator/info", result.Links["info"].Href)
+	assert.Equal(t, "/actuator/metrics", result.Links["metrics"].Href)
+	assert.Equal(t, Link{Href: "/actuator/metrics/{requiredMetricName}", Templated: true},
+		result.Links["metrics-requiredMetricName"])
+	assert.Equal(t, Link{Href: "/actuator/health/{*path}", Templated: true}, result.Links["health-path"])
+	assert.Len(t, result.Links, 7)
 }
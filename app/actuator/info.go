@@ -0,0 +1,70 @@
+package actuator
+
+import (
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/umputun/sys-agent/app/status"
+)
+
+// InfoResponse represents Spring Boot Actuator compatible info response, with build and process of the agent,
+// host and configured volumes and services
+type InfoResponse struct {
+	Build    BuildInfo    `json:"build"`
+	Process  ProcessInfo  `json:"process"`
+	Host     HostInfo     `json:"host"`
+	Volumes  []VolumeInfo `json:"volumes"`
+	Services []string     `json:"services"` // names of checked services
+}
+
+// BuildInfo describes the agent binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// ProcessInfo describes the running agent
+type ProcessInfo struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Uptime  int64     `json:"uptime"` // seconds
+}
+
+// HostInfo describes the host the agent is running on
+type HostInfo struct {
+	Hostname string `json:"hostname"`
+	HostID   string `json:"host_id"`
+	CPUCores int    `json:"cpu_cores"`
+	Uptime   uint64 `json:"uptime"` // seconds
+}
+
+// VolumeInfo is a volume checked by the agent
+type VolumeInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Info makes actuator info response from status info, agent version and start time.
+// Service urls are not reported, as they may contain credentials.
+func Info(info *status.Info, version string, started, now time.Time) *InfoResponse {
+	res := &InfoResponse{
+		Build: BuildInfo{Version: version, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH},
+		Process: ProcessInfo{PID: os.Getpid(), Started: started,
+			Uptime: int64(now.Sub(started).Truncate(time.Second).Seconds())},
+		Host:     HostInfo{Hostname: info.HostName, HostID: info.HostID, CPUCores: info.CPUCores, Uptime: info.Uptime},
+		Volumes:  []VolumeInfo{},
+		Services: slices.Sorted(maps.Keys(info.ExtServices)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(info.Volumes)) {
+		res.Volumes = append(res.Volumes, VolumeInfo{Name: name, Path: info.Volumes[name].Path})
+	}
+	if res.Services == nil {
+		res.Services = []string{}
+	}
+	return res
+}
@@ -0,0 +1,34 @@
+package actuator
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestInfo(t *testing.T) {
+	started := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	info := &status.Info{HostName: "h1", HostID: "id1", CPUCores: 4, Uptime: 3600,
+		Volumes:     map[string]status.Volume{"root": {Name: "root", Path: "/"}, "data": {Name: "data", Path: "/data"}},
+		ExtServices: map[string]external.Response{"mongo": {Name: "mongo"}, "docker": {Name: "docker"}}}
+
+	res := Info(info, "v1.2.3", started, started.Add(90*time.Second+500*time.Millisecond))
+	assert.Equal(t, &InfoResponse{
+		Build:    BuildInfo{Version: "v1.2.3", GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH},
+		Process:  ProcessInfo{PID: os.Getpid(), Started: started, Uptime: 90},
+		Host:     HostInfo{Hostname: "h1", HostID: "id1", CPUCores: 4, Uptime: 3600},
+		Volumes:  []VolumeInfo{{Name: "data", Path: "/data"}, {Name: "root", Path: "/"}},
+		Services: []string{"docker", "mongo"},
+	}, res)
+
+	res = Info(&status.Info{}, "v1", started, started)
+	assert.Empty(t, res.Volumes)
+	assert.NotNil(t, res.Volumes)
+	assert.NotNil(t, res.Services)
+}
@@ -0,0 +1,181 @@
+package actuator
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/tagset"
+)
+
+// ErrMetricNotFound is returned for unknown metric or if no measurements match the tags
+var ErrMetricNotFound = errors.New("metric not found")
+
+// statistics of measurements, as in Spring Boot Actuator
+const (
+	StatisticValue = "VALUE" // summed over matching measurements
+	StatisticMax   = "MAX"   // maximum of matching measurements
+)
+
+// MetricNamesResponse represents Spring Boot Actuator compatible response with names of available metrics
+type MetricNamesResponse struct {
+	Names []string `json:"names"`
+}
+
+// MetricResponse represents Spring Boot Actuator compatible metric response, with measurements aggregated
+// over samples matching requested tags, and tags available for further drill down
+type MetricResponse struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description,omitempty"`
+	BaseUnit      string        `json:"baseUnit,omitempty"`
+	Measurements  []Measurement `json:"measurements"`
+	AvailableTags []Tag         `json:"availableTags"`
+}
+
+// Measurement is a single statistic of a metric
+type Measurement struct {
+	Statistic string  `json:"statistic"`
+	Value     float64 `json:"value"`
+}
+
+// Tag lists values of a tag available for drill down, i.e. volume names of disk.free
+type Tag struct {
+	Tag    string   `json:"tag"`
+	Values []string `json:"values"`
+}
+
+// metric defines actuator metric made from status info
+type metric struct {
+	description string
+	baseUnit    string
+	statistic   string
+	samples     func(info *status.Info) []sample
+}
+
+// sample is a value of metric with its tags
+type sample struct {
+	value float64
+	tags  map[string]string
+}
+
+// value makes metric with a single untagged sample
+func value(description, baseUnit string, fn func(info *status.Info) float64) metric {
+	return metric{description: description, baseUnit: baseUnit, statistic: StatisticValue,
+		samples: func(info *status.Info) []sample { return []sample{{value: fn(info)}} }}
+}
+
+// volumeValue makes metric with a sample per volume, tagged by volume name, path and labels
+func volumeValue(description, baseUnit string, fn func(v status.Volume) float64) metric {
+	return metric{description: description, baseUnit: baseUnit, statistic: StatisticValue,
+		samples: func(info *status.Info) []sample {
+			res := make([]sample, 0, len(info.Volumes))
+			for name, v := range info.Volumes {
+				res = append(res, sample{value: fn(v), tags: tagset.Map(v.Labels, "volume", name, "path", v.Path)})
+			}
+			return res
+		}}
+}
+
+var metrics = map[string]metric{
+	"system.cpu.usage": value("recent cpu usage of the system, ratio", "",
+		func(info *status.Info) float64 { return float64(info.CPUPercent) / 100 }),
+	"system.cpu.count": value("number of cpu cores", "",
+		func(info *status.Info) float64 { return float64(info.CPUCores) }),
+	"system.memory.usage": value("memory usage of the system, ratio", "",
+		func(info *status.Info) float64 { return float64(info.MemPercent) / 100 }),
+	"system.load.average.1m": value("system load average for the last minute", "",
+		func(info *status.Info) float64 { return info.Loads.One }),
+	"system.load.average.5m": value("system load average for the last 5 minutes", "",
+		func(info *status.Info) float64 { return info.Loads.Five }),
+	"system.load.average.15m": value("system load average for the last 15 minutes", "",
+		func(info *status.Info) float64 { return info.Loads.Fifteen }),
+	"system.uptime": value("uptime of the host", "seconds",
+		func(info *status.Info) float64 { return float64(info.Uptime) }),
+	"system.processes": value("number of running processes", "",
+		func(info *status.Info) float64 { return float64(info.Procs) }),
+	"disk.free": volumeValue("usable space of the volume", "bytes",
+		func(v status.Volume) float64 { return float64(v.Free) }),
+	"disk.total": volumeValue("total space of the volume", "bytes",
+		func(v status.Volume) float64 { return float64(v.Total) }),
+	"disk.usage": volumeValue("used space of the volume, ratio", "",
+		func(v status.Volume) float64 { return float64(v.UsagePercent) / 100 }),
+	"service.response.time": {description: "response time of the last check of the service", baseUnit: "milliseconds",
+		statistic: StatisticMax, samples: func(info *status.Info) []sample {
+			res := make([]sample, 0, len(info.ExtServices))
+			for name, r := range info.ExtServices {
+				res = append(res, sample{value: float64(r.ResponseTime), tags: tagset.Map(r.Labels, "service", name)})
+			}
+			return res
+		}},
+}
+
+// MetricNames returns sorted names of metrics with any measurements in the status info
+func MetricNames(info *status.Info) *MetricNamesResponse {
+	res := &MetricNamesResponse{Names: []string{}}
+	for _, name := range slices.Sorted(maps.Keys(metrics)) {
+		if len(metrics[name].samples(info)) > 0 {
+			res.Names = append(res.Names, name)
+		}
+	}
+	return res
+}
+
+// Metric returns measurement of the metric made from samples matching all tags, i.e. {"volume": "root"}.
+// Returns ErrMetricNotFound if the metric is unknown or no samples match.
+func Metric(info *status.Info, name string, tags map[string]string) (*MetricResponse, error) {
+	m, ok := metrics[name]
+	if !ok {
+		return nil, ErrMetricNotFound
+	}
+
+	var matched []sample
+	for _, s := range m.samples(info) {
+		if matchTags(s.tags, tags) {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrMetricNotFound
+	}
+
+	var total float64
+	for i, s := range matched {
+		switch {
+		case m.statistic == StatisticMax && (i == 0 || s.value > total):
+			total = s.value
+		case m.statistic != StatisticMax:
+			total += s.value
+		}
+	}
+
+	// tags not used in the request are available for drill down
+	available := map[string]map[string]bool{}
+	for _, s := range matched {
+		for k, v := range s.tags {
+			if _, used := tags[k]; used {
+				continue
+			}
+			if available[k] == nil {
+				available[k] = map[string]bool{}
+			}
+			available[k][v] = true
+		}
+	}
+	res := &MetricResponse{Name: name, Description: m.description, BaseUnit: m.baseUnit,
+		Measurements: []Measurement{{Statistic: m.statistic, Value: total}}, AvailableTags: []Tag{}}
+	for _, k := range slices.Sorted(maps.Keys(available)) {
+		res.AvailableTags = append(res.AvailableTags, Tag{Tag: k, Values: slices.Sorted(maps.Keys(available[k]))})
+	}
+	return res, nil
+}
+
+// matchTags returns true if sample tags have all requested tags with the same values
+func matchTags(sampleTags, tags map[string]string) bool {
+	for k, v := range tags {
+		if sampleTags[k] != v {
+			return false
+		}
+	}
+	return true
+}
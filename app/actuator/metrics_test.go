@@ -0,0 +1,101 @@
+package actuator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestMetricNames(t *testing.T) {
+	res := MetricNames(&status.Info{})
+	assert.Equal(t, []string{"system.cpu.count", "system.cpu.usage", "system.load.average.15m", "system.load.average.1m",
+		"system.load.average.5m", "system.memory.usage", "system.processes", "system.uptime"}, res.Names,
+		"no volume and service metrics without volumes and services")
+
+	res = MetricNames(&status.Info{Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/"}},
+		ExtServices: map[string]external.Response{"mongo": {Name: "mongo"}}})
+	assert.Len(t, res.Names, 12)
+	assert.Contains(t, res.Names, "disk.free")
+	assert.Contains(t, res.Names, "service.response.time")
+}
+
+func TestMetric(t *testing.T) {
+	info := &status.Info{CPUPercent: 25, CPUCores: 4,
+		Volumes: map[string]status.Volume{
+			"root": {Name: "root", Path: "/", UsagePercent: 50, Free: 1000, Total: 2000},
+			"data": {Name: "data", Path: "/data", UsagePercent: 10, Free: 9000, Total: 10000},
+		},
+		ExtServices: map[string]external.Response{
+			"mongo":  {Name: "mongo", ResponseTime: 15},
+			"docker": {Name: "docker", ResponseTime: 40},
+		}}
+	info.Loads.One = 1.5
+
+	tbl := []struct {
+		name string
+		tags map[string]string
+		exp  *MetricResponse
+		err  error
+	}{
+		{"system.cpu.usage", nil, &MetricResponse{Name: "system.cpu.usage", Description: "recent cpu usage of the system, ratio",
+			Measurements: []Measurement{{Statistic: "VALUE", Value: 0.25}}, AvailableTags: []Tag{}}, nil},
+		{"system.load.average.1m", nil, &MetricResponse{Name: "system.load.average.1m",
+			Description:  "system load average for the last minute",
+			Measurements: []Measurement{{Statistic: "VALUE", Value: 1.5}}, AvailableTags: []Tag{}}, nil},
+		{"disk.free", nil, &MetricResponse{Name: "disk.free", Description: "usable space of the volume", BaseUnit: "bytes",
+			Measurements:  []Measurement{{Statistic: "VALUE", Value: 10000}},
+			AvailableTags: []Tag{{Tag: "path", Values: []string{"/", "/data"}}, {Tag: "volume", Values: []string{"data", "root"}}}}, nil},
+		{"disk.free", map[string]string{"volume": "root"}, &MetricResponse{Name: "disk.free",
+			Description: "usable space of the volume", BaseUnit: "bytes",
+			Measurements:  []Measurement{{Statistic: "VALUE", Value: 1000}},
+			AvailableTags: []Tag{{Tag: "path", Values: []string{"/"}}}}, nil},
+		{"service.response.time", nil, &MetricResponse{Name: "service.response.time",
+			Description: "response time of the last check of the service", BaseUnit: "milliseconds",
+			Measurements:  []Measurement{{Statistic: "MAX", Value: 40}},
+			AvailableTags: []Tag{{Tag: "service", Values: []string{"docker", "mongo"}}}}, nil},
+		{"service.response.time", map[string]string{"service": "mongo"}, &MetricResponse{Name: "service.response.time",
+			Description: "response time of the last check of the service", BaseUnit: "milliseconds",
+			Measurements: []Measurement{{Statistic: "MAX", Value: 15}}, AvailableTags: []Tag{}}, nil},
+		{"disk.free", map[string]string{"volume": "unknown"}, nil, ErrMetricNotFound},
+		{"system.cpu.usage", map[string]string{"volume": "root"}, nil, ErrMetricNotFound},
+		{"unknown", nil, nil, ErrMetricNotFound},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Metric(info, tt.name, tt.tags)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.exp, res)
+		})
+	}
+}
+
+func TestMetric_Labels(t *testing.T) {
+	info := &status.Info{
+		Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/", Free: 1000, Labels: map[string]string{"team": "infra"}}},
+		ExtServices: map[string]external.Response{
+			"mongo":  {Name: "mongo", ResponseTime: 15, Labels: map[string]string{"team": "billing"}},
+			"docker": {Name: "docker", ResponseTime: 40, Labels: map[string]string{"team": "infra"}},
+			"nginx":  {Name: "nginx", ResponseTime: 50},
+		}}
+
+	res, err := Metric(info, "service.response.time", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{Tag: "service", Values: []string{"docker", "mongo", "nginx"}},
+		{Tag: "team", Values: []string{"billing", "infra"}}}, res.AvailableTags)
+
+	res, err = Metric(info, "service.response.time", map[string]string{"team": "billing"})
+	require.NoError(t, err)
+	assert.Equal(t, []Measurement{{Statistic: "MAX", Value: 15}}, res.Measurements)
+
+	res, err = Metric(info, "disk.free", map[string]string{"team": "infra"})
+	require.NoError(t, err)
+	assert.Equal(t, []Measurement{{Statistic: "VALUE", Value: 1000}}, res.Measurements)
+}
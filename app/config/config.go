@@ -2,93 +2,99 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
+	"path"
+	"slices"
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/yaml.v3"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/maintenance"
+	"github.com/umputun/sys-agent/app/tagset"
 )
 
 // Parameters represents the whole configuration parameters
 type Parameters struct {
-	Volumes  []Volume `yaml:"volumes"`
-	Services struct {
-		HTTP        []HTTP        `yaml:"http"`
-		Certificate []Certificate `yaml:"certificate"`
-		File        []File        `yaml:"file"`
-		Mongo       []Mongo       `yaml:"mongo"`
-		Nginx       []Nginx       `yaml:"nginx"`
-		Program     []Program     `yaml:"program"`
-		Docker      []Docker      `yaml:"docker"`
-		RMQ         []RMQ         `yaml:"rmq"`
-	} `yaml:"services"`
+	Volumes     []Volume               `yaml:"volumes"`
+	Thresholds  Thresholds             `yaml:"thresholds"`
+	Services    map[string][]yaml.Node `yaml:"services"` // service entries by provider section, i.e. "docker"
+	Hooks       map[string]Hook        `yaml:"hooks"`    // hooks of system components, i.e. "cpu" or "diskSpace:root"
+	Auth        Auth                   `yaml:"auth"`
+	Actuator    Actuator               `yaml:"actuator"`
+	Maintenance []Maintenance          `yaml:"maintenance"` // planned maintenance windows of components
 
 	fileName string `yaml:"-"`
 }
 
 // Volume represents a volumes to check
 type Volume struct {
-	Name string `yaml:"name"`
-	Path string `yaml:"path"`
-}
+	Name      string    `yaml:"name"`
+	Path      string    `yaml:"path"`
+	Threshold Threshold `yaml:"threshold"` // overrides the default disk threshold for the volume
 
-// HTTP represents a http service to check
-type HTTP struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Tags   []string          `yaml:"tags"`   // i.e. payments, used to select volumes and in health groups
+	Labels map[string]string `yaml:"labels"` // i.e. team: billing, used as tags and metric labels
 }
 
-// Certificate represents a certificate to check
-type Certificate struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+// Thresholds represents levels used by actuator health to determine the status of system components
+type Thresholds struct {
+	CPU         Threshold `yaml:"cpu"`
+	Memory      Threshold `yaml:"memory"`
+	Disk        Threshold `yaml:"disk"`         // default for all volumes
+	LoadAverage Threshold `yaml:"load_average"` // 5 minutes load average per cpu core
 }
 
-// Docker represents a docker container to check
-type Docker struct {
-	Name       string   `yaml:"name"`
-	URL        string   `yaml:"url"`
-	Containers []string `yaml:"containers"` // required containers
+// Threshold represents warn and down levels, zero value means the level is not set
+type Threshold struct {
+	Warn float64 `yaml:"warn"`
+	Down float64 `yaml:"down"`
 }
 
-// File represents a file to check
-type File struct {
-	Name string `yaml:"name"`
-	Path string `yaml:"path"`
+// Hook defines shell commands run when a component goes DOWN or recovers.
+// Services set hooks with on_down and on_up fields of their entries.
+type Hook struct {
+	OnDown string `yaml:"on_down"`
+	OnUp   string `yaml:"on_up"`
 }
 
-// Mongo represents a mongo service to check
-type Mongo struct {
-	Name          string        `yaml:"name"`
-	URL           string        `yaml:"url"`
-	OplogMaxDelta time.Duration `yaml:"oplog_max_delta"`
-	Collection    string        `yaml:"collection"`
-	DB            string        `yaml:"db"`
-	CountQuery    string        `yaml:"count_query"`
+// Auth defines credentials of the http api, authentication is disabled if no users and tokens set
+type Auth struct {
+	Users        map[string]string `yaml:"users"`         // bcrypt hashes of passwords by user name
+	Tokens       []string          `yaml:"tokens"`        // static bearer tokens
+	PublicHealth bool              `yaml:"public_health"` // actuator health summary is available without auth
 }
 
-// Nginx represents a nginx service to check
-type Nginx struct {
-	Name      string `yaml:"name"`
-	StatusURL string `yaml:"status_url"`
+// Actuator defines details reported by actuator health, groups of components, like liveness and readiness,
+// and aggregation of statuses
+type Actuator struct {
+	ShowDetails string              `yaml:"show_details"` // always (default), never or when-authorized
+	Groups      map[string][]string `yaml:"groups"`       // component names or glob patterns by group name
+	Status      Status              `yaml:"status"`
 }
 
-// Program represents a program to check
-type Program struct {
-	Name string   `yaml:"name"`
-	Path string   `yaml:"path"`
-	Args []string `yaml:"args"`
+// Status defines the order of statuses, from the most to the least severe, and http codes of statuses,
+// same as management.endpoint.health.status of Spring Boot
+type Status struct {
+	Order       []string       `yaml:"order"`
+	HTTPMapping map[string]int `yaml:"http_mapping"`
 }
 
-// RMQ represents a rmq to check
-type RMQ struct {
-	Name  string `yaml:"name"`
-	URL   string `yaml:"url"`
-	User  string `yaml:"user"`
-	Pass  string `yaml:"pass"`
-	Vhost string `yaml:"vhost"`
-	Queue string `yaml:"queue"`
+// Maintenance defines a window of planned maintenance, absolute with start and end, or recurring by cron with duration.
+// Components are names, glob patterns or "tag:" tags, the same as members of actuator groups.
+type Maintenance struct {
+	Name       string        `yaml:"name"`
+	Components []string      `yaml:"components"`
+	Reason     string        `yaml:"reason"`
+	Start      time.Time     `yaml:"start"`
+	End        time.Time     `yaml:"end"`
+	Cron       string        `yaml:"cron"`     // start times of recurring window, i.e. "0 3 * * 0"
+	Duration   time.Duration `yaml:"duration"` // length of recurring window
 }
 
 // New creates a new Parameters from the given file
@@ -101,100 +107,157 @@ func New(fname string) (*Parameters, error) {
 	if err = yaml.Unmarshal(data, &p); err != nil {
 		return nil, fmt.Errorf("failed to parsse config %s: %w", fname, err)
 	}
+	if err = p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", fname, err)
+	}
 	return p, nil
 }
 
-// MarshalVolumes returns the volumes as a list of strings with the format "name:path"
-func (p *Parameters) MarshalVolumes() []string {
-	res := make([]string, 0, len(p.Volumes))
-	for _, v := range p.Volumes {
-		res = append(res, fmt.Sprintf("%s:%s", v.Name, v.Path))
+// Validate checks that volumes are complete and thresholds are consistent.
+// Services are validated by the providers registry, as their format is defined by providers.
+func (p *Parameters) Validate() error {
+	names := map[string]bool{}
+	for i, v := range p.Volumes {
+		if v.Name == "" || v.Path == "" {
+			return fmt.Errorf("volume #%d: name and path are required", i+1)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("volume %q: duplicate name", v.Name)
+		}
+		names[v.Name] = true
+		if err := v.Threshold.validate(cmp.Or(p.Thresholds.Disk.Down, actuator.DefaultDown)); err != nil {
+			return fmt.Errorf("volume %q: %w", v.Name, err)
+		}
+		if err := tagset.Validate(v.Tags, v.Labels); err != nil {
+			return fmt.Errorf("volume %q: %w", v.Name, err)
+		}
 	}
-	return res
-}
 
-// MarshalServices returns the services as a list of strings with the format used by command line
-func (p *Parameters) MarshalServices() []string {
-	size := len(p.Services.HTTP) + len(p.Services.Certificate) + len(p.Services.Docker) +
-		len(p.Services.File) + len(p.Services.Mongo) + len(p.Services.Nginx) + len(p.Services.Program) + len(p.Services.RMQ)
-	res := make([]string, 0, size)
-
-	for _, v := range p.Services.HTTP {
-		res = append(res, fmt.Sprintf("%s:%s", v.Name, v.URL))
+	for _, th := range []struct {
+		name string
+		Threshold
+		defDown float64
+	}{{"cpu", p.Thresholds.CPU, actuator.DefaultDown}, {"memory", p.Thresholds.Memory, actuator.DefaultDown},
+		{"disk", p.Thresholds.Disk, actuator.DefaultDown}, {"load_average", p.Thresholds.LoadAverage, 0}} {
+		if err := th.validate(th.defDown); err != nil {
+			return fmt.Errorf("%s threshold: %w", th.name, err)
+		}
 	}
 
-	for _, v := range p.Services.Certificate {
-		url := strings.TrimPrefix(v.URL, "https://")
-		url = strings.TrimPrefix(url, "http://")
-		res = append(res, fmt.Sprintf("%s:cert://%s", v.Name, url))
+	for name := range p.Hooks {
+		if name != "cpu" && name != "memory" && name != "loadAverage" && !strings.HasPrefix(name, "diskSpace:") {
+			return fmt.Errorf("hook %q: should be cpu, memory, loadAverage or diskSpace:<volume>", name)
+		}
 	}
 
-	for _, v := range p.Services.Docker {
-		url := strings.TrimPrefix(v.URL, "https://")
-		url = strings.TrimPrefix(url, "http://")
-		url = strings.TrimPrefix(url, "tcp://")
-		url = strings.TrimPrefix(url, "unix://")
-		if len(v.Containers) > 0 {
-			url += "?containers=" + strings.Join(v.Containers, ":")
+	for user, hash := range p.Auth.Users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("auth user %q: invalid bcrypt hash: %w", user, err)
 		}
-		res = append(res, fmt.Sprintf("%s:docker://%s", v.Name, url))
 	}
-
-	for _, v := range p.Services.File {
-		res = append(res, fmt.Sprintf("%s:file://%s", v.Name, v.Path))
+	if slices.Contains(p.Auth.Tokens, "") {
+		return fmt.Errorf("auth tokens: empty token")
 	}
 
-	for _, v := range p.Services.Mongo {
-		m := fmt.Sprintf("%s:%s", v.Name, v.URL)
-		if v.OplogMaxDelta > 0 {
-			if strings.Contains(m, "?") {
-				m += fmt.Sprintf("&oplogMaxDelta=%v", v.OplogMaxDelta)
-			} else {
-				m += fmt.Sprintf("?oplogMaxDelta=%v", v.OplogMaxDelta)
-			}
+	switch p.Actuator.ShowDetails {
+	case "", "always", "never", "when-authorized":
+	default:
+		return fmt.Errorf("actuator show_details %q: should be always, never or when-authorized", p.Actuator.ShowDetails)
+	}
+	for name, members := range p.Actuator.Groups {
+		if name == "" || strings.Contains(name, ":") || name == "cpu" || name == "memory" || name == "loadAverage" {
+			return fmt.Errorf("actuator group %q: invalid name, conflicts with components", name)
 		}
-		if v.Collection != "" {
-			m += fmt.Sprintf("&collection=%s", v.Collection)
+		if len(members) == 0 {
+			return fmt.Errorf("actuator group %q: no components", name)
 		}
-		if v.DB != "" {
-			m += fmt.Sprintf("&db=%s", v.DB)
+		for _, m := range members {
+			if _, err := path.Match(m, ""); err != nil {
+				return fmt.Errorf("actuator group %q: invalid pattern %q: %w", name, m, err)
+			}
 		}
-		if v.CountQuery != "" {
-			m += fmt.Sprintf("&countQuery=%s", v.CountQuery)
+	}
+	for i, st := range p.Actuator.Status.Order {
+		if st == "" || slices.Contains(p.Actuator.Status.Order[:i], st) {
+			return fmt.Errorf("actuator status order: empty or duplicate status %q", st)
 		}
-		res = append(res, m)
 	}
-
-	for _, v := range p.Services.Nginx {
-		u := v.StatusURL
-		u = strings.TrimPrefix(u, "http://")
-		u = strings.TrimPrefix(u, "https://")
-
-		res = append(res, fmt.Sprintf("%s:nginx://%s", v.Name, u))
+	for st, code := range p.Actuator.Status.HTTPMapping {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("actuator status http_mapping %q: invalid http code %d", st, code)
+		}
 	}
 
-	for _, v := range p.Services.Program {
-		prg := fmt.Sprintf("%s:program://%s", v.Name, v.Path)
-		if len(v.Args) > 0 {
-			prg += "?args=\"" + strings.Join(v.Args, " ") + "\""
+	windows := map[string]bool{}
+	for i, m := range p.Maintenance {
+		if m.Name == "" || windows[m.Name] {
+			return fmt.Errorf("maintenance #%d: empty or duplicate name %q", i+1, m.Name)
+		}
+		windows[m.Name] = true
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("maintenance %q: %w", m.Name, err)
 		}
-		res = append(res, prg)
 	}
+	return nil
+}
 
-	for _, v := range p.Services.RMQ {
-		u := v.URL
-		u = strings.TrimPrefix(u, "http://")
-		u = strings.TrimPrefix(u, "https://")
-		if v.User != "" && v.Pass != "" {
-			u = fmt.Sprintf("%s:%s@%s", v.User, v.Pass, u)
+// validate checks the window the same way as maintenance schedule does
+func (m Maintenance) validate() error {
+	return maintenance.Window{Name: m.Name, Components: m.Components, Reason: m.Reason, Start: m.Start, End: m.End,
+		Cron: m.Cron, Duration: m.Duration}.Validate()
+}
 
+// ComponentHooks returns hooks by actuator component name, hooks of system components combined with
+// on_down and on_up of services, as "service:<name>"
+func (p *Parameters) ComponentHooks() (map[string]Hook, error) {
+	res := make(map[string]Hook, len(p.Hooks))
+	maps.Copy(res, p.Hooks)
+	for section, nodes := range p.Services {
+		for i, node := range nodes {
+			var svc struct {
+				Name string `yaml:"name"`
+				Hook `yaml:",inline"`
+			}
+			if err := node.Decode(&svc); err != nil {
+				return nil, fmt.Errorf("%s service #%d: can't decode: %w", section, i+1, err)
+			}
+			if svc.OnDown != "" || svc.OnUp != "" {
+				res["service:"+svc.Name] = svc.Hook
+			}
 		}
-		res = append(res, fmt.Sprintf("%s:rmq://%s/%s/%s", v.Name, u, v.Vhost, v.Queue))
 	}
+	return res, nil
+}
+
+// validate checks that levels are not negative and warn level is below down level, or below defDown if down is not set,
+// so warn level is reachable. Zero defDown means no default down level.
+func (t Threshold) validate(defDown float64) error {
+	if t.Warn < 0 || t.Down < 0 {
+		return fmt.Errorf("negative level, warn: %v, down: %v", t.Warn, t.Down)
+	}
+	if t.Warn > 0 && t.Down > 0 && t.Warn > t.Down {
+		return fmt.Errorf("warn level %v is above down level %v", t.Warn, t.Down)
+	}
+	if t.Warn > 0 && t.Down == 0 && defDown > 0 && t.Warn > defDown {
+		return fmt.Errorf("warn level %v is above default down level %v, down level should be set", t.Warn, defDown)
+	}
+	return nil
+}
 
+// MarshalVolumes returns the volumes as a list of strings with the format "name:path"
+func (p *Parameters) MarshalVolumes() []string {
+	res := make([]string, 0, len(p.Volumes))
+	for _, v := range p.Volumes {
+		res = append(res, fmt.Sprintf("%s:%s", v.Name, v.Path))
+	}
 	return res
 }
 
 func (p *Parameters) String() string {
-	return fmt.Sprintf("config file: %q, %+v", p.fileName, *p)
+	svcs := make([]string, 0, len(p.Services))
+	for _, section := range slices.Sorted(maps.Keys(p.Services)) {
+		svcs = append(svcs, fmt.Sprintf("%s:%d", section, len(p.Services[section])))
+	}
+	return fmt.Sprintf("config file: %q, volumes: %+v, thresholds: %+v, services: %v",
+		p.fileName, p.Volumes, p.Thresholds, svcs)
 }
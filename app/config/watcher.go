@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls onChange each time the config file is written or replaced, and blocks until context is canceled.
+// The directory of the file is watched, not the file itself, as editors and config management tools
+// usually replace the file instead of writing to it. Events within the delay are merged into a single call.
+func Watch(ctx context.Context, fname string, delay time.Duration, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to make watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	fname = filepath.Clean(fname)
+	if err = watcher.Add(filepath.Dir(fname)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", fname, err)
+	}
+	log.Printf("[INFO] watching config %s for changes", fname)
+
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) != fname || !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) {
+				continue
+			}
+			log.Printf("[DEBUG] config file event %s", ev)
+			timer.Reset(delay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[WARN] config watcher error, %v", err)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
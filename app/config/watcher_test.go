@@ -0,0 +1,49 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(fname, []byte("volumes: []"), 0o600))
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Watch(ctx, fname, 50*time.Millisecond, func() { calls.Add(1) }) }()
+	time.Sleep(50 * time.Millisecond) // let watcher start
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yml"), []byte("blah"), 0o600))
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, calls.Load(), "changes of other files ignored")
+
+	for range 3 { // multiple writes merged into a single call
+		require.NoError(t, os.WriteFile(fname, []byte("volumes: [{name: root, path: /}]"), 0o600))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// replace the file, as editors do
+	tmp := filepath.Join(dir, "config.yml.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte("volumes: []"), 0o600))
+	require.NoError(t, os.Rename(tmp, fname))
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatch_NoDir(t *testing.T) {
+	err := Watch(context.Background(), "/no-such-dir/config.yml", time.Millisecond, func() {})
+	require.Error(t, err)
+}
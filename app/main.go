@@ -8,29 +8,75 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-pkgz/lgr"
 	"github.com/umputun/go-flags"
 
+	"github.com/umputun/sys-agent/app/actuator"
 	"github.com/umputun/sys-agent/app/config"
+	"github.com/umputun/sys-agent/app/maintenance"
+	"github.com/umputun/sys-agent/app/notify"
 	"github.com/umputun/sys-agent/app/server"
 	"github.com/umputun/sys-agent/app/status"
 	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/store"
 )
 
 var revision string
 
 var opts struct {
-	Config string `short:"f" long:"config" env:"CONFIG" description:"config file"`
+	Config      string `short:"f" long:"config" env:"CONFIG" description:"config file"`
+	ConfigWatch bool   `long:"config-watch" env:"CONFIG_WATCH" description:"reload config on file change"`
 
-	Listen  string   `short:"l" long:"listen" env:"LISTEN" default:"localhost:8080" description:"listen on host:port"`
-	Volumes []string `short:"v" long:"volume" env:"VOLUMES" default:"root:/" env-delim:"," description:"volumes to report"`
+	Listen string `short:"l" long:"listen" env:"LISTEN" default:"localhost:8080" description:"listen on host:port"`
+
+	TLSCert     string   `long:"tls-cert" env:"TLS_CERT" description:"tls certificate file, enables https"`
+	TLSKey      string   `long:"tls-key" env:"TLS_KEY" description:"tls key file"`
+	TLSCA       string   `long:"tls-ca" env:"TLS_CA" description:"CA bundle to verify client certificates, enables mTLS"`
+	TLSRedirect string   `long:"tls-redirect" env:"TLS_REDIRECT" description:"listen on host:port for http redirect to https"`
+	Volumes     []string `short:"v" long:"volume" env:"VOLUMES" default:"root:/" env-delim:"," description:"volumes to report"`
 
 	Services []string      `short:"s" long:"service" env:"SERVICES" env-delim:"," description:"services to report"`
 	TimeOut  time.Duration `long:"timeout" env:"TIMEOUT" default:"5s" description:"timeout for each request to services"`
+	Interval time.Duration `long:"interval" env:"INTERVAL" default:"30s" description:"default interval between checks of each service"`
+
+	HistorySize     int           `long:"history-size" env:"HISTORY_SIZE" default:"100" description:"number of results kept in history of each service and metric"`
+	HistoryInterval time.Duration `long:"history-interval" env:"HISTORY_INTERVAL" default:"10s" description:"interval of recording to history"`
+	FlapWindow      int           `long:"flap-window" env:"FLAP_WINDOW" default:"10" description:"number of last results checked for flapping"`
+	FlapChanges     int           `long:"flap-changes" env:"FLAP_CHANGES" default:"4" description:"number of state changes in flap window to report flapping, 0 to disable"`
+
+	StreamInterval  time.Duration `long:"stream-interval" env:"STREAM_INTERVAL" default:"5s" description:"interval of checking changes for status stream"`
+	StreamHeartbeat time.Duration `long:"stream-heartbeat" env:"STREAM_HEARTBEAT" default:"15s" description:"interval of heartbeat comments in status stream"`
+
+	Data          string        `long:"data" env:"DATA" description:"directory of persistent history store, disabled if not set"`
+	DataRetention time.Duration `long:"data-retention" env:"DATA_RETENTION" default:"720h" description:"how long to keep persistent history, 0 to keep forever"`
+
+	Webhooks          []string      `long:"webhook" env:"WEBHOOKS" env-delim:"," description:"webhook urls notified on state changes"`
+	WebhookRetries    int           `long:"webhook-retries" env:"WEBHOOK_RETRIES" default:"3" description:"number of retries of failed webhook requests"`
+	NotifyInterval    time.Duration `long:"notify-interval" env:"NOTIFY_INTERVAL" default:"10s" description:"interval of checking state changes for notifications"`
+	NotifyMinDuration time.Duration `long:"notify-min-duration" env:"NOTIFY_MIN_DURATION" default:"0s" description:"how long the new state should be kept to notify"`
+
+	SMTPHost      string        `long:"smtp-host" env:"SMTP_HOST" description:"smtp server host"`
+	SMTPPort      int           `long:"smtp-port" env:"SMTP_PORT" default:"587" description:"smtp server port"`
+	SMTPUsername  string        `long:"smtp-username" env:"SMTP_USERNAME" description:"smtp auth username"`
+	SMTPPassword  string        `long:"smtp-password" env:"SMTP_PASSWORD" description:"smtp auth password"`
+	SMTPTLS       bool          `long:"smtp-tls" env:"SMTP_TLS" description:"connect to smtp server with tls"`
+	SMTPStartTLS  bool          `long:"smtp-starttls" env:"SMTP_STARTTLS" description:"upgrade smtp connection with starttls"`
+	EmailFrom     string        `long:"email-from" env:"EMAIL_FROM" description:"sender of email notifications"`
+	EmailTo       []string      `long:"email-to" env:"EMAIL_TO" env-delim:"," description:"recipients of email notifications"`
+	EmailBatch    time.Duration `long:"email-batch" env:"EMAIL_BATCH" default:"30s" description:"state changes within this window are sent in one email"`
+	EmailTemplate string        `long:"email-template" env:"EMAIL_TEMPLATE" description:"text/template file of email body"`
+
+	MaintenanceMax time.Duration `long:"maintenance-max" env:"MAINTENANCE_MAX" default:"24h" description:"max duration of maintenance windows added by api"`
+
+	HookTimeout  time.Duration `long:"hook-timeout" env:"HOOK_TIMEOUT" default:"30s" description:"max run time of on_down and on_up hooks"`
+	HookCooldown time.Duration `long:"hook-cooldown" env:"HOOK_COOLDOWN" default:"5m" description:"min time between runs of the same hook"`
 
 	Concurrency      int    `long:"concurrency" env:"CONCURRENCY" default:"4" description:"number of concurrent requests to services"`
 	DockerAPIVersion string `long:"docker-api" env:"DOCKER_API" default:"1.24" description:"docker API version"`
@@ -52,6 +98,9 @@ func main() {
 		os.Exit(2)
 	}
 	setupLog(opts.Dbg)
+	if err := checkTLS(); err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
@@ -83,41 +132,196 @@ func main() {
 		log.Fatalf("[ERROR] %s", err)
 	}
 
-	providers := external.Providers{
-		HTTP:        &external.HTTPProvider{Client: http.Client{Timeout: opts.TimeOut}},
-		Mongo:       &external.MongoProvider{TimeOut: opts.TimeOut},
-		Docker:      &external.DockerProvider{TimeOut: opts.TimeOut, APIVersion: opts.DockerAPIVersion},
-		Program:     &external.ProgramProvider{TimeOut: opts.TimeOut, WithShell: true},
-		Nginx:       &external.NginxProvider{TimeOut: opts.TimeOut},
-		Certificate: &external.CertificateProvider{TimeOut: opts.TimeOut},
-		File:        &external.FileProvider{TimeOut: opts.TimeOut},
-		RMQ:         &external.RMQProvider{TimeOut: opts.TimeOut},
+	// each provider handles its url schemes and config section, new providers should be added here
+	registry, err := external.NewRegistry(
+		&external.HTTPProvider{Client: http.Client{Timeout: opts.TimeOut}},
+		&external.CertificateProvider{TimeOut: opts.TimeOut},
+		&external.DockerProvider{TimeOut: opts.TimeOut, APIVersion: opts.DockerAPIVersion},
+		&external.FileProvider{TimeOut: opts.TimeOut},
+		&external.MongoProvider{TimeOut: opts.TimeOut},
+		&external.NginxProvider{TimeOut: opts.TimeOut},
+		&external.ProgramProvider{TimeOut: opts.TimeOut, WithShell: true},
+		&external.RMQProvider{TimeOut: opts.TimeOut},
+	)
+	if err != nil {
+		log.Fatalf("[ERROR] can't make providers registry, %s", err)
+	}
+
+	reqs, err := services(registry, opts.Services, conf)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
 	}
 
+	// checks of external services run in background, status reports the last known responses
+	extSvc := external.NewService(registry, reqs...)
+	extSvc.Timeout = opts.TimeOut
+	scheduler := external.NewScheduler(extSvc, opts.Interval, opts.Concurrency)
+
+	// maintenance windows are set in config and added by api
+	maint, err := maintenance.New(maintenanceWindows(conf)...)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+	maint.MaxDuration = opts.MaintenanceMax
+
+	statusSvc := &status.Service{Volumes: vols, ExtServices: scheduler, Maintenance: maint}
+	history := status.NewHistory(opts.HistorySize, opts.FlapWindow, opts.FlapChanges)
 	srv := server.Rest{
-		Listen:  opts.Listen,
-		Version: revision,
-		Status: &status.Service{
-			Volumes:     vols,
-			ExtServices: external.NewService(providers, opts.Concurrency, services(opts.Services, conf)...),
-		},
+		Listen:     opts.Listen,
+		Version:    revision,
+		Started:    time.Now(),
+		Thresholds: thresholds(conf),
+		Auth:       apiAuth(conf),
+
+		HealthOptions: healthOptions(conf),
+
+		StreamInterval:  opts.StreamInterval,
+		StreamHeartbeat: opts.StreamHeartbeat,
+
+		TLSCert:        opts.TLSCert,
+		TLSKey:         opts.TLSKey,
+		TLSClientCA:    opts.TLSCA,
+		RedirectListen: opts.TLSRedirect,
+		Status:         statusSvc,
+		Services:       scheduler,
+		History:        history,
+		Maintenance:    maint,
+	}
+	recorder := &status.Recorder{Status: statusSvc, Evaluator: &srv, History: history, Interval: opts.HistoryInterval}
+
+	if opts.Data != "" {
+		st, err := openStore(opts.Data, opts.DataRetention)
+		if err != nil {
+			log.Fatalf("[ERROR] %s", err)
+		}
+		defer st.Close()
+		recorder.Storage, srv.SLA = st, st
+		scheduler.OnResponse = recorder.SaveResponse // each check is saved, not only ones seen by recorder
+		go st.RunCleanup(ctx, time.Hour)
+	}
+	go scheduler.Run(ctx)
+	go recorder.Run(ctx)
+
+	// hooks are set in config and updated on reload
+	var hooks *notify.Hooks
+	if conf != nil {
+		hooks = &notify.Hooks{Timeout: opts.HookTimeout, Cooldown: opts.HookCooldown}
+		commands, err := hookCommands(conf)
+		if err != nil {
+			log.Fatalf("[ERROR] %s", err)
+		}
+		hooks.Update(commands)
+	}
+	notifiers, err := makeNotifiers(hooks)
+	if err != nil {
+		log.Fatalf("[ERROR] %s", err)
+	}
+	var notifyWg sync.WaitGroup // waits for pending notifications, i.e. email batch, on shutdown
+	if len(notifiers) > 0 {
+		notifier := &notify.Service{Status: statusSvc, Evaluator: &srv, Notifiers: notifiers,
+			MinDuration: opts.NotifyMinDuration, Interval: opts.NotifyInterval}
+		notifyWg.Go(func() { notifier.Run(ctx) })
+	}
+
+	if opts.Config != "" {
+		reloader := &configReloader{fname: opts.Config, volumes: opts.Volumes, services: opts.Services,
+			registry: registry, status: statusSvc, extSvc: extSvc, scheduler: scheduler, srv: &srv, hooks: hooks,
+			maintenance: maint}
+		go reloader.onSignal(ctx)
+		if opts.ConfigWatch {
+			go func() {
+				if err := config.Watch(ctx, opts.Config, time.Second, reloader.run); err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("[WARN] config watcher stopped, %v", err)
+				}
+			}()
+		}
 	}
 
 	if err := srv.Run(ctx); err != nil && err.Error() != "http: Server closed" {
 		log.Fatalf("[ERROR] %s", err)
 	}
+	notifyWg.Wait()
+}
+
+// openStore makes data directory if needed and opens persistent store in it
+func openStore(dir string, retention time.Duration) (*store.Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't make data directory %s: %w", dir, err)
+	}
+	st, err := store.New(filepath.Join(dir, "sys-agent.db"), retention)
+	if err != nil {
+		return nil, fmt.Errorf("can't open store: %w", err)
+	}
+	log.Printf("[INFO] persistent history in %s, retention %v", dir, retention)
+	return st, nil
+}
+
+// makeNotifiers returns notifiers of state changes enabled by options, duplicate webhooks are ignored.
+// Email is enabled if recipients are set, hooks are optional.
+func makeNotifiers(hooks *notify.Hooks) ([]notify.Notifier, error) {
+	res := []notify.Notifier{}
+	if hooks != nil {
+		res = append(res, hooks)
+	}
+	for _, u := range slices.Compact(slices.Sorted(slices.Values(opts.Webhooks))) {
+		if u == "" {
+			continue
+		}
+		res = append(res, &notify.Webhook{URL: u, Client: http.Client{Timeout: opts.TimeOut}, Retries: opts.WebhookRetries})
+	}
+
+	if len(opts.EmailTo) > 0 {
+		email, err := notify.NewEmail(notify.EmailParams{Host: opts.SMTPHost, Port: opts.SMTPPort,
+			Username: opts.SMTPUsername, Password: opts.SMTPPassword, TLS: opts.SMTPTLS, StartTLS: opts.SMTPStartTLS,
+			Timeout: opts.TimeOut, From: opts.EmailFrom, To: opts.EmailTo, BatchWindow: opts.EmailBatch,
+			TemplateFile: opts.EmailTemplate})
+		if err != nil {
+			return nil, fmt.Errorf("can't make email notifier: %w", err)
+		}
+		res = append(res, email)
+	}
+	return res, nil
+}
+
+// hookCommands returns hooks of components from config
+func hookCommands(conf *config.Parameters) (map[string]notify.Hook, error) {
+	confHooks, err := conf.ComponentHooks()
+	if err != nil {
+		return nil, fmt.Errorf("can't get hooks: %w", err)
+	}
+	res := make(map[string]notify.Hook, len(confHooks))
+	for name, h := range confHooks {
+		res[name] = notify.Hook{OnDown: h.OnDown, OnUp: h.OnUp}
+	}
+	return res, nil
 }
 
-// service returns list of services to check, merge config and command line
-func services(optsSvcs []string, conf *config.Parameters) (res []string) {
-	if len(optsSvcs) > 0 {
-		res = optsSvcs
+// services returns list of requests to check, merge config and command line.
+// Fails on unknown url schemes and config sections, as well as on invalid services and dependencies.
+func services(registry *external.Registry, optsSvcs []string, conf *config.Parameters) ([]external.Request, error) {
+	res, err := registry.ParseServices(optsSvcs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command line services: %w", err)
 	}
 	if conf != nil {
-		res = append(res, conf.MarshalServices()...)
+		confReqs, err := registry.ParseConfig(conf.Services)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config services: %w", err)
+		}
+		res = append(res, confReqs...)
 	}
-	log.Printf("[DEBUG] services: %+v", res)
-	return res
+	names := map[string]bool{}
+	for _, r := range res {
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate service name %q, names of command line and config services should be unique", r.Name)
+		}
+		names[r.Name] = true
+	}
+	if err = external.ValidateDependencies(res); err != nil {
+		return nil, fmt.Errorf("invalid services: %w", err)
+	}
+	log.Printf("[DEBUG] services: %v", res)
+	return res, nil
 }
 
 // parseVolumes parses volumes from string list, each element in format "name:path"
@@ -128,7 +332,7 @@ func parseVolumes(volumes []string, conf *config.Parameters) ([]status.Volume, e
 	// load from config if present and volumes provided
 	if conf != nil && len(conf.Volumes) > 0 {
 		for _, v := range conf.Volumes {
-			res = append(res, status.Volume{Name: v.Name, Path: v.Path})
+			res = append(res, status.Volume{Name: v.Name, Path: v.Path, Tags: v.Tags, Labels: v.Labels})
 		}
 	}
 
@@ -148,6 +352,149 @@ func parseVolumes(volumes []string, conf *config.Parameters) ([]status.Volume, e
 	return res, nil
 }
 
+// thresholds returns actuator thresholds from config, with per-volume overrides for volumes defined in config.
+// empty thresholds returned if config is not set, and actuator uses the defaults in this case.
+func thresholds(conf *config.Parameters) actuator.Thresholds {
+	if conf == nil {
+		return actuator.Thresholds{}
+	}
+	level := func(t config.Threshold) actuator.Level { return actuator.Level{Warn: t.Warn, Down: t.Down} }
+	res := actuator.Thresholds{
+		CPU:         level(conf.Thresholds.CPU),
+		Memory:      level(conf.Thresholds.Memory),
+		Disk:        level(conf.Thresholds.Disk),
+		LoadPerCore: level(conf.Thresholds.LoadAverage),
+		Volumes:     map[string]actuator.Level{},
+	}
+	for _, v := range conf.Volumes {
+		res.Volumes[v.Name] = level(v.Threshold)
+	}
+	return res
+}
+
+// checkTLS checks that certificate and key are set together, and client CA and redirect are set with them
+func checkTLS() error {
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return errors.New("both tls-cert and tls-key should be set")
+	}
+	if opts.TLSCert == "" && (opts.TLSCA != "" || opts.TLSRedirect != "") {
+		return errors.New("tls-ca and tls-redirect require tls-cert and tls-key")
+	}
+	return nil
+}
+
+// apiAuth returns credentials of the api from config, no auth if config is not set
+func apiAuth(conf *config.Parameters) server.Auth {
+	if conf == nil {
+		return server.Auth{}
+	}
+	return server.Auth{Users: conf.Auth.Users, Tokens: conf.Auth.Tokens, PublicHealth: conf.Auth.PublicHealth}
+}
+
+// healthOptions returns show-details mode, groups and statuses of actuator health from config, defaults if config is not set
+func healthOptions(conf *config.Parameters) actuator.HealthOptions {
+	if conf == nil {
+		return actuator.HealthOptions{}
+	}
+	return actuator.HealthOptions{ShowDetails: conf.Actuator.ShowDetails, Groups: conf.Actuator.Groups,
+		StatusOrder: conf.Actuator.Status.Order, HTTPMapping: conf.Actuator.Status.HTTPMapping}
+}
+
+// maintenanceWindows returns maintenance windows from config, none if config is not set
+func maintenanceWindows(conf *config.Parameters) []maintenance.Window {
+	if conf == nil {
+		return nil
+	}
+	res := make([]maintenance.Window, 0, len(conf.Maintenance))
+	for _, m := range conf.Maintenance {
+		res = append(res, maintenance.Window{Name: m.Name, Components: m.Components, Reason: m.Reason,
+			Start: m.Start, End: m.End, Cron: m.Cron, Duration: m.Duration})
+	}
+	return res
+}
+
+// configReloader reloads config file and updates volumes, services, thresholds, auth, actuator, maintenance windows
+// and hooks of running components.
+// Components updated only if the new config is valid, otherwise the previous config keeps running.
+type configReloader struct {
+	fname    string
+	volumes  []string // volumes from command line, override config volumes
+	services []string // services from command line, merged with config services
+
+	registry  *external.Registry
+	status    *status.Service
+	extSvc    *external.Service
+	scheduler *external.Scheduler
+	srv       *server.Rest
+	hooks     *notify.Hooks // optional
+
+	maintenance *maintenance.Schedule
+
+	mu sync.Mutex // serializes reloads triggered by signal and file watcher
+}
+
+// onSignal reloads config on SIGHUP until context is canceled
+func (c *configReloader) onSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("[INFO] SIGHUP signal, reload config %s", c.fname)
+			c.run()
+		}
+	}
+}
+
+// run reloads config and reports failure
+func (c *configReloader) run() {
+	if err := c.reload(); err != nil {
+		log.Printf("[WARN] config reload failed, keep running with the previous config: %v", err)
+	}
+}
+
+// reload parses and validates config, and swaps volumes, services, thresholds, auth and hooks.
+// Checks of services with unchanged name and url keep running with their state.
+func (c *configReloader) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conf, err := config.New(c.fname)
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+	vols, err := parseVolumes(c.volumes, conf)
+	if err != nil {
+		return fmt.Errorf("can't parse volumes: %w", err)
+	}
+	reqs, err := services(c.registry, c.services, conf)
+	if err != nil {
+		return err
+	}
+	commands, err := hookCommands(conf)
+	if err != nil {
+		return err
+	}
+	if err = c.maintenance.Update(maintenanceWindows(conf)...); err != nil {
+		return err
+	}
+
+	c.status.UpdateVolumes(vols)
+	c.extSvc.Update(reqs...)
+	c.scheduler.Reload()
+	c.srv.UpdateThresholds(thresholds(conf))
+	c.srv.UpdateAuth(apiAuth(conf))
+	c.srv.UpdateHealthOptions(healthOptions(conf))
+	if c.hooks != nil {
+		c.hooks.Update(commands)
+	}
+	log.Printf("[INFO] config %s reloaded, %d volumes, %d services", c.fname, len(vols), len(c.extSvc.Requests()))
+	return nil
+}
+
 func setupLog(dbg bool) {
 	logOpts := []lgr.Option{lgr.Msec, lgr.LevelBraces, lgr.StackTraceOnError}
 	if dbg {
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"testing"
@@ -14,9 +15,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
+	"github.com/umputun/sys-agent/app/actuator"
 	"github.com/umputun/sys-agent/app/config"
+	"github.com/umputun/sys-agent/app/maintenance"
+	"github.com/umputun/sys-agent/app/notify"
+	"github.com/umputun/sys-agent/app/server"
 	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
 )
 
 func Test_parseVolumes_ArgsOnly(t *testing.T) {
@@ -60,6 +67,80 @@ func Test_parseVolumes_ArgsAndConfig(t *testing.T) {
 	assert.Equal(t, []status.Volume{{Name: "data volume", Path: "/data"}, {Name: "blah", Path: "/"}}, vols)
 }
 
+func Test_thresholds(t *testing.T) {
+	assert.Equal(t, actuator.Thresholds{}, thresholds(nil))
+
+	conf, err := config.New("config/testdata/config.yml")
+	require.NoError(t, err)
+	exp := actuator.Thresholds{
+		CPU:         actuator.Level{Warn: 80, Down: 95},
+		Memory:      actuator.Level{Down: 85},
+		LoadPerCore: actuator.Level{Warn: 1.5, Down: 3},
+		Volumes:     map[string]actuator.Level{"root": {}, "data": {Warn: 95, Down: 99}},
+	}
+	assert.Equal(t, exp, thresholds(conf))
+}
+
+func Test_checkTLS(t *testing.T) {
+	defer func(cert, key, ca, redirect string) {
+		opts.TLSCert, opts.TLSKey, opts.TLSCA, opts.TLSRedirect = cert, key, ca, redirect
+	}(opts.TLSCert, opts.TLSKey, opts.TLSCA, opts.TLSRedirect)
+
+	tbl := []struct {
+		cert, key, ca, redirect string
+		err                     string
+	}{
+		{"", "", "", "", ""},
+		{"cert.pem", "key.pem", "ca.pem", ":8080", ""},
+		{"cert.pem", "", "", "", "both tls-cert and tls-key should be set"},
+		{"", "key.pem", "", "", "both tls-cert and tls-key should be set"},
+		{"", "", "ca.pem", "", "tls-ca and tls-redirect require tls-cert and tls-key"},
+		{"", "", "", ":8080", "tls-ca and tls-redirect require tls-cert and tls-key"},
+	}
+	for i, tt := range tbl {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			opts.TLSCert, opts.TLSKey, opts.TLSCA, opts.TLSRedirect = tt.cert, tt.key, tt.ca, tt.redirect
+			err := checkTLS()
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func Test_apiAuth(t *testing.T) {
+	assert.Equal(t, server.Auth{}, apiAuth(nil))
+	conf := &config.Parameters{Auth: config.Auth{Users: map[string]string{"admin": "hash"}, Tokens: []string{"t1"},
+		PublicHealth: true}}
+	assert.Equal(t, server.Auth{Users: map[string]string{"admin": "hash"}, Tokens: []string{"t1"}, PublicHealth: true},
+		apiAuth(conf))
+}
+
+func Test_healthOptions(t *testing.T) {
+	assert.Equal(t, actuator.HealthOptions{}, healthOptions(nil))
+	conf := &config.Parameters{Actuator: config.Actuator{ShowDetails: "never",
+		Groups: map[string][]string{"liveness": {"cpu"}},
+		Status: config.Status{Order: []string{"DOWN", "UP"}, HTTPMapping: map[string]int{"WARN": 200}}}}
+	assert.Equal(t, actuator.HealthOptions{ShowDetails: actuator.ShowDetailsNever,
+		Groups:      map[string][]string{"liveness": {"cpu"}},
+		StatusOrder: []string{"DOWN", "UP"}, HTTPMapping: map[string]int{"WARN": 200}}, healthOptions(conf))
+}
+
+func Test_maintenanceWindows(t *testing.T) {
+	assert.Nil(t, maintenanceWindows(nil))
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	conf := &config.Parameters{Maintenance: []config.Maintenance{
+		{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *", Duration: time.Hour},
+		{Name: "upgrade", Components: []string{"tag:db"}, Reason: "db upgrade", Start: start, End: start.Add(time.Hour)},
+	}}
+	assert.Equal(t, []maintenance.Window{
+		{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *", Duration: time.Hour},
+		{Name: "upgrade", Components: []string{"tag:db"}, Reason: "db upgrade", Start: start, End: start.Add(time.Hour)},
+	}, maintenanceWindows(conf))
+}
+
 func Test_main(t *testing.T) {
 	port := 40000 + int(rand.Int31n(1000)) //nolint:gosec
 	os.Args = []string{"app", "--listen=127.0.0.1:" + strconv.Itoa(port), "-v root:/", "-s echo:https://echo.umputun.com", "--dbg"}
@@ -116,37 +197,185 @@ func waitForHTTPServerStart(port int) {
 }
 
 func Test_services(t *testing.T) {
+	registry, err := external.NewRegistry(&external.HTTPProvider{}, &external.FileProvider{})
+	require.NoError(t, err)
+
+	confWith := func(data string) func() *config.Parameters {
+		return func() *config.Parameters {
+			res := &config.Parameters{}
+			require.NoError(t, yaml.Unmarshal([]byte(data), res))
+			return res
+		}
+	}
+
 	tbl := []struct {
 		cli []string
 		cfg func() *config.Parameters
-		res []string
+		res []external.Request
+		err string
 	}{
 		{[]string{"echo:https://echo.umputun.com"}, func() *config.Parameters { return nil },
-			[]string{"echo:https://echo.umputun.com"}},
-
-		{
-			[]string{"s1", "s2", "s3"}, func() *config.Parameters {
-				res := &config.Parameters{}
-				res.Services.HTTP = []config.HTTP{{Name: "n1", URL: "http://example.com"}}
-				return res
-			},
-			[]string{"s1", "s2", "s3", "n1:http://example.com"},
-		},
-
-		{
-			[]string{}, func() *config.Parameters {
-				res := &config.Parameters{}
-				res.Services.HTTP = []config.HTTP{{Name: "n1", URL: "http://example.com"}}
-				return res
-			},
-			[]string{"n1:http://example.com"},
-		},
+			[]external.Request{{Name: "echo", URL: "https://echo.umputun.com"}}, ""},
+
+		{[]string{"s1:http://example.org", "s2:file:///tmp/blah"},
+			confWith("services: {http: [{name: n1, url: http://example.com}]}"),
+			[]external.Request{{Name: "s1", URL: "http://example.org"}, {Name: "s2", URL: "file:///tmp/blah"},
+				{Name: "n1", URL: "http://example.com"}}, ""},
+
+		{[]string{}, confWith("services: {http: [{name: n1, url: http://example.com}]}"),
+			[]external.Request{{Name: "n1", URL: "http://example.com"}}, ""},
+
+		{[]string{"s1:mongodb://example.com"}, func() *config.Parameters { return nil }, nil,
+			`invalid command line services: service "s1": unsupported url "mongodb://example.com"`},
+
+		{[]string{}, confWith("services: {mongo: [{name: n1, url: mongodb://example.com}]}"), nil,
+			`invalid config services: unknown services section "mongo"`},
+
+		{[]string{"s1:http://example.org?depends_on=n1"},
+			confWith("services: {http: [{name: n1, url: http://example.com}]}"),
+			[]external.Request{{Name: "s1", URL: "http://example.org", Options: external.Options{DependsOn: []string{"n1"}}},
+				{Name: "n1", URL: "http://example.com"}}, ""},
+
+		{[]string{}, confWith(`services: {http: [{name: n1, url: http://example.com, depends_on: [n2]},
+			{name: n2, url: http://example.org, depends_on: [n1]}]}`), nil,
+			"invalid services: dependency cycle n1 -> n2 -> n1"},
+
+		{[]string{"n1:http://example.org"}, confWith("services: {http: [{name: n1, url: http://example.com}]}"), nil,
+			`duplicate service name "n1", names of command line and config services should be unique`},
+
+		{[]string{"s1:http://example.org", "s1:file:///tmp/blah"}, func() *config.Parameters { return nil }, nil,
+			`duplicate service name "s1", names of command line and config services should be unique`},
 	}
 
 	for i, tt := range tbl {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			res := services(tt.cli, tt.cfg())
+			res, err := services(registry, tt.cli, tt.cfg())
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
 			assert.Equal(t, tt.res, res)
 		})
 	}
 }
+
+func Test_configReloader(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig := func(cfg string) { require.NoError(t, os.WriteFile(fname, []byte(cfg), 0o600)) }
+
+	writeConfig(`
+volumes: [{name: root, path: /}]
+services:
+  http: [{name: s1, url: "http://example.com"}]
+`)
+	registry, err := external.NewRegistry(&external.HTTPProvider{})
+	require.NoError(t, err)
+	extSvc := external.NewService(registry)
+	c := &configReloader{fname: fname, services: []string{"cli:http://example.org"}, registry: registry,
+		status: &status.Service{}, extSvc: extSvc, scheduler: external.NewScheduler(extSvc, time.Minute, 1), srv: &server.Rest{},
+		hooks: &notify.Hooks{}, maintenance: &maintenance.Schedule{}}
+
+	require.NoError(t, c.reload())
+	assert.Equal(t, []status.Volume{{Name: "root", Path: "/"}}, c.status.Volumes)
+	assert.Equal(t, []external.Request{{Name: "cli", URL: "http://example.org"}, {Name: "s1", URL: "http://example.com"}},
+		extSvc.Requests())
+	assert.Equal(t, actuator.Thresholds{Volumes: map[string]actuator.Level{"root": {}}}, c.srv.Thresholds)
+
+	writeConfig(`
+volumes: [{name: root, path: /}, {name: data, path: /data}]
+thresholds: {cpu: {warn: 70, down: 80}}
+hooks: {cpu: {on_down: "echo down"}}
+maintenance: [{name: backup, components: ["diskSpace:*"], cron: "0 3 * * *", duration: 1h}]
+services:
+  http: [{name: s2, url: "http://example.net", on_up: "echo up"}]
+`)
+	require.NoError(t, c.reload())
+	assert.Equal(t, "hooks of 2 components", c.hooks.String())
+	assert.Len(t, c.status.Volumes, 2)
+	assert.Equal(t, []external.Request{{Name: "cli", URL: "http://example.org"}, {Name: "s2", URL: "http://example.net"}},
+		extSvc.Requests())
+	assert.Equal(t, actuator.Level{Warn: 70, Down: 80}, c.srv.Thresholds.CPU)
+	require.Len(t, c.maintenance.States(time.Now()), 1)
+	assert.Equal(t, "backup", c.maintenance.States(time.Now())[0].Name)
+
+	// invalid config keeps the previous one
+	writeConfig(`
+volumes: [{name: root, path: /}]
+thresholds: {cpu: {warn: 90, down: 80}}
+`)
+	require.ErrorContains(t, c.reload(), "cpu threshold: warn level 90 is above down level 80")
+	assert.Len(t, c.status.Volumes, 2)
+	assert.Len(t, extSvc.Requests(), 2)
+	assert.Equal(t, actuator.Level{Warn: 70, Down: 80}, c.srv.Thresholds.CPU)
+
+	writeConfig(`
+volumes: [{name: root, path: /}]
+services:
+  blah: [{name: s3, url: "http://example.net"}]
+`)
+	require.ErrorContains(t, c.reload(), `unknown services section "blah"`)
+	assert.Len(t, extSvc.Requests(), 2)
+
+	writeConfig(`
+volumes: [{name: root, path: /}]
+services:
+  http: [{name: s3, url: "http://example.net", on_up: [echo, up]}]
+`)
+	require.ErrorContains(t, c.reload(), "can't get hooks")
+	assert.Len(t, c.status.Volumes, 2, "volumes kept on invalid hooks")
+	assert.Equal(t, []external.Request{{Name: "cli", URL: "http://example.org"}, {Name: "s2", URL: "http://example.net"}},
+		extSvc.Requests())
+	assert.Equal(t, "hooks of 2 components", c.hooks.String())
+
+	writeConfig("volumes: [bad")
+	require.ErrorContains(t, c.reload(), "failed to parsse config")
+	assert.Len(t, c.status.Volumes, 2)
+}
+
+func Test_openStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	st, err := openStore(dir, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, st.Close())
+	assert.FileExists(t, filepath.Join(dir, "sys-agent.db"))
+
+	fname := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(fname, []byte("blah"), 0o600))
+	_, err = openStore(fname, time.Hour)
+	require.ErrorContains(t, err, "can't make data directory")
+}
+
+func Test_makeNotifiers(t *testing.T) {
+	defer func(webhooks, emailTo []string) { opts.Webhooks, opts.EmailTo = webhooks, emailTo }(opts.Webhooks, opts.EmailTo)
+
+	opts.Webhooks = nil
+	res, err := makeNotifiers(nil)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	opts.Webhooks = []string{"https://example.com/h2", "https://example.com/h1", "", "https://example.com/h2"}
+	opts.WebhookRetries = 2
+	res, err = makeNotifiers(nil)
+	require.NoError(t, err)
+	require.Len(t, res, 2, "duplicates and empty urls ignored")
+	wh, ok := res[0].(*notify.Webhook)
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/h1", wh.URL)
+	assert.Equal(t, 2, wh.Retries)
+
+	opts.EmailTo = []string{"ops@example.com"}
+	_, err = makeNotifiers(nil)
+	require.ErrorContains(t, err, "can't make email notifier")
+
+	opts.SMTPHost, opts.SMTPPort, opts.EmailFrom = "smtp.example.com", 465, "agent@example.com"
+	res, err = makeNotifiers(nil)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, "email smtp.example.com:465 to ops@example.com", res[2].String())
+
+	res, err = makeNotifiers(&notify.Hooks{})
+	require.NoError(t, err)
+	require.Len(t, res, 4)
+	assert.Equal(t, "hooks of 0 components", res[0].String())
+}
@@ -0,0 +1,193 @@
+// Package maintenance keeps planned maintenance windows of components, from config or added by api,
+// so components are reported as OUT_OF_SERVICE instead of DOWN during the window.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+// Window is a maintenance window of components, absolute with start and end, or recurring by cron with duration
+type Window struct {
+	Name       string
+	Components []string      // component names, glob patterns or "tag:" tags, the same as members of health groups
+	Reason     string        // optional description, i.e. "mongo upgrade"
+	Start      time.Time     // start of absolute window
+	End        time.Time     // end of absolute window
+	Cron       string        // start times of recurring window, i.e. "0 3 * * 0"
+	Duration   time.Duration // length of recurring window
+
+	manual   bool          // added by api
+	schedule cron.Schedule // parsed cron
+}
+
+// State is the window with its current or the next period, as reported by api
+type State struct {
+	Name       string    `json:"name"`
+	Components []string  `json:"components"`
+	Reason     string    `json:"reason,omitempty"`
+	Cron       string    `json:"cron,omitempty"`
+	Manual     bool      `json:"manual"` // added by api, not kept on restart
+	Active     bool      `json:"active"`
+	Start      time.Time `json:"start"` // start of the current period if active, of the next one otherwise
+	End        time.Time `json:"end"`
+}
+
+// Schedule keeps windows from config and ones added by api, the latter are removed after their end
+type Schedule struct {
+	MaxDuration time.Duration // max duration of windows added by api, not limited if not set
+
+	mu      sync.RWMutex
+	windows []Window // from config
+	manual  []Window // added by api
+}
+
+// New makes schedule with windows from config, returns error if any window is invalid
+func New(windows ...Window) (*Schedule, error) {
+	res := &Schedule{}
+	if err := res.Update(windows...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Update replaces windows from config, i.e. on config reload. Windows added by api are kept.
+func (s *Schedule) Update(windows ...Window) error {
+	parsed := make([]Window, 0, len(windows))
+	for _, w := range windows {
+		if err := w.parse(); err != nil {
+			return fmt.Errorf("maintenance window %q: %w", w.Name, err)
+		}
+		parsed = append(parsed, w)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = parsed
+	return nil
+}
+
+// Add adds window starting now for the duration, replacing window added by api with the same name.
+// Returns error if the window is invalid, longer than MaxDuration or its name is used by a window from config.
+func (s *Schedule) Add(name string, components []string, reason string, duration time.Duration, now time.Time) error {
+	if duration <= 0 {
+		return errors.New("duration should be positive")
+	}
+	if s.MaxDuration > 0 && duration > s.MaxDuration {
+		return fmt.Errorf("duration %v is above max %v", duration, s.MaxDuration)
+	}
+	w := Window{Name: name, Components: components, Reason: reason, Start: now, End: now.Add(duration), manual: true}
+	if err := w.parse(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.windows, func(cw Window) bool { return cw.Name == name }) {
+		return fmt.Errorf("window %q is defined in config", name)
+	}
+	s.manual = slices.DeleteFunc(s.manual, func(mw Window) bool { return mw.Name == name || !now.Before(mw.End) })
+	s.manual = append(s.manual, w)
+	return nil
+}
+
+// Remove removes window added by api, returns false if there is no such window
+func (s *Schedule) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.manual)
+	s.manual = slices.DeleteFunc(s.manual, func(w Window) bool { return w.Name == name })
+	return len(s.manual) < n
+}
+
+// Active returns the name of the first active window matching the component, by name or tags
+func (s *Schedule) Active(component string, tags []string, now time.Time) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range slices.Concat(s.windows, s.manual) {
+		if !w.active(now) {
+			continue
+		}
+		if slices.ContainsFunc(w.Components, func(p string) bool { return actuator.Match(p, component, tags) }) {
+			return w.Name, true
+		}
+	}
+	return "", false
+}
+
+// States returns all windows with their current or the next periods. Windows without the next period are skipped,
+// i.e. past absolute windows.
+func (s *Schedule) States(now time.Time) []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	res := []State{}
+	for _, w := range slices.Concat(s.windows, s.manual) {
+		start, end := w.period(now)
+		if start.IsZero() || !now.Before(end) {
+			continue
+		}
+		res = append(res, State{Name: w.Name, Components: w.Components, Reason: w.Reason, Cron: w.Cron, Manual: w.manual,
+			Active: w.active(now), Start: start, End: end})
+	}
+	return res
+}
+
+// Validate checks the window is valid, i.e. window from config before it is used
+func (w Window) Validate() error {
+	return w.parse()
+}
+
+// parse checks the window is valid and parses its cron, the same way as cron of service checks
+func (w *Window) parse() error {
+	if w.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(w.Components) == 0 {
+		return errors.New("no components")
+	}
+	for _, c := range w.Components {
+		if _, err := path.Match(strings.TrimPrefix(c, "tag:"), ""); err != nil {
+			return fmt.Errorf("invalid component pattern %q: %w", c, err)
+		}
+	}
+	switch {
+	case w.Cron != "" && (!w.Start.IsZero() || !w.End.IsZero()):
+		return errors.New("either cron or start and end should be set")
+	case w.Cron != "":
+		sched, err := external.ParseCron(w.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron %q: %w", w.Cron, err)
+		}
+		if w.Duration <= 0 {
+			return errors.New("duration of cron window should be positive")
+		}
+		w.schedule = sched
+	case w.Start.IsZero() || !w.End.After(w.Start):
+		return errors.New("start and end should be set, end after start, or cron with duration")
+	}
+	return nil
+}
+
+// period returns start and end of the current period of the window, or the next one if not active
+func (w Window) period(now time.Time) (start, end time.Time) {
+	if w.schedule == nil {
+		return w.Start, w.End
+	}
+	start = w.schedule.Next(now.Add(-w.Duration)) // the first start after which the window is not over yet
+	return start, start.Add(w.Duration)
+}
+
+// active returns true if now is within the window
+func (w Window) active(now time.Time) bool {
+	start, end := w.period(now)
+	return !now.Before(start) && now.Before(end)
+}
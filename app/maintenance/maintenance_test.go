@@ -0,0 +1,146 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tbl := []struct {
+		name   string
+		window Window
+		err    string
+	}{
+		{"absolute", Window{Name: "w", Components: []string{"service:*"}, Start: start, End: start.Add(time.Hour)}, ""},
+		{"cron", Window{Name: "w", Components: []string{"tag:db"}, Cron: "0 3 * * 0", Duration: time.Hour}, ""},
+		{"no name", Window{Components: []string{"cpu"}, Start: start, End: start.Add(time.Hour)}, "name is required"},
+		{"no components", Window{Name: "w", Start: start, End: start.Add(time.Hour)}, "no components"},
+		{"bad pattern", Window{Name: "w", Components: []string{"tag:[a"}, Start: start, End: start.Add(time.Hour)},
+			`invalid component pattern "tag:[a"`},
+		{"no period", Window{Name: "w", Components: []string{"cpu"}}, "start and end should be set, end after start"},
+		{"end before start", Window{Name: "w", Components: []string{"cpu"}, Start: start, End: start.Add(-time.Hour)},
+			"start and end should be set, end after start"},
+		{"cron and start", Window{Name: "w", Components: []string{"cpu"}, Cron: "0 3 * * 0", Duration: time.Hour, Start: start},
+			"either cron or start and end should be set"},
+		{"bad cron", Window{Name: "w", Components: []string{"cpu"}, Cron: "blah", Duration: time.Hour}, `invalid cron "blah"`},
+		{"cron descriptor", Window{Name: "w", Components: []string{"cpu"}, Cron: "@daily", Duration: time.Hour},
+			`invalid cron "@daily"`}, // the same cron as service checks, without descriptors
+		{"no duration", Window{Name: "w", Components: []string{"cpu"}, Cron: "0 3 * * 0"},
+			"duration of cron window should be positive"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.window)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSchedule_Active(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s, err := New(
+		Window{Name: "upgrade", Components: []string{"service:mongo", "tag:db"}, Start: start, End: start.Add(time.Hour)},
+		Window{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *", Duration: 30 * time.Minute},
+	)
+	require.NoError(t, err)
+
+	tbl := []struct {
+		component string
+		tags      []string
+		now       time.Time
+		window    string
+	}{
+		{"service:mongo", nil, start, "upgrade"},
+		{"service:mongo", nil, start.Add(30 * time.Minute), "upgrade"},
+		{"service:mongo", nil, start.Add(time.Hour), ""},
+		{"service:mongo", nil, start.Add(-time.Second), ""},
+		{"service:pg", []string{"db"}, start.Add(time.Minute), "upgrade"},
+		{"service:pg", []string{"web"}, start.Add(time.Minute), ""},
+		{"diskSpace:root", nil, time.Date(2024, 5, 2, 3, 10, 0, 0, time.UTC), "backup"},
+		{"diskSpace:root", nil, time.Date(2024, 5, 2, 3, 30, 0, 0, time.UTC), ""},
+		{"diskSpace:root", nil, time.Date(2024, 5, 2, 2, 59, 0, 0, time.UTC), ""},
+		{"cpu", nil, time.Date(2024, 5, 2, 3, 10, 0, 0, time.UTC), ""},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.component+" "+tt.now.Format(time.TimeOnly), func(t *testing.T) {
+			name, ok := s.Active(tt.component, tt.tags, tt.now)
+			assert.Equal(t, tt.window, name)
+			assert.Equal(t, tt.window != "", ok)
+		})
+	}
+}
+
+func TestSchedule_AddRemove(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s, err := New(Window{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *", Duration: time.Hour})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Add("deploy", []string{"service:web"}, "release", time.Hour, now))
+	name, ok := s.Active("service:web", nil, now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, "deploy", name)
+	_, ok = s.Active("service:web", nil, now.Add(time.Hour))
+	assert.False(t, ok, "over after the duration")
+
+	// the same name replaces the window
+	require.NoError(t, s.Add("deploy", []string{"service:api"}, "", 2*time.Hour, now))
+	_, ok = s.Active("service:web", nil, now)
+	assert.False(t, ok)
+	_, ok = s.Active("service:api", nil, now.Add(90*time.Minute))
+	assert.True(t, ok)
+
+	require.ErrorContains(t, s.Add("backup", []string{"cpu"}, "", time.Hour, now), `window "backup" is defined in config`)
+	require.ErrorContains(t, s.Add("x", []string{"cpu"}, "", 0, now), "duration should be positive")
+	require.ErrorContains(t, s.Add("x", nil, "", time.Hour, now), "no components")
+	s.MaxDuration = 2 * time.Hour
+	require.ErrorContains(t, s.Add("x", []string{"*"}, "", 8760*time.Hour, now), "duration 8760h0m0s is above max 2h0m0s")
+	s.MaxDuration = 0
+
+	// windows added by api are kept on config update
+	require.NoError(t, s.Update())
+	_, ok = s.Active("service:api", nil, now)
+	assert.True(t, ok)
+	require.Error(t, s.Update(Window{Name: "bad"}))
+
+	assert.True(t, s.Remove("deploy"))
+	assert.False(t, s.Remove("deploy"))
+	_, ok = s.Active("service:api", nil, now)
+	assert.False(t, ok)
+}
+
+func TestSchedule_States(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s, err := New(
+		Window{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *", Duration: time.Hour},
+		Window{Name: "past", Components: []string{"cpu"}, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)},
+		Window{Name: "upgrade", Components: []string{"tag:db"}, Reason: "mongo upgrade",
+			Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+	)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("deploy", []string{"service:web"}, "", time.Hour, now.Add(-time.Minute)))
+
+	assert.Equal(t, []State{
+		{Name: "backup", Components: []string{"diskSpace:*"}, Cron: "0 3 * * *",
+			Start: time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC), End: time.Date(2024, 5, 2, 4, 0, 0, 0, time.UTC)},
+		{Name: "upgrade", Components: []string{"tag:db"}, Reason: "mongo upgrade", Active: true,
+			Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+		{Name: "deploy", Components: []string{"service:web"}, Manual: true, Active: true,
+			Start: now.Add(-time.Minute), End: now.Add(59 * time.Minute)},
+	}, s.States(now))
+
+	// active cron window reports the current period
+	states := s.States(time.Date(2024, 5, 2, 3, 30, 0, 0, time.UTC))
+	require.Len(t, states, 1)
+	assert.True(t, states[0].Active)
+	assert.Equal(t, time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC), states[0].Start)
+}
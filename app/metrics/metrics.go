@@ -0,0 +1,137 @@
+// Package metrics renders status info in prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/tagset"
+)
+
+// ContentType is the content type of prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const prefix = "sys_agent_"
+
+// labelEscaper escapes label values as required by the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Write renders status info as a set of prometheus gauges. Numeric fields from the top level
+// of each service body (docker counters, certificate days left, rmq messages and so on) are
+// reported as sys_agent_service_value with "service" and "field" labels.
+// Labels of volumes and services are added to their samples.
+func Write(w io.Writer, info *status.Info) error {
+	if info == nil {
+		return fmt.Errorf("no status info")
+	}
+	g := &gauges{}
+
+	g.family("cpu_percent", "cpu utilization, percent")
+	g.sample("cpu_percent", float64(info.CPUPercent))
+
+	g.family("memory_percent", "memory utilization, percent")
+	g.sample("memory_percent", float64(info.MemPercent))
+
+	g.family("load_average", "system load average")
+	g.sample("load_average", info.Loads.One, "period", "1m")
+	g.sample("load_average", info.Loads.Five, "period", "5m")
+	g.sample("load_average", info.Loads.Fifteen, "period", "15m")
+
+	g.family("uptime_seconds", "host uptime, seconds")
+	g.sample("uptime_seconds", float64(info.Uptime))
+
+	g.family("procs", "number of running processes")
+	g.sample("procs", float64(info.Procs))
+
+	if len(info.Volumes) > 0 {
+		g.family("volume_usage_percent", "volume utilization, percent")
+		for _, name := range slices.Sorted(maps.Keys(info.Volumes)) {
+			v := info.Volumes[name]
+			g.sample("volume_usage_percent", float64(v.UsagePercent), tagset.Pairs(v.Labels, "volume", name, "path", v.Path)...)
+		}
+	}
+
+	if len(info.ExtServices) > 0 {
+		g.services(info.ExtServices)
+	}
+
+	_, err := g.buf.WriteTo(w)
+	return err
+}
+
+// gauges accumulates gauge families and samples in text exposition format
+type gauges struct {
+	buf bytes.Buffer
+}
+
+// family writes HELP and TYPE headers of a gauge family
+func (g *gauges) family(name, help string) {
+	fmt.Fprintf(&g.buf, "# HELP %s%s %s\n", prefix, name, help)
+	fmt.Fprintf(&g.buf, "# TYPE %s%s gauge\n", prefix, name)
+}
+
+// sample writes a single sample, labels are passed as key, value pairs
+func (g *gauges) sample(name string, value float64, labels ...string) {
+	g.buf.WriteString(prefix + name)
+	if len(labels) > 1 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+		}
+		g.buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	g.buf.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+// services adds status code, response time and numeric body fields for each service
+func (g *gauges) services(svcs map[string]external.Response) {
+	names := slices.Sorted(maps.Keys(svcs))
+
+	g.family("service_status_code", "status code of the service check")
+	for _, name := range names {
+		g.sample("service_status_code", float64(svcs[name].StatusCode), tagset.Pairs(svcs[name].Labels, "service", name)...)
+	}
+
+	g.family("service_response_time_seconds", "response time of the service check, seconds")
+	for _, name := range names {
+		g.sample("service_response_time_seconds", float64(svcs[name].ResponseTime)/1000,
+			tagset.Pairs(svcs[name].Labels, "service", name)...)
+	}
+
+	g.family("service_value", "numeric field reported in the service check body")
+	for _, name := range names {
+		body := svcs[name].Body
+		for _, field := range slices.Sorted(maps.Keys(body)) {
+			if v, ok := toFloat(body[field]); ok {
+				g.sample("service_value", v, tagset.Pairs(svcs[name].Labels, "service", name, "field", field)...)
+			}
+		}
+	}
+}
+
+// toFloat converts numeric body values to float64, reports false for non-numeric values
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestWrite(t *testing.T) {
+	info := &status.Info{
+		CPUPercent: 25,
+		MemPercent: 50,
+		Procs:      123,
+		Uptime:     3600,
+		Volumes: map[string]status.Volume{
+			"root": {Name: "root", Path: "/", UsagePercent: 45},
+			"data": {Name: "data", Path: "/data", UsagePercent: 60},
+		},
+		ExtServices: map[string]external.Response{
+			"docker": {Name: "docker", StatusCode: 200, ResponseTime: 15,
+				Body: map[string]any{"running": 4, "healthy": 1, "required": "ok", "containers": map[string]any{}}},
+			"cert":  {Name: "cert", StatusCode: 200, ResponseTime: 1500, Body: map[string]any{"days_left": 73, "status": "ok"}},
+			"mongo": {Name: "mongo", StatusCode: 500, ResponseTime: 5000, Body: map[string]any{"count": int64(42)}},
+			"web":   {Name: "web", StatusCode: 200, ResponseTime: 10, Body: map[string]any{"rate": 0.5}},
+		},
+	}
+	info.Loads.One, info.Loads.Five, info.Loads.Fifteen = 1.5, 1.25, 1
+
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, info))
+	res := buf.String()
+	t.Log(res)
+
+	for _, line := range []string{
+		"# TYPE sys_agent_cpu_percent gauge",
+		"sys_agent_cpu_percent 25",
+		"sys_agent_memory_percent 50",
+		`sys_agent_load_average{period="1m"} 1.5`,
+		`sys_agent_load_average{period="5m"} 1.25`,
+		`sys_agent_load_average{period="15m"} 1`,
+		"sys_agent_uptime_seconds 3600",
+		"sys_agent_procs 123",
+		`sys_agent_volume_usage_percent{volume="data",path="/data"} 60`,
+		`sys_agent_volume_usage_percent{volume="root",path="/"} 45`,
+		`sys_agent_service_status_code{service="mongo"} 500`,
+		`sys_agent_service_response_time_seconds{service="cert"} 1.5`,
+		`sys_agent_service_value{service="docker",field="healthy"} 1`,
+		`sys_agent_service_value{service="docker",field="running"} 4`,
+		`sys_agent_service_value{service="cert",field="days_left"} 73`,
+		`sys_agent_service_value{service="mongo",field="count"} 42`,
+		`sys_agent_service_value{service="web",field="rate"} 0.5`,
+	} {
+		assert.Contains(t, res, line+"\n")
+	}
+	assert.NotContains(t, res, `field="required"`, "non-numeric fields should be skipped")
+	assert.NotContains(t, res, `field="containers"`, "nested fields should be skipped")
+	assert.Less(t, strings.Index(res, `volume="data"`), strings.Index(res, `volume="root"`), "volumes should be sorted")
+}
+
+func TestWrite_NoServices(t *testing.T) {
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, &status.Info{CPUPercent: 10}))
+	assert.Contains(t, buf.String(), "sys_agent_cpu_percent 10\n")
+	assert.NotContains(t, buf.String(), "sys_agent_service_")
+	assert.NotContains(t, buf.String(), "sys_agent_volume_")
+}
+
+func TestWrite_EscapeLabels(t *testing.T) {
+	info := &status.Info{Volumes: map[string]status.Volume{`a"b`: {Name: `a"b`, Path: `c:\d`}}}
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, info))
+	assert.Contains(t, buf.String(), `sys_agent_volume_usage_percent{volume="a\"b",path="c:\\d"} 0`)
+}
+
+func TestWrite_Labels(t *testing.T) {
+	info := &status.Info{
+		Volumes: map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 5,
+			Labels: map[string]string{"team": "infra"}}},
+		ExtServices: map[string]external.Response{"pay": {Name: "pay", StatusCode: 200, ResponseTime: 100,
+			Body: map[string]any{"queued": 3}, Labels: map[string]string{"team": "billing", "env": "prod"}}},
+	}
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, info))
+	assert.Contains(t, buf.String(), `sys_agent_volume_usage_percent{volume="root",path="/",team="infra"} 5`)
+	assert.Contains(t, buf.String(), `sys_agent_service_status_code{service="pay",env="prod",team="billing"} 200`)
+	assert.Contains(t, buf.String(), `sys_agent_service_response_time_seconds{service="pay",env="prod",team="billing"} 0.1`)
+	assert.Contains(t, buf.String(), `sys_agent_service_value{service="pay",field="queued",env="prod",team="billing"} 3`)
+}
+
+func TestWrite_NilInfo(t *testing.T) {
+	require.Error(t, Write(&bytes.Buffer{}, nil))
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// defaultEmailTemplate is the body of the email, executed with emailData
+const defaultEmailTemplate = `{{range .Events -}}
+{{.Component}} on {{.Hostname}} is {{.NewStatus}}, was {{.OldStatus}}, since {{.Time.Format "2006-01-02 15:04:05 MST"}}
+{{- range $k, $v := .Details}}
+  {{$k}}: {{$v}}
+{{- end}}
+
+{{end -}}
+`
+
+// EmailParams defines smtp server, sender, recipients and body template of emails
+type EmailParams struct {
+	Host     string
+	Port     int
+	Username string // smtp auth is used if set
+	Password string
+	TLS      bool // connect with tls, usually on port 465
+	StartTLS bool // upgrade plain connection with STARTTLS, usually on port 587
+	Timeout  time.Duration
+
+	From         string
+	To           []string
+	BatchWindow  time.Duration // events within the window are sent in one email, zero sends each event immediately
+	TemplateFile string        // text/template of the body, optional
+}
+
+// Email sends events by email. Events within the batch window after the first one are sent in one email.
+type Email struct {
+	EmailParams
+	tmpl *template.Template
+
+	mu    sync.Mutex
+	batch []Event
+	timer *time.Timer
+}
+
+// emailData is passed to the body template
+type emailData struct {
+	Hostname string
+	Events   []Event
+}
+
+// NewEmail makes email notifier with the body template loaded from the file, or the default one
+func NewEmail(params EmailParams) (*Email, error) {
+	if params.Host == "" || params.From == "" || len(params.To) == 0 {
+		return nil, fmt.Errorf("smtp host, from and to are required")
+	}
+	if params.TLS && params.StartTLS {
+		return nil, fmt.Errorf("tls and starttls can't be used together")
+	}
+	text := defaultEmailTemplate
+	if params.TemplateFile != "" {
+		data, err := os.ReadFile(params.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read email template: %w", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("email").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse email template: %w", err)
+	}
+	return &Email{EmailParams: params, tmpl: tmpl}, nil
+}
+
+// Send sends the event immediately if batch window is not set, otherwise adds it to the batch sent at the end
+// of the window, or by Flush. Errors of batched sends are logged.
+func (e *Email) Send(_ context.Context, ev Event) error {
+	if e.BatchWindow <= 0 {
+		return e.send([]Event{ev})
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batch = append(e.batch, ev)
+	if e.timer == nil {
+		e.timer = time.AfterFunc(e.BatchWindow, e.Flush)
+	}
+	return nil
+}
+
+// Flush sends the batched events without waiting for the end of the batch window, i.e. on shutdown
+func (e *Email) Flush() {
+	e.mu.Lock()
+	batch := e.batch
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.batch, e.timer = nil, nil
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if err := e.send(batch); err != nil {
+		log.Printf("[WARN] can't send %d events by %s, %v", len(batch), e, err)
+	}
+}
+
+// send makes the message of events and sends it
+func (e *Email) send(events []Event) error {
+	msg, err := e.message(events, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := e.sendMail(msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message makes email with headers and body of the events
+func (e *Email) message(events []Event, now time.Time) ([]byte, error) {
+	hostname := events[0].Hostname
+	body := bytes.Buffer{}
+	if err := e.tmpl.Execute(&body, emailData{Hostname: hostname, Events: events}); err != nil {
+		return nil, fmt.Errorf("can't execute email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("%s is %s on %s", events[0].Component, events[0].NewStatus, hostname)
+	if len(events) > 1 {
+		subject = fmt.Sprintf("%d state changes on %s", len(events), hostname)
+	}
+
+	res := bytes.Buffer{}
+	res.WriteString("From: " + e.From + "\r\n")
+	res.WriteString("To: " + strings.Join(e.To, ", ") + "\r\n")
+	res.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	res.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	res.WriteString("MIME-Version: 1.0\r\n")
+	res.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	res.WriteString("\r\n")
+	res.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return res.Bytes(), nil
+}
+
+// sendMail connects to smtp server, with tls or STARTTLS if set, authenticates if username set, and sends the message
+func (e *Email) sendMail(msg []byte) error {
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	tlsConf := &tls.Config{ServerName: e.Host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: e.Timeout}
+
+	var conn net.Conn
+	var err error
+	if e.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConf)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", addr, err)
+	}
+	if e.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(e.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("can't make smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if e.StartTLS {
+		if err = client.StartTLS(tlsConf); err != nil {
+			return fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+	if e.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return fmt.Errorf("auth failed: %w", err)
+		}
+	}
+	if err = client.Mail(e.From); err != nil {
+		return fmt.Errorf("bad from address %s: %w", e.From, err)
+	}
+	for _, to := range e.To {
+		if err = client.Rcpt(to); err != nil {
+			return fmt.Errorf("bad to address %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("can't start data: %w", err)
+	}
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("can't write message: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("can't close data: %w", err)
+	}
+	return client.Quit()
+}
+
+// String returns smtp server and recipients
+func (e *Email) String() string {
+	return fmt.Sprintf("email %s to %s", net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), strings.Join(e.To, ","))
+}
@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmail_Send(t *testing.T) {
+	srv := newFakeSMTP(t)
+	email, err := NewEmail(EmailParams{Host: "127.0.0.1", Port: srv.port, Username: "user", Password: "passwd",
+		Timeout: time.Second, From: "agent@example.com", To: []string{"ops@example.com", "dev@example.com"}})
+	require.NoError(t, err)
+
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	err = email.Send(context.Background(), Event{Component: "service:s1", Hostname: "h1", OldStatus: "UP",
+		NewStatus: "DOWN", Details: map[string]any{"status_code": 500, "reason": "bad"}, Time: ts})
+	require.NoError(t, err)
+
+	msg := srv.next(t)
+	assert.Equal(t, "agent@example.com", msg.from)
+	assert.Equal(t, []string{"ops@example.com", "dev@example.com"}, msg.to)
+	assert.Equal(t, "PLAIN "+base64.StdEncoding.EncodeToString([]byte("\x00user\x00passwd")), msg.auth)
+	assert.Contains(t, msg.data, "Subject: service:s1 is DOWN on h1\r\n")
+	assert.Contains(t, msg.data, "To: ops@example.com, dev@example.com\r\n")
+	assert.Contains(t, msg.data, "service:s1 on h1 is DOWN, was UP, since 2026-01-02 15:00:00 UTC\r\n"+
+		"  reason: bad\r\n  status_code: 500\r\n")
+	assert.Equal(t, "email 127.0.0.1:"+strconv.Itoa(srv.port)+" to ops@example.com,dev@example.com", email.String())
+}
+
+func TestEmail_SendBatch(t *testing.T) {
+	srv := newFakeSMTP(t)
+	tmpl := filepath.Join(t.TempDir(), "email.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("{{.Hostname}}:{{range .Events}} {{.Component}}={{.NewStatus}}{{end}}\n"), 0o600))
+	email, err := NewEmail(EmailParams{Host: "127.0.0.1", Port: srv.port, Timeout: time.Second, From: "agent@example.com",
+		To: []string{"ops@example.com"}, BatchWindow: 50 * time.Millisecond, TemplateFile: tmpl})
+	require.NoError(t, err)
+
+	require.NoError(t, email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "DOWN"}))
+	require.NoError(t, email.Send(context.Background(), Event{Component: "service:s1", Hostname: "h1", NewStatus: "DOWN"}))
+	require.NoError(t, email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "UP"}))
+
+	msg := srv.next(t)
+	assert.Empty(t, msg.auth, "no auth without username")
+	assert.Contains(t, msg.data, "Subject: 3 state changes on h1\r\n")
+	assert.True(t, strings.HasSuffix(msg.data, "\r\n\r\nh1: cpu=DOWN service:s1=DOWN cpu=UP\r\n"), msg.data)
+
+	require.NoError(t, email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "DOWN"}))
+	msg = srv.next(t)
+	assert.Contains(t, msg.data, "Subject: cpu is DOWN on h1\r\n", "next batch")
+}
+
+func TestEmail_Flush(t *testing.T) {
+	srv := newFakeSMTP(t)
+	email, err := NewEmail(EmailParams{Host: "127.0.0.1", Port: srv.port, Timeout: time.Second, From: "agent@example.com",
+		To: []string{"ops@example.com"}, BatchWindow: time.Hour})
+	require.NoError(t, err)
+
+	require.NoError(t, email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "DOWN"}))
+	require.NoError(t, email.Send(context.Background(), Event{Component: "service:s1", Hostname: "h1", NewStatus: "DOWN"}))
+	email.Flush()
+
+	msg := srv.next(t)
+	assert.Contains(t, msg.data, "Subject: 2 state changes on h1\r\n", "flushed without waiting for the window")
+	email.Flush() // nothing batched, no-op
+}
+
+func TestEmail_SendFailed(t *testing.T) {
+	srv := newFakeSMTP(t)
+	email, err := NewEmail(EmailParams{Host: "127.0.0.1", Port: srv.port, Timeout: time.Second, TLS: true,
+		From: "agent@example.com", To: []string{"ops@example.com"}})
+	require.NoError(t, err)
+	err = email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "DOWN"})
+	require.ErrorContains(t, err, "failed to send email", "tls to plain server")
+
+	email, err = NewEmail(EmailParams{Host: "127.0.0.1", Port: 1, Timeout: time.Second,
+		From: "agent@example.com", To: []string{"ops@example.com"}})
+	require.NoError(t, err)
+	err = email.Send(context.Background(), Event{Component: "cpu", Hostname: "h1", NewStatus: "DOWN"})
+	require.ErrorContains(t, err, "can't connect to 127.0.0.1:1")
+}
+
+func TestNewEmail(t *testing.T) {
+	_, err := NewEmail(EmailParams{Host: "127.0.0.1", From: "agent@example.com"})
+	require.ErrorContains(t, err, "smtp host, from and to are required")
+
+	_, err = NewEmail(EmailParams{Host: "127.0.0.1", From: "a@example.com", To: []string{"b@example.com"}, TLS: true, StartTLS: true})
+	require.ErrorContains(t, err, "tls and starttls can't be used together")
+
+	_, err = NewEmail(EmailParams{Host: "127.0.0.1", From: "a@example.com", To: []string{"b@example.com"},
+		TemplateFile: "/no-such-file"})
+	require.ErrorContains(t, err, "can't read email template")
+
+	tmpl := filepath.Join(t.TempDir(), "email.tmpl")
+	require.NoError(t, os.WriteFile(tmpl, []byte("{{.Blah"), 0o600))
+	_, err = NewEmail(EmailParams{Host: "127.0.0.1", From: "a@example.com", To: []string{"b@example.com"},
+		TemplateFile: tmpl})
+	require.ErrorContains(t, err, "can't parse email template")
+}
+
+// fakeSMTP is a minimal smtp server accepting all messages
+type fakeSMTP struct {
+	port int
+	msgs chan smtpMessage
+}
+
+type smtpMessage struct {
+	from, auth, data string
+	to               []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	res := &fakeSMTP{port: ln.Addr().(*net.TCPAddr).Port, msgs: make(chan smtpMessage, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go res.serve(conn)
+		}
+	}()
+	return res
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	msg := smtpMessage{}
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case cmd == "AUTH":
+			msg.auth = strings.TrimPrefix(line, "AUTH ")
+			reply("235 authenticated")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			data := strings.Builder{}
+			for {
+				l, err := rd.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			f.msgs <- msg
+			msg = smtpMessage{}
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (f *fakeSMTP) next(t *testing.T) smtpMessage {
+	t.Helper()
+	select {
+	case m := <-f.msgs:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return smtpMessage{}
+	}
+}
@@ -0,0 +1,73 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package notify
+
+import (
+	"sync"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+)
+
+// EvaluatorMock is a mock implementation of Evaluator.
+//
+//	func TestSomethingThatUsesEvaluator(t *testing.T) {
+//
+//		// make and configure a mocked Evaluator
+//		mockedEvaluator := &EvaluatorMock{
+//			HealthFunc: func(info *status.Info) *actuator.HealthResponse {
+//				panic("mock out the Health method")
+//			},
+//		}
+//
+//		// use mockedEvaluator in code that requires Evaluator
+//		// and then make assertions.
+//
+//	}
+type EvaluatorMock struct {
+	// HealthFunc mocks the Health method.
+	HealthFunc func(info *status.Info) *actuator.HealthResponse
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Health holds details about calls to the Health method.
+		Health []struct {
+			// Info is the info argument value.
+			Info *status.Info
+		}
+	}
+	lockHealth sync.RWMutex
+}
+
+// Health calls HealthFunc.
+func (mock *EvaluatorMock) Health(info *status.Info) *actuator.HealthResponse {
+	if mock.HealthFunc == nil {
+		panic("EvaluatorMock.HealthFunc: method is nil but Evaluator.Health was just called")
+	}
+	callInfo := struct {
+		Info *status.Info
+	}{
+		Info: info,
+	}
+	mock.lockHealth.Lock()
+	mock.calls.Health = append(mock.calls.Health, callInfo)
+	mock.lockHealth.Unlock()
+	return mock.HealthFunc(info)
+}
+
+// HealthCalls gets all the calls that were made to Health.
+// Check the length with:
+//
+//	len(mockedEvaluator.HealthCalls())
+func (mock *EvaluatorMock) HealthCalls() []struct {
+	Info *status.Info
+} {
+	var calls []struct {
+		Info *status.Info
+	}
+	mock.lockHealth.RLock()
+	calls = mock.calls.Health
+	mock.lockHealth.RUnlock()
+	return calls
+}
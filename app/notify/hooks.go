@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/actuator"
+)
+
+// Hook defines shell commands run when the component goes DOWN or recovers, empty command is not run
+type Hook struct {
+	OnDown string
+	OnUp   string
+}
+
+// Hooks runs commands of components on their state changes. The event is passed as json on stdin and as
+// SYS_AGENT_* environment variables. The same hook of the component is not run again within the cooldown.
+type Hooks struct {
+	Timeout  time.Duration // max run time of a command
+	Cooldown time.Duration // min time between runs of the same hook of the component
+
+	mu       sync.Mutex
+	commands map[string]Hook      // by component name, i.e. "service:s1" or "cpu"
+	lastRun  map[string]time.Time // by component name and new status
+}
+
+// Update replaces hooks of components, i.e. on config reload
+func (h *Hooks) Update(commands map[string]Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = commands
+}
+
+// Send runs the hook of the event component, if any, and returns error if the command failed
+func (h *Hooks) Send(ctx context.Context, ev Event) error {
+	h.mu.Lock()
+	hook := h.commands[ev.Component]
+	command := hook.OnUp
+	if ev.NewStatus == actuator.StatusDown {
+		command = hook.OnDown
+	}
+	key := ev.Component + ":" + ev.NewStatus
+	if command == "" {
+		h.mu.Unlock()
+		return nil
+	}
+	if last, ok := h.lastRun[key]; ok && time.Since(last) < h.Cooldown {
+		h.mu.Unlock()
+		log.Printf("[INFO] skip %s hook of %s, last run at %s", ev.NewStatus, ev.Component, last.Format(time.RFC3339))
+		return nil
+	}
+	if h.lastRun == nil {
+		h.lastRun = map[string]time.Time{}
+	}
+	h.lastRun[key] = time.Now()
+	h.mu.Unlock()
+
+	return h.run(ctx, command, ev)
+}
+
+// run executes the command with shell, the event as json on stdin and in environment variables
+func (h *Hooks) run(ctx context.Context, command string, ev Event) error {
+	stdin, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	log.Printf("[INFO] run %s hook of %s: %s", ev.NewStatus, ev.Component, command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec // we trust the command as it comes from the config
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Env = append(os.Environ(), hookEnv(ev)...)
+	cmd.WaitDelay = time.Second // don't wait for output of children left running after the timeout
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		log.Printf("[DEBUG] %s hook of %s output: %s", ev.NewStatus, ev.Component, strings.TrimSpace(string(out)))
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("hook %q of %s timed out: %w", command, ev.Component, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("hook %q of %s failed: %w", command, ev.Component, err)
+	}
+	return nil
+}
+
+// hookEnv returns environment variables of the event
+func hookEnv(ev Event) []string {
+	res := []string{
+		"SYS_AGENT_COMPONENT=" + ev.Component,
+		"SYS_AGENT_HOSTNAME=" + ev.Hostname,
+		"SYS_AGENT_OLD_STATUS=" + ev.OldStatus,
+		"SYS_AGENT_NEW_STATUS=" + ev.NewStatus,
+		"SYS_AGENT_TIME=" + ev.Time.Format(time.RFC3339),
+	}
+	if name, ok := strings.CutPrefix(ev.Component, "service:"); ok {
+		res = append(res, "SYS_AGENT_SERVICE="+name)
+	}
+	for _, key := range []string{"reason", "error"} {
+		if v, ok := ev.Details[key]; ok {
+			res = append(res, fmt.Sprintf("SYS_AGENT_%s=%v", strings.ToUpper(key), v))
+		}
+	}
+	return res
+}
+
+// String returns number of hooks
+func (h *Hooks) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return fmt.Sprintf("hooks of %d components", len(h.commands))
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_Send(t *testing.T) {
+	dir := t.TempDir()
+	hooks := Hooks{Timeout: time.Second, Cooldown: time.Hour}
+	hooks.Update(map[string]Hook{
+		"service:s1": {OnDown: "env | grep SYS_AGENT_ | sort > " + filepath.Join(dir, "down.env") +
+			" && cat > " + filepath.Join(dir, "down.json"), OnUp: "echo up > " + filepath.Join(dir, "up.txt")},
+		"cpu": {OnUp: "echo cpu up"},
+	})
+	assert.Equal(t, "hooks of 2 components", hooks.String())
+
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	ev := Event{Component: "service:s1", Hostname: "h1", OldStatus: "UP", NewStatus: "DOWN", Time: ts,
+		Details: map[string]any{"reason": "container stopped", "status_code": 500}}
+	require.NoError(t, hooks.Send(context.Background(), ev))
+
+	env, err := os.ReadFile(filepath.Join(dir, "down.env"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SYS_AGENT_COMPONENT=service:s1", "SYS_AGENT_HOSTNAME=h1", "SYS_AGENT_NEW_STATUS=DOWN",
+		"SYS_AGENT_OLD_STATUS=UP", "SYS_AGENT_REASON=container stopped", "SYS_AGENT_SERVICE=s1",
+		"SYS_AGENT_TIME=2026-01-02T15:00:00Z"}, strings.Split(strings.TrimSpace(string(env)), "\n"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "down.json"))
+	require.NoError(t, err)
+	var stdin Event
+	require.NoError(t, json.Unmarshal(data, &stdin))
+	assert.Equal(t, "service:s1", stdin.Component)
+	assert.Equal(t, "DOWN", stdin.NewStatus)
+	assert.Equal(t, "container stopped", stdin.Details["reason"])
+
+	// cooldown of down hook
+	require.NoError(t, os.Remove(filepath.Join(dir, "down.json")))
+	require.NoError(t, hooks.Send(context.Background(), ev))
+	assert.NoFileExists(t, filepath.Join(dir, "down.json"), "down hook not run within cooldown")
+
+	ev.OldStatus, ev.NewStatus = "DOWN", "UP"
+	require.NoError(t, hooks.Send(context.Background(), ev))
+	assert.FileExists(t, filepath.Join(dir, "up.txt"), "up hook has its own cooldown")
+
+	require.NoError(t, hooks.Send(context.Background(), Event{Component: "cpu", NewStatus: "DOWN"}), "no down hook")
+	require.NoError(t, hooks.Send(context.Background(), Event{Component: "memory", NewStatus: "DOWN"}), "no hooks")
+}
+
+func TestHooks_SendFailed(t *testing.T) {
+	hooks := Hooks{Timeout: 100 * time.Millisecond}
+	hooks.Update(map[string]Hook{"cpu": {OnDown: "exit 1", OnUp: "sleep 5"}})
+
+	err := hooks.Send(context.Background(), Event{Component: "cpu", NewStatus: "DOWN"})
+	require.ErrorContains(t, err, `hook "exit 1" of cpu failed`)
+
+	st := time.Now()
+	err = hooks.Send(context.Background(), Event{Component: "cpu", NewStatus: "UP"})
+	require.ErrorContains(t, err, "timed out")
+	assert.Less(t, time.Since(st), 2*time.Second)
+
+	err = hooks.Send(context.Background(), Event{Component: "cpu", NewStatus: "DOWN"})
+	require.Error(t, err, "no cooldown, run again")
+}
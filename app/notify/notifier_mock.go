@@ -0,0 +1,114 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// NotifierMock is a mock implementation of Notifier.
+//
+//	func TestSomethingThatUsesNotifier(t *testing.T) {
+//
+//		// make and configure a mocked Notifier
+//		mockedNotifier := &NotifierMock{
+//			SendFunc: func(ctx context.Context, ev Event) error {
+//				panic("mock out the Send method")
+//			},
+//			StringFunc: func() string {
+//				panic("mock out the String method")
+//			},
+//		}
+//
+//		// use mockedNotifier in code that requires Notifier
+//		// and then make assertions.
+//
+//	}
+type NotifierMock struct {
+	// SendFunc mocks the Send method.
+	SendFunc func(ctx context.Context, ev Event) error
+
+	// StringFunc mocks the String method.
+	StringFunc func() string
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Send holds details about calls to the Send method.
+		Send []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ev is the ev argument value.
+			Ev Event
+		}
+		// String holds details about calls to the String method.
+		String []struct {
+		}
+	}
+	lockSend   sync.RWMutex
+	lockString sync.RWMutex
+}
+
+// Send calls SendFunc.
+func (mock *NotifierMock) Send(ctx context.Context, ev Event) error {
+	if mock.SendFunc == nil {
+		panic("NotifierMock.SendFunc: method is nil but Notifier.Send was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ev  Event
+	}{
+		Ctx: ctx,
+		Ev:  ev,
+	}
+	mock.lockSend.Lock()
+	mock.calls.Send = append(mock.calls.Send, callInfo)
+	mock.lockSend.Unlock()
+	return mock.SendFunc(ctx, ev)
+}
+
+// SendCalls gets all the calls that were made to Send.
+// Check the length with:
+//
+//	len(mockedNotifier.SendCalls())
+func (mock *NotifierMock) SendCalls() []struct {
+	Ctx context.Context
+	Ev  Event
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ev  Event
+	}
+	mock.lockSend.RLock()
+	calls = mock.calls.Send
+	mock.lockSend.RUnlock()
+	return calls
+}
+
+// String calls StringFunc.
+func (mock *NotifierMock) String() string {
+	if mock.StringFunc == nil {
+		panic("NotifierMock.StringFunc: method is nil but Notifier.String was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockString.Lock()
+	mock.calls.String = append(mock.calls.String, callInfo)
+	mock.lockString.Unlock()
+	return mock.StringFunc()
+}
+
+// StringCalls gets all the calls that were made to String.
+// Check the length with:
+//
+//	len(mockedNotifier.StringCalls())
+func (mock *NotifierMock) StringCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockString.RLock()
+	calls = mock.calls.String
+	mock.lockString.RUnlock()
+	return calls
+}
@@ -0,0 +1,193 @@
+// Package notify watches actuator health components and sends notifications when a component goes DOWN
+// or recovers, i.e. to webhooks, by email or to hook commands.
+package notify
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+)
+
+//go:generate moq -out notifier_mock.go -skip-ensure -fmt goimports . Notifier
+//go:generate moq -out status_mock.go -skip-ensure -fmt goimports . Status
+//go:generate moq -out evaluator_mock.go -skip-ensure -fmt goimports . Evaluator
+
+// Event is a change of the component state, sent to notifiers
+type Event struct {
+	Component string         `json:"component"` // actuator component name, i.e. "cpu" or "service:rmq"
+	Hostname  string         `json:"hostname"`
+	OldStatus string         `json:"old_status"` // UP or DOWN
+	NewStatus string         `json:"new_status"` // UP or DOWN
+	Details   map[string]any `json:"details,omitempty"`
+	Time      time.Time      `json:"time"` // time the new status was first seen
+}
+
+// Notifier sends the event to a destination, i.e. webhook
+type Notifier interface {
+	Send(ctx context.Context, ev Event) error
+	String() string
+}
+
+// flusher is implemented by notifiers keeping events to send later, i.e. email batching events,
+// Flush sends them on shutdown
+type flusher interface {
+	Flush()
+}
+
+// Status is used to get status info, implemented by status.Service
+type Status interface {
+	Get() (*status.Info, error)
+}
+
+// Evaluator makes actuator health of the status info, implemented by server.Rest
+type Evaluator interface {
+	Health(info *status.Info) *actuator.HealthResponse
+}
+
+// Service periodically evaluates health of all components and sends events to notifiers when a component
+// goes DOWN or recovers. WARN is not a change of state, i.e. UP -> WARN is not reported and DOWN -> WARN is
+// reported as recovery. The new state should be kept for MinDuration to be reported, so short blips are ignored,
+// and each state change is reported once. Changes of flapping components are held until they settle, and the state
+// kept after that is reported. Components seen for the first time are assumed to be UP.
+// OUT_OF_SERVICE components, i.e. in maintenance, and UNKNOWN ones, i.e. with failed dependency, are not reported.
+type Service struct {
+	Status      Status
+	Evaluator   Evaluator
+	Notifiers   []Notifier
+	MinDuration time.Duration
+	Interval    time.Duration
+
+	mu     sync.Mutex
+	states map[string]*state
+}
+
+// queueSize is the number of events waiting to be sent to each notifier, events are dropped if the queue is full
+const queueSize = 100
+
+// state of a component, as reported to notifiers
+type state struct {
+	notified string    // last reported status
+	pending  string    // new status waiting for MinDuration, empty if the status didn't change
+	since    time.Time // time the pending status was first seen
+}
+
+// Run checks health each interval and sends notifications, until context is canceled.
+// Each notifier sends events from its own queue, so a slow notifier doesn't delay the others, and events
+// are sent to each notifier in order. On cancel Run returns after queued events are passed to notifiers
+// and events kept by notifiers are flushed.
+func (s *Service) Run(ctx context.Context) {
+	log.Printf("[INFO] start notifications to %v, interval %v, min duration %v", s.Notifiers, s.Interval, s.MinDuration)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	queues := make([]chan Event, len(s.Notifiers))
+	var wg sync.WaitGroup
+	for i, n := range s.Notifiers {
+		queues[i] = make(chan Event, queueSize)
+		wg.Go(func() { send(ctx, n, queues[i]) })
+	}
+	defer func() { // queued events are passed to notifiers with canceled context, so they can send or drop them
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := s.Status.Get()
+		if err != nil {
+			log.Printf("[WARN] can't get status for notifications, %v", err)
+			continue
+		}
+		for _, ev := range s.Update(s.Evaluator.Health(info), info.HostName, time.Now()) {
+			s.notify(ev, queues)
+		}
+	}
+}
+
+// Update tracks states of the health components and returns events for state changes kept for MinDuration
+func (s *Service) Update(health *actuator.HealthResponse, hostname string, now time.Time) []Event {
+	if health == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = map[string]*state{}
+	}
+
+	var res []Event
+	for name, comp := range health.Components {
+		st, ok := s.states[name]
+		if !ok {
+			st = &state{notified: actuator.StatusUp}
+			s.states[name] = st
+		}
+		if comp.Status == actuator.StatusOutOfService || comp.Status == actuator.StatusUnknown {
+			st.pending = "" // in maintenance or not checked, changes are not reported and the state is kept for after
+			continue
+		}
+		newStatus := actuator.StatusUp
+		if comp.Status == actuator.StatusDown {
+			newStatus = actuator.StatusDown
+		}
+		if newStatus == st.notified {
+			st.pending = ""
+			continue
+		}
+		if newStatus != st.pending {
+			st.pending, st.since = newStatus, now
+		}
+		if comp.IsFlapping() || now.Sub(st.since) < s.MinDuration {
+			continue
+		}
+		res = append(res, Event{Component: name, Hostname: hostname, OldStatus: st.notified, NewStatus: newStatus,
+			Details: comp.Details, Time: st.since})
+		st.notified, st.pending = newStatus, ""
+	}
+
+	// forget removed components, i.e. services removed on config reload
+	for name := range s.states {
+		if _, ok := health.Components[name]; !ok {
+			delete(s.states, name)
+		}
+	}
+	slices.SortFunc(res, func(a, b Event) int { return strings.Compare(a.Component, b.Component) })
+	return res
+}
+
+// notify queues the event to all notifiers, without waiting for them to send it
+func (s *Service) notify(ev Event, queues []chan Event) {
+	log.Printf("[INFO] %s on %s changed %s -> %s", ev.Component, ev.Hostname, ev.OldStatus, ev.NewStatus)
+	for i, q := range queues {
+		select {
+		case q <- ev:
+		default:
+			log.Printf("[WARN] notification queue of %s is full, %s %s dropped", s.Notifiers[i], ev.Component, ev.NewStatus)
+		}
+	}
+}
+
+// send sends events from the queue to the notifier until the queue is closed, errors are logged.
+// Events kept by the notifier are flushed after the last one.
+func send(ctx context.Context, n Notifier, queue <-chan Event) {
+	for ev := range queue {
+		if err := n.Send(ctx, ev); err != nil {
+			log.Printf("[WARN] can't send notification to %s, %v", n, err)
+		}
+	}
+	if f, ok := n.(flusher); ok {
+		f.Flush()
+	}
+}
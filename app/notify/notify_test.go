@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+)
+
+func TestService_Update(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	health := func(statuses map[string]string) *actuator.HealthResponse {
+		res := &actuator.HealthResponse{Components: map[string]actuator.Component{}}
+		for name, st := range statuses {
+			res.Components[name] = actuator.Component{Status: st, Details: map[string]any{"reason": "r-" + name}}
+		}
+		return res
+	}
+
+	t.Run("no min duration", func(t *testing.T) {
+		svc := Service{}
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "UP", "service:s1": "WARN"}), "h1", ts))
+
+		evs := svc.Update(health(map[string]string{"cpu": "DOWN", "service:s1": "DOWN"}), "h1", ts.Add(time.Second))
+		assert.Equal(t, []Event{
+			{Component: "cpu", Hostname: "h1", OldStatus: "UP", NewStatus: "DOWN", Details: map[string]any{"reason": "r-cpu"},
+				Time: ts.Add(time.Second)},
+			{Component: "service:s1", Hostname: "h1", OldStatus: "UP", NewStatus: "DOWN",
+				Details: map[string]any{"reason": "r-service:s1"}, Time: ts.Add(time.Second)},
+		}, evs)
+
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "DOWN", "service:s1": "DOWN"}), "h1", ts.Add(2*time.Second)),
+			"same state reported once")
+
+		evs = svc.Update(health(map[string]string{"cpu": "DOWN", "service:s1": "WARN"}), "h1", ts.Add(3*time.Second))
+		require.Len(t, evs, 1)
+		assert.Equal(t, "service:s1", evs[0].Component)
+		assert.Equal(t, "DOWN", evs[0].OldStatus)
+		assert.Equal(t, "UP", evs[0].NewStatus, "WARN is a recovery")
+	})
+
+	t.Run("down at start", func(t *testing.T) {
+		svc := Service{}
+		evs := svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts)
+		require.Len(t, evs, 1)
+		assert.Equal(t, "UP", evs[0].OldStatus)
+		assert.Equal(t, "DOWN", evs[0].NewStatus)
+	})
+
+	t.Run("min duration", func(t *testing.T) {
+		svc := Service{MinDuration: time.Minute}
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "UP"}), "h1", ts))
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts.Add(10*time.Second)))
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "UP"}), "h1", ts.Add(20*time.Second)), "blip ignored")
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts.Add(30*time.Second)))
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts.Add(80*time.Second)))
+
+		evs := svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts.Add(90*time.Second))
+		require.Len(t, evs, 1)
+		assert.Equal(t, "DOWN", evs[0].NewStatus)
+		assert.Equal(t, ts.Add(30*time.Second), evs[0].Time, "time the state was first seen")
+		assert.Empty(t, svc.Update(health(map[string]string{"cpu": "DOWN"}), "h1", ts.Add(200*time.Second)))
+	})
+
+	t.Run("removed component", func(t *testing.T) {
+		svc := Service{}
+		require.Len(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts), 1)
+		assert.Empty(t, svc.Update(health(map[string]string{}), "h1", ts.Add(time.Second)))
+		require.Len(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts.Add(2*time.Second)), 1,
+			"re-added component starts as UP")
+	})
+
+	t.Run("out of service", func(t *testing.T) {
+		svc := Service{}
+		require.Len(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts), 1)
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "OUT_OF_SERVICE"}), "h1", ts.Add(time.Second)),
+			"maintenance is not a recovery")
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts.Add(2*time.Second)),
+			"still down after maintenance")
+		evs := svc.Update(health(map[string]string{"service:s1": "UP"}), "h1", ts.Add(3*time.Second))
+		require.Len(t, evs, 1)
+		assert.Equal(t, "UP", evs[0].NewStatus)
+
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "OUT_OF_SERVICE"}), "h1", ts.Add(4*time.Second)),
+			"going down in maintenance is not reported")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		svc := Service{}
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "UNKNOWN"}), "h1", ts))
+		require.Len(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts.Add(time.Second)), 1)
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "UNKNOWN"}), "h1", ts.Add(2*time.Second)),
+			"unknown is not a recovery")
+	})
+
+	t.Run("flapping", func(t *testing.T) {
+		flapping := func(st string) *actuator.HealthResponse {
+			return &actuator.HealthResponse{Components: map[string]actuator.Component{
+				"service:s1": {Status: st, Details: map[string]any{"flapping": true}}}}
+		}
+		svc := Service{}
+		assert.Empty(t, svc.Update(flapping("DOWN"), "h1", ts), "down while flapping is held")
+		assert.Empty(t, svc.Update(flapping("UP"), "h1", ts.Add(time.Second)))
+		assert.Empty(t, svc.Update(flapping("DOWN"), "h1", ts.Add(2*time.Second)))
+
+		evs := svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts.Add(3*time.Second))
+		require.Len(t, evs, 1, "reported when settled")
+		assert.Equal(t, "DOWN", evs[0].NewStatus)
+		assert.Equal(t, ts.Add(2*time.Second), evs[0].Time)
+
+		assert.Empty(t, svc.Update(flapping("UP"), "h1", ts.Add(4*time.Second)), "recovery while flapping is held")
+		assert.Empty(t, svc.Update(flapping("DOWN"), "h1", ts.Add(5*time.Second)), "no false recovery")
+		assert.Empty(t, svc.Update(health(map[string]string{"service:s1": "DOWN"}), "h1", ts.Add(6*time.Second)),
+			"settled in the reported state")
+	})
+
+	t.Run("nil health", func(t *testing.T) {
+		svc := Service{}
+		assert.Empty(t, svc.Update(nil, "h1", ts))
+	})
+}
+
+func TestService_Run(t *testing.T) {
+	var mu sync.Mutex
+	compStatus := "UP"
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return &status.Info{HostName: "h1"}, nil }}
+	ev := &EvaluatorMock{HealthFunc: func(info *status.Info) *actuator.HealthResponse {
+		mu.Lock()
+		defer mu.Unlock()
+		return &actuator.HealthResponse{Components: map[string]actuator.Component{"cpu": {Status: compStatus}}}
+	}}
+	sent := make(chan Event, 10)
+	slow := &NotifierMock{SendFunc: func(ctx context.Context, ev Event) error { <-ctx.Done(); return ctx.Err() },
+		StringFunc: func() string { return "slow" }}
+	n1 := &NotifierMock{SendFunc: func(ctx context.Context, ev Event) error { sent <- ev; return nil },
+		StringFunc: func() string { return "n1" }}
+	n2 := &NotifierMock{SendFunc: func(ctx context.Context, ev Event) error { return errors.New("failed") },
+		StringFunc: func() string { return "n2" }}
+
+	svc := Service{Status: sts, Evaluator: ev, Notifiers: []Notifier{slow, n1, n2}, Interval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	compStatus = "DOWN"
+	mu.Unlock()
+
+	select {
+	case e := <-sent:
+		assert.Equal(t, "cpu", e.Component)
+		assert.Equal(t, "h1", e.Hostname)
+		assert.Equal(t, "DOWN", e.NewStatus)
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+	}
+	cancel()
+	<-done
+	assert.Empty(t, sent, "sent once")
+	assert.Len(t, n2.SendCalls(), 1, "failed notifier doesn't block others")
+	assert.Len(t, slow.SendCalls(), 1, "slow notifier doesn't block others")
+}
+
+func TestService_RunFlush(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return &status.Info{HostName: "h1"}, nil }}
+	ev := &EvaluatorMock{HealthFunc: func(info *status.Info) *actuator.HealthResponse {
+		return &actuator.HealthResponse{Components: map[string]actuator.Component{"cpu": {Status: "UP"}}}
+	}}
+	n := &flushNotifier{NotifierMock: &NotifierMock{SendFunc: func(ctx context.Context, ev Event) error { return nil },
+		StringFunc: func() string { return "batch" }}}
+
+	svc := Service{Status: sts, Evaluator: ev, Notifiers: []Notifier{n}, Interval: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+	svc.Run(ctx)
+	assert.Equal(t, 1, n.flushed, "flushed once, before Run returned")
+}
+
+type flushNotifier struct {
+	*NotifierMock
+	flushed int
+}
+
+func (f *flushNotifier) Flush() { f.flushed++ }
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Webhook posts events as json to the url, failed requests are retried with doubled delay
+type Webhook struct {
+	URL        string
+	Client     http.Client
+	Retries    int           // number of retries after the first failed attempt
+	RetryDelay time.Duration // delay before the first retry, default 1s
+}
+
+// Send posts the event to the webhook, any response other than 2xx is an error
+func (w *Webhook) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delay := w.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	attempt := 1
+	for ; ; attempt++ {
+		if err = w.post(ctx, body); err == nil || attempt > w.Retries {
+			break
+		}
+		log.Printf("[DEBUG] %s attempt %d failed, retry in %v, %v", w, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook canceled: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+	}
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// String returns webhook url without path and query, as they may contain secrets
+func (w *Webhook) String() string {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return "webhook"
+	}
+	return fmt.Sprintf("webhook %s://%s", u.Scheme, u.Host)
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Send(t *testing.T) {
+	ts0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/hook/secret", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var ev map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ev))
+		assert.Equal(t, map[string]any{"component": "service:s1", "hostname": "h1", "old_status": "UP",
+			"new_status": "DOWN", "details": map[string]any{"status_code": float64(500)}, "time": "2026-01-02T15:00:00Z"}, ev)
+	}))
+	defer srv.Close()
+
+	wh := Webhook{URL: srv.URL + "/hook/secret", Client: http.Client{Timeout: time.Second}}
+	err := wh.Send(context.Background(), Event{Component: "service:s1", Hostname: "h1", OldStatus: "UP", NewStatus: "DOWN",
+		Details: map[string]any{"status_code": 500}, Time: ts0})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "webhook http://"+srv.Listener.Addr().String(), wh.String(), "path is hidden")
+}
+
+func TestWebhook_SendRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("recovered", func(t *testing.T) {
+		calls.Store(0)
+		wh := Webhook{URL: srv.URL, Retries: 2, RetryDelay: time.Millisecond}
+		require.NoError(t, wh.Send(context.Background(), Event{Component: "cpu"}))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		calls.Store(0)
+		wh := Webhook{URL: srv.URL, Retries: 1, RetryDelay: time.Millisecond}
+		err := wh.Send(context.Background(), Event{Component: "cpu"})
+		require.ErrorContains(t, err, "failed after 2 attempts")
+		require.ErrorContains(t, err, "502 Bad Gateway")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		calls.Store(0)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		wh := Webhook{URL: srv.URL, Retries: 5, RetryDelay: time.Hour}
+		err := wh.Send(ctx, Event{Component: "cpu"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestWebhook_SendUnreachable(t *testing.T) {
+	wh := Webhook{URL: "http://127.0.0.1:1/hook", Client: http.Client{Timeout: time.Second}}
+	err := wh.Send(context.Background(), Event{Component: "cpu"})
+	require.ErrorContains(t, err, "failed after 1 attempts")
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Auth defines credentials of the api. Authentication is disabled if no users and tokens set.
+type Auth struct {
+	Users        map[string]string // bcrypt hashes of passwords by user name, for basic auth
+	Tokens       []string          // static bearer tokens
+	PublicHealth bool              // summary of /actuator/health, without components, is available without auth
+}
+
+// enabled returns true if any credentials set
+func (a Auth) enabled() bool {
+	return len(a.Users) > 0 || len(a.Tokens) > 0
+}
+
+// authEnabled returns true if the api requires credentials
+func (s *Rest) authEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Auth.enabled()
+}
+
+// check returns true if the request has valid bearer token or basic auth credentials
+func (a Auth) check(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		valid := false
+		for _, t := range a.Tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				valid = true
+			}
+		}
+		return valid
+	}
+	user, passwd, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	hash, ok := a.Users[user]
+	if !ok {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(passwd)) == nil
+}
+
+type ctxKey string
+
+const (
+	summaryOnlyKey ctxKey = "summaryOnly" // marks unauthenticated requests to public health, limited to the overall status
+	authorizedKey  ctxKey = "authorized"  // marks requests with valid credentials
+)
+
+// UpdateAuth replaces credentials of the api
+func (s *Rest) UpdateAuth(auth Auth) {
+	s.mu.Lock()
+	s.Auth = auth
+	s.mu.Unlock()
+}
+
+// authMiddleware rejects requests without valid credentials if auth is enabled.
+// Unauthenticated requests to /actuator/health and its groups are allowed with PublicHealth, marked to report summary only.
+// Requests with valid credentials are marked as authorized, for when-authorized show-details mode.
+func (s *Rest) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		auth := s.Auth
+		s.mu.RUnlock()
+
+		if !auth.enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.check(r) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authorizedKey, true)))
+			return
+		}
+		if auth.PublicHealth && s.publicHealthPath(r.URL.Path) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), summaryOnlyKey, true)))
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="sys-agent"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// publicHealthPath returns true for /actuator/health and its groups, allowed without auth with PublicHealth
+func (s *Rest) publicHealthPath(p string) bool {
+	if p == "/actuator/health" {
+		return true
+	}
+	group, ok := strings.CutPrefix(p, "/actuator/health/")
+	if !ok {
+		return false
+	}
+	_, ok = s.healthOptions().Groups[group]
+	return ok
+}
+
+// authorized returns true if the request has valid credentials
+func authorized(r *http.Request) bool {
+	v, _ := r.Context().Value(authorizedKey).(bool)
+	return v
+}
+
+// summaryOnly returns true if the request is allowed to get summary only
+func summaryOnly(r *http.Request) bool {
+	v, _ := r.Context().Value(summaryOnlyKey).(bool)
+	return v
+}
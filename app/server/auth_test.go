@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+)
+
+func TestRest_Auth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("passwd"), bcrypt.MinCost)
+	require.NoError(t, err)
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return &status.Info{HostName: "h1", CPUPercent: 10}, nil }}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1",
+		Auth: Auth{Users: map[string]string{"admin": string(hash)}, Tokens: []string{"token1", "token2"}}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, setAuth func(r *http.Request)) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, http.NoBody)
+		require.NoError(t, err)
+		if setAuth != nil {
+			setAuth(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	basic := func(user, passwd string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, passwd) }
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	tbl := []struct {
+		name    string
+		path    string
+		setAuth func(r *http.Request)
+		code    int
+	}{
+		{"ping is public", "/ping", nil, http.StatusOK},
+		{"no auth", "/status", nil, http.StatusUnauthorized},
+		{"basic auth", "/status", basic("admin", "passwd"), http.StatusOK},
+		{"wrong password", "/status", basic("admin", "bad"), http.StatusUnauthorized},
+		{"unknown user", "/status", basic("user", "passwd"), http.StatusUnauthorized},
+		{"token", "/status", bearer("token2"), http.StatusOK},
+		{"wrong token", "/status", bearer("token3"), http.StatusUnauthorized},
+		{"health without auth", "/actuator/health", nil, http.StatusUnauthorized},
+		{"health with token", "/actuator/health", bearer("token1"), http.StatusOK},
+		{"metrics without auth", "/metrics", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, tt.path, tt.setAuth)
+			assert.Equal(t, tt.code, resp.StatusCode)
+			if tt.code == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="sys-agent"`, resp.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("public health", func(t *testing.T) {
+		srv.UpdateAuth(Auth{Tokens: []string{"token1"}, PublicHealth: true})
+
+		resp := get(t, "/actuator/health", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var health actuator.HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Equal(t, "UP", health.Status)
+		assert.Empty(t, health.Components, "summary only")
+
+		resp = get(t, "/actuator/health", bearer("token1"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.NotEmpty(t, health.Components, "full details with auth")
+
+		assert.Equal(t, http.StatusUnauthorized, get(t, "/actuator/health/cpu", nil).StatusCode, "components are not public")
+		assert.Equal(t, http.StatusUnauthorized, get(t, "/status", nil).StatusCode)
+	})
+
+	t.Run("details when authorized", func(t *testing.T) {
+		srv.UpdateAuth(Auth{Tokens: []string{"token1"}, PublicHealth: true})
+		srv.UpdateHealthOptions(actuator.HealthOptions{ShowDetails: actuator.ShowDetailsWhenAuthorized,
+			Groups: map[string][]string{"liveness": {"cpu"}}})
+		defer srv.UpdateHealthOptions(actuator.HealthOptions{})
+
+		var health actuator.HealthResponse
+		resp := get(t, "/actuator/health/liveness", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "groups are public")
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Empty(t, health.Components)
+
+		resp = get(t, "/actuator/health/liveness", bearer("token1"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Contains(t, health.Components, "cpu")
+
+		var comp actuator.Component
+		resp = get(t, "/actuator/health/memory", bearer("token1"))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&comp))
+		assert.NotEmpty(t, comp.Details)
+
+		srv.UpdateAuth(Auth{})
+		health = actuator.HealthResponse{}
+		resp = get(t, "/actuator/health", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Equal(t, "UP", health.Status)
+		assert.Empty(t, health.Components, "no details without auth")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		srv.UpdateAuth(Auth{})
+		assert.Equal(t, http.StatusOK, get(t, "/status", nil).StatusCode)
+	})
+}
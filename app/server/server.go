@@ -1,9 +1,15 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/didip/tollbooth/v8"
@@ -12,27 +18,65 @@ import (
 	"github.com/go-pkgz/routegroup"
 
 	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/maintenance"
+	"github.com/umputun/sys-agent/app/metrics"
 	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/store"
 )
 
 //go:generate moq -out status_mock.go -skip-ensure -fmt goimports . Status
+//go:generate moq -out sla_mock.go -skip-ensure -fmt goimports . SLAReporter
+//go:generate moq -out services_mock.go -skip-ensure -fmt goimports . Services
 
 // Rest implement http api invoking remote execution for requested tasks
 type Rest struct {
-	Listen  string
-	Version string
-	Status  Status
+	Listen      string
+	Version     string
+	Started     time.Time // start time of the agent, reported by actuator info
+	Status      Status
+	Services    Services              // responses of single services, optional, taken from Status if not set
+	Thresholds  actuator.Thresholds   // levels for actuator health components
+	History     *status.History       // history of services and metrics, optional
+	SLA         SLAReporter           // availability reports of services, optional
+	Maintenance *maintenance.Schedule // maintenance windows of components, optional
+	Auth        Auth                  // credentials of the api, no auth if not set
+
+	HealthOptions actuator.HealthOptions // show-details mode and groups of actuator health
+
+	StreamInterval  time.Duration // interval of status checks for status stream, 5s if not set
+	StreamHeartbeat time.Duration // interval of heartbeat comments in status stream, 15s if not set
+
+	TLSCert        string // certificate file, https is used if set, reloaded on change
+	TLSKey         string // key file of the certificate
+	TLSClientCA    string // CA bundle to verify client certificates, optional
+	RedirectListen string // address of http listener redirecting to https, optional
+
+	mu sync.RWMutex // protects Thresholds, Auth and HealthOptions on update
 }
 
+// freshTimeout limits immediate check of a service, below the write timeout of the server
+const freshTimeout = 20 * time.Second
+
 // Status is used to get status info of the server
 type Status interface {
 	Get() (*status.Info, error)
+	GetFiltered(f status.Filter) (*status.Info, error)
 }
 
-// Run starts http server and closes on context cancellation
-func (s *Rest) Run(ctx context.Context) error {
-	log.Printf("[INFO] start http server on %s", s.Listen)
+// Services provides responses of single services without checking the rest, implemented by external.Scheduler
+type Services interface {
+	Response(name string) (external.Response, bool)
+	CheckNow(ctx context.Context, name string) (external.Response, bool, error)
+}
+
+// SLAReporter makes availability report of a service for the period, implemented by store.Store
+type SLAReporter interface {
+	SLA(name string, period time.Duration, now time.Time) (store.SLA, error)
+}
 
+// Run starts http server, or https server if certificate is set, and closes on context cancellation
+func (s *Rest) Run(ctx context.Context) error {
 	httpServer := &http.Server{
 		Addr:              s.Listen,
 		Handler:           s.router(),
@@ -52,56 +96,436 @@ func (s *Rest) Run(ctx context.Context) error {
 
 	}()
 
-	return httpServer.ListenAndServe()
+	if s.TLSCert == "" {
+		log.Printf("[INFO] start http server on %s", s.Listen)
+		return httpServer.ListenAndServe()
+	}
+
+	tlsConf, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	httpServer.TLSConfig = tlsConf
+	if s.RedirectListen != "" {
+		go s.runRedirect(ctx)
+	}
+	log.Printf("[INFO] start https server on %s, client certificates required: %v", s.Listen, s.TLSClientCA != "")
+	return httpServer.ListenAndServeTLS("", "")
+}
+
+// runRedirect starts http server redirecting to https, until context is canceled
+func (s *Rest) runRedirect(ctx context.Context) {
+	log.Printf("[INFO] start http to https redirect on %s", s.RedirectListen)
+	redirectServer := &http.Server{
+		Addr:              s.RedirectListen,
+		Handler:           redirectHandler(s.Listen),
+		ReadHeaderTimeout: time.Second,
+		WriteTimeout:      time.Second,
+		IdleTimeout:       time.Second,
+		ErrorLog:          log.ToStdLogger(log.Default(), "WARN"),
+	}
+	go func() {
+		<-ctx.Done()
+		if err := redirectServer.Close(); err != nil {
+			log.Printf("[ERROR] failed to close redirect server, %v", err)
+		}
+	}()
+	if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[WARN] redirect server failed, %v", err)
+	}
+}
+
+// UpdateThresholds replaces levels used for actuator health components
+func (s *Rest) UpdateThresholds(th actuator.Thresholds) {
+	s.mu.Lock()
+	s.Thresholds = th
+	s.mu.Unlock()
+}
+
+// thresholds returns current levels for actuator health components
+func (s *Rest) thresholds() actuator.Thresholds {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Thresholds
+}
+
+// UpdateHealthOptions replaces show-details mode and groups of actuator health
+func (s *Rest) UpdateHealthOptions(opts actuator.HealthOptions) {
+	s.mu.Lock()
+	s.HealthOptions = opts
+	s.mu.Unlock()
+}
+
+// healthOptions returns current show-details mode and groups of actuator health
+func (s *Rest) healthOptions() actuator.HealthOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.HealthOptions
+}
+
+// showDetails returns true if actuator health components should be reported for the request
+func (s *Rest) showDetails(r *http.Request) bool {
+	if summaryOnly(r) {
+		return false
+	}
+	return s.healthOptions().Details(authorized(r))
+}
+
+// Evaluate makes history records of all health components with the current thresholds, implements status.Evaluator
+func (s *Rest) Evaluate(info *status.Info) map[string]status.Record {
+	return actuator.Records(info, s.thresholds(), time.Now())
+}
+
+// Health returns actuator health of the status info, with components in maintenance reported as OUT_OF_SERVICE,
+// flapping components marked, and the overall status aggregated by the configured order of statuses. Implements notify.Evaluator
+func (s *Rest) Health(info *status.Info) *actuator.HealthResponse {
+	health := actuator.FromStatusInfo(info, s.thresholds())
+	s.mark(health)
+	s.healthOptions().Aggregate(health)
+	return health
+}
+
+// mark reports components in maintenance as OUT_OF_SERVICE and marks flapping components
+func (s *Rest) mark(health *actuator.HealthResponse) {
+	if s.Maintenance != nil {
+		now := time.Now()
+		health.MarkMaintenance(func(name string, tags []string) (string, bool) { return s.Maintenance.Active(name, tags, now) })
+	}
+	if s.History != nil {
+		health.MarkFlapping(s.History.Flapping())
+	}
+}
+
+// serviceResponse returns the last known response of the service, or checks it immediately if fresh is set.
+// Fresh check is limited by freshTimeout, to complete before the write timeout of the server.
+// Without Services the response is taken from the full status, and fresh is ignored.
+func (s *Rest) serviceResponse(ctx context.Context, name string, fresh bool) (external.Response, bool, error) {
+	if s.Services != nil {
+		if fresh {
+			ctx, cancel := context.WithTimeout(ctx, freshTimeout)
+			defer cancel()
+			return s.Services.CheckNow(ctx, name)
+		}
+		resp, ok := s.Services.Response(name)
+		return resp, ok, nil
+	}
+	info, err := s.Status.Get()
+	if err != nil {
+		return external.Response{}, false, fmt.Errorf("can't get status: %w", err)
+	}
+	resp, ok := info.ExtServices[name]
+	return resp, ok, nil
+}
+
+// serviceHealth returns actuator health component of the service, with maintenance and flapping marked
+func (s *Rest) serviceHealth(ctx context.Context, name string, fresh bool) (actuator.Component, bool, error) {
+	resp, ok, err := s.serviceResponse(ctx, name, fresh)
+	if err != nil || !ok {
+		return actuator.Component{}, ok, err
+	}
+	health := &actuator.HealthResponse{Components: map[string]actuator.Component{"service:" + name: actuator.ServiceComponent(resp)}}
+	s.mark(health)
+	return health.Components["service:"+name], true, nil
+}
+
+// writeHealthStatus writes http code mapped from the health status, if it differs from 200
+func (s *Rest) writeHealthStatus(w http.ResponseWriter, healthStatus string) {
+	if code := s.healthOptions().HTTPStatus(healthStatus); code != http.StatusOK {
+		w.WriteHeader(code)
+	}
 }
 
 func (s *Rest) router() http.Handler {
 	router := routegroup.New(http.NewServeMux())
 	router.Use(rest.Recoverer(log.Default()))
-	router.Use(rest.Throttle(100)) // limit the total number of the running requests
+	router.Use(skipStreams(rest.Throttle(100))) // limit the total number of the running requests, streams limited separately
 	router.Use(rest.AppInfo("sys-agent", "umputun", s.Version))
 	router.Use(rest.Ping)
 	router.Use(tollbooth.HTTPMiddleware(tollbooth.NewLimiter(10, nil)))
+	router.Use(s.authMiddleware) // after ping and rate limiter, so ping is public and brute force is limited
 
+	// sections, services, tags and bodies can be selected, i.e. ?include=volumes,services&service=docker*&tag=payments&bodies=false
 	router.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
-		resp, err := s.Status.Get()
+		filter, err := statusFilter(r)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid filter")
+			return
+		}
+		if filter.Sections == nil && filter.Services == nil && filter.Tags == nil && !filter.NoBodies {
+			resp, err := s.Status.Get()
+			if err != nil {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
+				return
+			}
+			rest.RenderJSON(w, resp)
+			return
+		}
+		resp, err := s.Status.GetFiltered(filter)
 		if err != nil {
 			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
 			return
 		}
+		if filter.Sections == nil {
+			rest.RenderJSON(w, resp)
+			return
+		}
+		sections, err := project(resp, filter.Sections)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to make status")
+			return
+		}
+		rest.RenderJSON(w, sections)
+	})
+
+	// single service, from the last check or checked immediately with ?fresh=true
+	router.HandleFunc("GET /status/services/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		resp, ok, err := s.serviceResponse(r.Context(), name, r.URL.Query().Get("fresh") == "true")
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), errorCode(err), err, "failed to get status")
+			return
+		}
+		if !ok {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("service %q not found", name), "service not found")
+			return
+		}
 		rest.RenderJSON(w, resp)
 	})
 
+	streamInterval, heartbeat := cmp.Or(s.StreamInterval, 5*time.Second), cmp.Or(s.StreamHeartbeat, 15*time.Second)
+	stream := newStatusStream(s.Status, s.Health, streamInterval)
+	router.With(rest.Throttle(maxStreams)).HandleFunc(streamPattern, s.streamHandler(stream, heartbeat))
+
+	router.HandleFunc("GET /status/history/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if s.History == nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("no history for %q", name), "history not found")
+			return
+		}
+		// services are recorded as actuator components, with "service:" prefix
+		report, ok := s.History.Report(name, time.Now())
+		if !ok {
+			report, ok = s.History.Report("service:"+name, time.Now())
+		}
+		if !ok {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("no history for %q", name), "history not found")
+			return
+		}
+		rest.RenderJSON(w, report)
+	})
+
+	router.HandleFunc("GET /sla/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if s.SLA == nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("store is not enabled"), "sla not available")
+			return
+		}
+		period := 30 * 24 * time.Hour
+		if p := r.URL.Query().Get("period"); p != "" {
+			var err error
+			if period, err = parsePeriod(p); err != nil {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid period")
+				return
+			}
+		}
+		sla, err := s.SLA.SLA(name, period, time.Now())
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, store.ErrNotFound) {
+				code = http.StatusNotFound
+			}
+			rest.SendErrorJSON(w, r, log.Default(), code, err, "failed to get sla")
+			return
+		}
+		rest.RenderJSON(w, sla)
+	})
+
+	router.HandleFunc("GET /maintenance", func(w http.ResponseWriter, r *http.Request) {
+		if s.Maintenance == nil {
+			rest.RenderJSON(w, []maintenance.State{})
+			return
+		}
+		rest.RenderJSON(w, s.Maintenance.States(time.Now()))
+	})
+
+	// starts maintenance window of components now, i.e. {"name": "upgrade", "components": ["service:mongo"], "duration": "30m"}.
+	// Windows silence components and their notifications, so changes are allowed only with auth enabled.
+	router.HandleFunc("POST /maintenance", func(w http.ResponseWriter, r *http.Request) {
+		if s.Maintenance == nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, errors.New("maintenance is not enabled"), "maintenance not available")
+			return
+		}
+		if !s.authEnabled() {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusForbidden, errors.New("auth is not enabled"), "maintenance api requires auth")
+			return
+		}
+		var req struct {
+			Name       string   `json:"name"`
+			Components []string `json:"components"`
+			Reason     string   `json:"reason"`
+			Duration   string   `json:"duration"`
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+		if err := rest.DecodeJSON(r, &req); err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid request")
+			return
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid duration")
+			return
+		}
+		now := time.Now()
+		if req.Name == "" {
+			req.Name = "manual-" + now.UTC().Format("20060102T150405")
+		}
+		if err = s.Maintenance.Add(req.Name, req.Components, req.Reason, duration, now); err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest, err, "invalid maintenance window")
+			return
+		}
+		log.Printf("[INFO] maintenance %q of %v started for %v", req.Name, req.Components, duration)
+		resp := maintenance.State{Name: req.Name, Components: req.Components, Reason: req.Reason, Manual: true,
+			Active: true, Start: now, End: now.Add(duration)}
+		if err = rest.EncodeJSON(w, http.StatusCreated, resp); err != nil {
+			log.Printf("[WARN] can't write maintenance response, %v", err)
+		}
+	})
+
+	// ends maintenance window added by api
+	router.HandleFunc("DELETE /maintenance/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !s.authEnabled() {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusForbidden, errors.New("auth is not enabled"), "maintenance api requires auth")
+			return
+		}
+		name := r.PathValue("name")
+		if s.Maintenance == nil || !s.Maintenance.Remove(name) {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("no maintenance window %q added by api", name),
+				"maintenance window not found")
+			return
+		}
+		log.Printf("[INFO] maintenance %q ended", name)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	router.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.Status.Get()
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
+			return
+		}
+		w.Header().Set("Content-Type", metrics.ContentType)
+		if err := metrics.Write(w, info); err != nil {
+			log.Printf("[WARN] failed to write metrics, %v", err)
+		}
+	})
+
 	router.HandleFunc("GET /actuator/health", func(w http.ResponseWriter, r *http.Request) {
 		info, err := s.Status.Get()
 		if err != nil {
 			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
 			return
 		}
-		health := actuator.FromStatusInfo(info)
-		if health.Status == actuator.StatusDown {
-			w.WriteHeader(http.StatusServiceUnavailable)
+		health := s.Health(info)
+		if !s.showDetails(r) {
+			health = health.Summary()
 		}
+		s.writeHealthStatus(w, health.Status)
 		rest.RenderJSON(w, health)
 	})
 
+	// component name or health group, i.e. liveness. Service components are evaluated alone,
+	// from the last check or checked immediately with ?fresh=true
 	router.HandleFunc("GET /actuator/health/{component}", func(w http.ResponseWriter, r *http.Request) {
 		component := r.PathValue("component")
+		opts := s.healthOptions()
+		members, isGroup := opts.Groups[component]
+		svcName, isService := strings.CutPrefix(component, "service:")
+
+		var comp actuator.Component
+		switch {
+		case !isGroup && isService:
+			var ok bool
+			var err error
+			comp, ok, err = s.serviceHealth(r.Context(), svcName, r.URL.Query().Get("fresh") == "true")
+			if err != nil {
+				rest.SendErrorJSON(w, r, log.Default(), errorCode(err), err, "failed to get status")
+				return
+			}
+			if !ok {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("component %q not found", component), "component not found")
+				return
+			}
+		default:
+			info, err := s.Status.Get()
+			if err != nil {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
+				return
+			}
+			health := s.Health(info)
+			if isGroup {
+				group := health.Group(members, opts.StatusOrder)
+				if !s.showDetails(r) {
+					group = group.Summary()
+				}
+				s.writeHealthStatus(w, group.Status)
+				rest.RenderJSON(w, group)
+				return
+			}
+			var ok bool
+			if comp, ok = health.Components[component]; !ok {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("component %q not found", component), "component not found")
+				return
+			}
+		}
+		if !s.showDetails(r) {
+			comp = actuator.Component{Status: comp.Status}
+		}
+		s.writeHealthStatus(w, comp.Status)
+		rest.RenderJSON(w, comp)
+	})
+
+	router.HandleFunc("GET /actuator/info", func(w http.ResponseWriter, r *http.Request) {
 		info, err := s.Status.Get()
 		if err != nil {
 			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
 			return
 		}
-		health := actuator.FromStatusInfo(info)
-		comp, ok := health.Components[component]
-		if !ok {
-			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("component %q not found", component), "component not found")
+		rest.RenderJSON(w, actuator.Info(info, s.Version, s.Started, time.Now()))
+	})
+
+	router.HandleFunc("GET /actuator/metrics", func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.Status.Get()
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
+			return
+		}
+		rest.RenderJSON(w, actuator.MetricNames(info))
+	})
+
+	// drill down by tags is the same as in spring, i.e. ?tag=volume:root&tag=path:/
+	router.HandleFunc("GET /actuator/metrics/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		tags := map[string]string{}
+		for _, tag := range r.URL.Query()["tag"] {
+			k, v, ok := strings.Cut(tag, ":")
+			if !ok || k == "" {
+				rest.SendErrorJSON(w, r, log.Default(), http.StatusBadRequest,
+					fmt.Errorf("invalid tag %q, should be name:value", tag), "invalid tag")
+				return
+			}
+			tags[k] = v
+		}
+		info, err := s.Status.Get()
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
 			return
 		}
-		if comp.Status == actuator.StatusDown {
-			w.WriteHeader(http.StatusServiceUnavailable)
+		m, err := actuator.Metric(info, name, tags)
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusNotFound, fmt.Errorf("metric %q: %w", name, err), "metric not found")
+			return
 		}
-		rest.RenderJSON(w, comp)
+		rest.RenderJSON(w, m)
 	})
 
 	router.HandleFunc("GET /actuator", func(w http.ResponseWriter, _ *http.Request) {
@@ -110,3 +534,77 @@ func (s *Rest) router() http.Handler {
 
 	return router
 }
+
+// errorCode returns http code of failed service response, 504 if the check is not completed in time
+// and 503 if the request is canceled
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statusFilter makes status filter from query parameters include, service, tag and bodies.
+// Lists can be comma separated or repeated, i.e. ?include=volumes,services or ?service=docker*&service=mongo
+func statusFilter(r *http.Request) (status.Filter, error) {
+	list := func(key string) []string {
+		var res []string
+		for _, v := range r.URL.Query()[key] {
+			for item := range strings.SplitSeq(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					res = append(res, item)
+				}
+			}
+		}
+		return res
+	}
+	res := status.Filter{Sections: list("include"), Services: list("service"), Tags: list("tag")}
+	if b := r.URL.Query().Get("bodies"); b != "" {
+		bodies, err := strconv.ParseBool(b)
+		if err != nil {
+			return status.Filter{}, fmt.Errorf("invalid bodies %q, should be true or false", b)
+		}
+		res.NoBodies = !bodies
+	}
+	if err := res.Validate(); err != nil {
+		return status.Filter{}, err
+	}
+	return res, nil
+}
+
+// project returns json fields of the value with the given keys only, to not report empty fields of unselected sections
+func project(v any, keys []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal: %w", err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("can't unmarshal: %w", err)
+	}
+	res := make(map[string]json.RawMessage, len(keys))
+	for _, k := range keys {
+		if f, ok := fields[k]; ok {
+			res[k] = f
+		}
+	}
+	return res, nil
+}
+
+// parsePeriod parses duration with optional days suffix, i.e. 30d, 12h or 90m
+func parsePeriod(p string) (time.Duration, error) {
+	res, err := time.ParseDuration(p)
+	if days, ok := strings.CutSuffix(p, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		res = time.Duration(n) * 24 * time.Hour
+	}
+	if err != nil || res <= 0 {
+		return 0, fmt.Errorf("invalid period %q, should be positive duration like 30d or 12h", p)
+	}
+	return res, nil
+}
@@ -3,9 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,8 +15,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/maintenance"
 	"github.com/umputun/sys-agent/app/status"
 	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/store"
 )
 
 func TestRest_Run(t *testing.T) {
@@ -52,6 +56,95 @@ func TestStatusCtrl(t *testing.T) {
 	assert.Len(t, sts.GetCalls(), 1)
 }
 
+func TestStatusCtrl_Filter(t *testing.T) {
+	sts := &StatusMock{GetFilteredFunc: func(f status.Filter) (*status.Info, error) {
+		return &status.Info{CPUPercent: 12, Volumes: map[string]status.Volume{"v1": {Name: "v1", Path: "/p1", UsagePercent: 5}},
+			ExtServices: map[string]external.Response{"docker": {Name: "docker", StatusCode: 200}}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, query string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/status" + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get(t, "?include=volumes,services&service=docker*&service=mongo,dev&tag=payments,team:billing&bodies=false")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"volumes":{"v1":{"name":"v1","path":"/p1","usage_percent":5}},
+		"services":{"docker":{"name":"docker","status_code":200,"response_time":0,"age":0}}}`, body)
+	require.Len(t, sts.GetFilteredCalls(), 1)
+	assert.Equal(t, status.Filter{Sections: []string{"volumes", "services"}, Services: []string{"docker*", "mongo", "dev"},
+		Tags: []string{"payments", "team:billing"}, NoBodies: true}, sts.GetFilteredCalls()[0].F)
+
+	code, body = get(t, "?bodies=false")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"cpu_percent":12`, "all sections without include")
+	assert.Equal(t, status.Filter{NoBodies: true}, sts.GetFilteredCalls()[1].F)
+
+	code, body = get(t, "?include=blah")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "invalid filter")
+	code, _ = get(t, "?service=[docker")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get(t, "?bodies=nope")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, sts.GetFilteredCalls(), 2)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	sts := &StatusMock{
+		GetFunc: func() (*status.Info, error) {
+			return &status.Info{
+				CPUPercent: 12,
+				Volumes:    map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 45}},
+				ExtServices: map[string]external.Response{
+					"docker": {Name: "docker", StatusCode: 200, ResponseTime: 10, Body: map[string]any{"running": 3}},
+				},
+			}, nil
+		},
+	}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "sys_agent_cpu_percent 12\n")
+	assert.Contains(t, string(body), `sys_agent_volume_usage_percent{volume="root",path="/"} 45`)
+	assert.Contains(t, string(body), `sys_agent_service_status_code{service="docker"} 200`)
+	assert.Contains(t, string(body), `sys_agent_service_value{service="docker",field="running"} 3`)
+	assert.Len(t, sts.GetCalls(), 1)
+}
+
+func TestMetricsEndpoint_Error(t *testing.T) {
+	sts := &StatusMock{
+		GetFunc: func() (*status.Info, error) {
+			return nil, assert.AnError
+		},
+	}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
 func TestActuatorHealthEndpoint(t *testing.T) {
 	sts := &StatusMock{
 		GetFunc: func() (*status.Info, error) {
@@ -160,6 +253,42 @@ func TestActuatorHealthEndpoint_Down(t *testing.T) {
 	assert.Equal(t, "DOWN", health.Components["cpu"].Status)
 }
 
+func TestActuatorHealthEndpoint_Thresholds(t *testing.T) {
+	sts := &StatusMock{
+		GetFunc: func() (*status.Info, error) {
+			return &status.Info{
+				CPUPercent: 75,
+				MemPercent: 50,
+				Volumes:    map[string]status.Volume{"archive": {Name: "archive", Path: "/archive", UsagePercent: 95}},
+			}, nil
+		},
+	}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1", Thresholds: actuator.Thresholds{
+		CPU:     actuator.Level{Warn: 70},
+		Volumes: map[string]actuator.Level{"archive": {Down: 99}},
+	}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/actuator/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "WARN status should return 200")
+
+	var health actuator.HealthResponse
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	require.NoError(t, err)
+	assert.Equal(t, "WARN", health.Status)
+	assert.Equal(t, "WARN", health.Components["cpu"].Status)
+	assert.Equal(t, "UP", health.Components["diskSpace:archive"].Status)
+
+	srv.UpdateThresholds(actuator.Thresholds{CPU: actuator.Level{Down: 70}})
+	resp2, err := http.Get(ts.URL + "/actuator/health")
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode, "updated thresholds should be used")
+}
+
 func TestActuatorHealthComponentEndpoint(t *testing.T) {
 	sts := &StatusMock{
 		GetFunc: func() (*status.Info, error) {
@@ -283,6 +412,175 @@ func TestActuatorHealthComponentEndpoint_Down(t *testing.T) {
 	assert.Equal(t, "DOWN", comp.Status)
 }
 
+func TestServiceEndpoints(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		return nil, assert.AnError // full status should not be collected
+	}}
+	svcs := &ServicesMock{
+		ResponseFunc: func(name string) (external.Response, bool) {
+			if name != "mongo" {
+				return external.Response{}, false
+			}
+			return external.Response{Name: "mongo", StatusCode: 200, ResponseTime: 10, Age: 5000}, true
+		},
+		CheckNowFunc: func(ctx context.Context, name string) (external.Response, bool, error) {
+			switch name {
+			case "mongo":
+				return external.Response{Name: "mongo", StatusCode: 500, ResponseTime: 20,
+					Health: external.Health{Status: external.HealthDown, Reason: "status code 500"}}, true, nil
+			case "slow":
+				_, ok := ctx.Deadline()
+				assert.True(t, ok, "fresh check should have a deadline")
+				return external.Response{}, true, fmt.Errorf("check of slow not completed: %w", context.DeadlineExceeded)
+			case "canceled":
+				return external.Response{}, true, fmt.Errorf("check of canceled not completed: %w", context.Canceled)
+			}
+			return external.Response{}, false, nil
+		},
+	}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Services: svcs, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, v any) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var svcResp external.Response
+	assert.Equal(t, http.StatusOK, get(t, "/status/services/mongo", &svcResp))
+	assert.Equal(t, 200, svcResp.StatusCode)
+	assert.Equal(t, int64(5000), svcResp.Age)
+	assert.Equal(t, http.StatusOK, get(t, "/status/services/mongo?fresh=true", &svcResp))
+	assert.Equal(t, 500, svcResp.StatusCode)
+	assert.Equal(t, http.StatusNotFound, get(t, "/status/services/unknown", nil))
+
+	var comp actuator.Component
+	assert.Equal(t, http.StatusOK, get(t, "/actuator/health/service:mongo", &comp))
+	assert.Equal(t, "UP", comp.Status)
+	assert.InDelta(t, 10, comp.Details["response_time"], 0.001)
+	comp = actuator.Component{}
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, "/actuator/health/service:mongo?fresh=true", &comp))
+	assert.Equal(t, "DOWN", comp.Status)
+	assert.Equal(t, "status code 500", comp.Details["reason"])
+	assert.Equal(t, http.StatusNotFound, get(t, "/actuator/health/service:unknown?fresh=true", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, get(t, "/status/services/slow?fresh=true", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, get(t, "/actuator/health/service:slow?fresh=true", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, "/status/services/canceled?fresh=true", nil))
+
+	require.Len(t, svcs.ResponseCalls(), 3)
+	require.Len(t, svcs.CheckNowCalls(), 6)
+	assert.Empty(t, sts.GetCalls())
+
+	// without services the response is taken from the full status
+	sts.GetFunc = func() (*status.Info, error) {
+		return &status.Info{ExtServices: map[string]external.Response{"mongo": {Name: "mongo", StatusCode: 200}}}, nil
+	}
+	srv.Services = nil
+	ts2 := httptest.NewServer(srv.router())
+	defer ts2.Close()
+	resp, err := http.Get(ts2.URL + "/status/services/mongo?fresh=true")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, sts.GetCalls(), 1)
+}
+
+func TestActuatorHealthGroups(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		return &status.Info{CPUPercent: 10, MemPercent: 50, ExtServices: map[string]external.Response{
+			"docker": {Name: "docker", StatusCode: 200, Health: external.Health{Status: external.HealthUp}},
+			"mongo":  {Name: "mongo", StatusCode: 500, Health: external.Health{Status: external.HealthDown}},
+		}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1", HealthOptions: actuator.HealthOptions{
+		Groups: map[string][]string{"liveness": {"cpu", "memory"}, "readiness": {"service:*"}}}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, path string) (int, actuator.HealthResponse) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var health actuator.HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		return resp.StatusCode, health
+	}
+
+	code, health := get(t, "/actuator/health/liveness")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", health.Status)
+	assert.Len(t, health.Components, 2)
+	assert.Contains(t, health.Components, "cpu")
+	assert.Contains(t, health.Components, "memory")
+
+	code, health = get(t, "/actuator/health/readiness")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "mongo is DOWN")
+	assert.Equal(t, "DOWN", health.Status)
+	assert.Len(t, health.Components, 2)
+
+	srv.UpdateHealthOptions(actuator.HealthOptions{ShowDetails: actuator.ShowDetailsNever,
+		Groups: map[string][]string{"liveness": {"cpu", "memory"}}})
+	code, health = get(t, "/actuator/health/liveness")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", health.Status)
+	assert.Empty(t, health.Components, "details are not shown")
+
+	code, health = get(t, "/actuator/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "DOWN", health.Status)
+	assert.Empty(t, health.Components, "details are not shown")
+
+	code, _ = get(t, "/actuator/health/readiness")
+	assert.Equal(t, http.StatusNotFound, code, "group removed")
+}
+
+func TestActuatorHealthStatusMapping(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		return &status.Info{CPUPercent: 10, MemPercent: 50, ExtServices: map[string]external.Response{
+			"app":   {Name: "app", StatusCode: 200, Health: external.Health{Status: external.HealthUnknown}},
+			"mongo": {Name: "mongo", StatusCode: 503, Health: external.Health{Status: external.HealthOutOfService}},
+		}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var health actuator.HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		return resp.StatusCode, health.Status
+	}
+
+	code, st := get(t, "/actuator/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "OUT_OF_SERVICE", st)
+	code, st = get(t, "/actuator/health/service:app")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UNKNOWN", st)
+
+	srv.UpdateHealthOptions(actuator.HealthOptions{
+		StatusOrder: []string{"DOWN", "UNKNOWN", "OUT_OF_SERVICE", "WARN", "UP"},
+		HTTPMapping: map[string]int{"UNKNOWN": http.StatusInternalServerError, "OUT_OF_SERVICE": http.StatusOK}})
+	code, st = get(t, "/actuator/health")
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "UNKNOWN", st)
+	code, st = get(t, "/actuator/health/service:mongo")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "OUT_OF_SERVICE", st)
+}
+
 func TestActuatorDiscoveryEndpoint(t *testing.T) {
 	srv := Rest{Listen: "localhost:54009", Version: "v1"}
 	ts := httptest.NewServer(srv.router())
@@ -301,6 +599,78 @@ func TestActuatorDiscoveryEndpoint(t *testing.T) {
 	assert.Equal(t, "/actuator", discovery.Links["self"].Href)
 	require.Contains(t, discovery.Links, "health")
 	assert.Equal(t, "/actuator/health", discovery.Links["health"].Href)
+	require.Contains(t, discovery.Links, "prometheus")
+	assert.Equal(t, "/metrics", discovery.Links["prometheus"].Href)
+	require.Contains(t, discovery.Links, "info")
+	assert.Equal(t, "/actuator/info", discovery.Links["info"].Href)
+	require.Contains(t, discovery.Links, "metrics")
+	assert.Equal(t, "/actuator/metrics", discovery.Links["metrics"].Href)
+}
+
+func TestActuatorInfoEndpoint(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		return &status.Info{HostName: "h1", CPUCores: 2,
+			Volumes:     map[string]status.Volume{"root": {Name: "root", Path: "/"}},
+			ExtServices: map[string]external.Response{"mongo": {Name: "mongo"}}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1", Started: time.Now().Add(-time.Minute)}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/actuator/info")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info actuator.InfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, "v1", info.Build.Version)
+	assert.Equal(t, "h1", info.Host.Hostname)
+	assert.InDelta(t, 60, info.Process.Uptime, 1)
+	assert.Equal(t, []actuator.VolumeInfo{{Name: "root", Path: "/"}}, info.Volumes)
+	assert.Equal(t, []string{"mongo"}, info.Services)
+}
+
+func TestActuatorMetricsEndpoint(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		return &status.Info{CPUPercent: 50, Volumes: map[string]status.Volume{
+			"root": {Name: "root", Path: "/", Free: 100}, "data": {Name: "data", Path: "/data", Free: 200}}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	get := func(t *testing.T, path string, res any) int {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if res != nil && resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+		}
+		return resp.StatusCode
+	}
+
+	var names actuator.MetricNamesResponse
+	require.Equal(t, http.StatusOK, get(t, "/actuator/metrics", &names))
+	assert.Contains(t, names.Names, "system.cpu.usage")
+	assert.Contains(t, names.Names, "disk.free")
+	assert.NotContains(t, names.Names, "service.response.time")
+
+	var m actuator.MetricResponse
+	require.Equal(t, http.StatusOK, get(t, "/actuator/metrics/system.cpu.usage", &m))
+	assert.Equal(t, []actuator.Measurement{{Statistic: "VALUE", Value: 0.5}}, m.Measurements)
+
+	require.Equal(t, http.StatusOK, get(t, "/actuator/metrics/disk.free", &m))
+	assert.Equal(t, []actuator.Measurement{{Statistic: "VALUE", Value: 300}}, m.Measurements)
+
+	require.Equal(t, http.StatusOK, get(t, "/actuator/metrics/disk.free?tag=volume:data", &m))
+	assert.Equal(t, []actuator.Measurement{{Statistic: "VALUE", Value: 200}}, m.Measurements)
+	assert.Equal(t, []actuator.Tag{{Tag: "path", Values: []string{"/data"}}}, m.AvailableTags)
+
+	assert.Equal(t, http.StatusBadRequest, get(t, "/actuator/metrics/disk.free?tag=volume", nil))
+	assert.Equal(t, http.StatusNotFound, get(t, "/actuator/metrics/disk.free?tag=volume:unknown", nil))
+	assert.Equal(t, http.StatusNotFound, get(t, "/actuator/metrics/unknown", nil))
 }
 
 func TestActuatorHealthEndpoint_Error(t *testing.T) {
@@ -342,3 +712,265 @@ func TestActuatorHealthComponentEndpoint_Error(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, string(body), "failed to get status")
 }
+
+func TestHistoryEndpoint(t *testing.T) {
+	history := status.NewHistory(10, 10, 4)
+	ts0 := time.Now().Add(-time.Minute)
+	history.Add("cpu", status.Record{Time: ts0, Status: "UP", Value: 12})
+	history.Add("service:rmq_orders", status.Record{Time: ts0, Status: "UP"})
+	history.Add("service:rmq_orders", status.Record{Time: ts0.Add(30 * time.Second), Status: "DOWN", Reason: "queue state crashed"})
+
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{}, Version: "v1", History: history}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	t.Run("service by name", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/status/history/rmq_orders")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var rep status.Report
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rep))
+		assert.Equal(t, "service:rmq_orders", rep.Name)
+		assert.Equal(t, "DOWN", rep.Status)
+		assert.True(t, rep.Since.Equal(ts0.Add(30*time.Second)))
+		require.Len(t, rep.Transitions, 1)
+		assert.Equal(t, "UP", rep.Transitions[0].From)
+		assert.Equal(t, "DOWN", rep.Transitions[0].To)
+		assert.Len(t, rep.Records, 2)
+	})
+
+	t.Run("metric", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/status/history/cpu")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var rep status.Report
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rep))
+		assert.Equal(t, "UP", rep.Status)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/status/history/blah")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestActuatorHealthEndpoint_Flapping(t *testing.T) {
+	sts := &StatusMock{
+		GetFunc: func() (*status.Info, error) {
+			return &status.Info{MemPercent: 50, ExtServices: map[string]external.Response{
+				"docker1": {Name: "docker1", StatusCode: 500, Health: external.Health{Status: external.HealthDown}},
+			}}, nil
+		},
+	}
+	history := status.NewHistory(10, 10, 2)
+	ts0 := time.Now().Add(-time.Minute)
+	for i, st := range []string{"UP", "DOWN", "UP", "DOWN"} {
+		history.Add("service:docker1", status.Record{Time: ts0.Add(time.Duration(i) * time.Second), Status: st})
+	}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1", History: history}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/actuator/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "flapping service keeps its status")
+
+	var health actuator.HealthResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, "DOWN", health.Status)
+	assert.Equal(t, "DOWN", health.Components["service:docker1"].Status)
+	assert.Equal(t, true, health.Components["service:docker1"].Details["flapping"])
+}
+
+func TestSLAEndpoint(t *testing.T) {
+	sla := &SLAReporterMock{SLAFunc: func(name string, period time.Duration, now time.Time) (store.SLA, error) {
+		if name != "s1" {
+			return store.SLA{}, fmt.Errorf("%w: %s", store.ErrNotFound, name)
+		}
+		return store.SLA{Name: name, From: now.Add(-period), To: now, Checks: 10, Uptime: 99.5, Outages: 1, MTTR: 60000,
+			ResponseTime: store.Percentiles{P50: 10, P95: 20, P99: 30}}, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{}, Version: "v1", SLA: sla}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	tbl := []struct {
+		url    string
+		code   int
+		period time.Duration
+	}{
+		{"/sla/s1", http.StatusOK, 30 * 24 * time.Hour},
+		{"/sla/s1?period=7d", http.StatusOK, 7 * 24 * time.Hour},
+		{"/sla/s1?period=12h", http.StatusOK, 12 * time.Hour},
+		{"/sla/s1?period=blah", http.StatusBadRequest, 0},
+		{"/sla/s1?period=-1h", http.StatusBadRequest, 0},
+		{"/sla/unknown", http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.url, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.code, resp.StatusCode)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var res store.SLA
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+			assert.Equal(t, "s1", res.Name)
+			assert.Equal(t, tt.period, res.To.Sub(res.From))
+			assert.InDelta(t, 99.5, res.Uptime, 0.001)
+			assert.Equal(t, int64(20), res.ResponseTime.P95)
+		})
+	}
+}
+
+func TestSLAEndpoint_Disabled(t *testing.T) {
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{}, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/sla/s1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestMaintenanceEndpoints(t *testing.T) {
+	sts := &StatusMock{
+		GetFunc: func() (*status.Info, error) {
+			return &status.Info{MemPercent: 50, ExtServices: map[string]external.Response{
+				"mongo": {Name: "mongo", StatusCode: 500, Health: external.Health{Status: external.HealthDown}},
+			}}, nil
+		},
+	}
+	maint, err := maintenance.New()
+	require.NoError(t, err)
+	maint.MaxDuration = 24 * time.Hour
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1", Maintenance: maint, Auth: Auth{Tokens: []string{"token1"}}}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer token1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	health := func() (code int, res actuator.HealthResponse) {
+		resp := do(http.MethodGet, "/actuator/health", "")
+		defer resp.Body.Close()
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return resp.StatusCode, res
+	}
+	code, h := health()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "DOWN", h.Components["service:mongo"].Status)
+
+	for body, code := range map[string]int{
+		`{"components": ["service:mongo"], "duration": "blah"}`: http.StatusBadRequest,
+		`{"components": [], "duration": "1h"}`:                  http.StatusBadRequest,
+		`{"components": ["service:mongo"], "duration": "-1h"}`:  http.StatusBadRequest,
+		`{"components": ["*"], "duration": "8760h"}`:            http.StatusBadRequest,
+		`{bad json`: http.StatusBadRequest,
+	} {
+		resp := do(http.MethodPost, "/maintenance", body)
+		resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, body)
+	}
+
+	resp, err := http.Post(ts.URL+"/maintenance", "application/json",
+		strings.NewReader(`{"components": ["service:mongo"], "duration": "1h"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "credentials required")
+
+	resp = do(http.MethodPost, "/maintenance",
+		`{"name": "upgrade", "components": ["service:mongo"], "reason": "mongo upgrade", "duration": "1h"}`)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var state maintenance.State
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	assert.Equal(t, "upgrade", state.Name)
+	assert.True(t, state.Active)
+	assert.Equal(t, time.Hour, state.End.Sub(state.Start))
+
+	code, h = health()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "out of service is mapped to 503 by default")
+	assert.Equal(t, "OUT_OF_SERVICE", h.Status)
+	assert.Equal(t, "OUT_OF_SERVICE", h.Components["service:mongo"].Status)
+	assert.Equal(t, "upgrade", h.Components["service:mongo"].Details["maintenance"])
+	assert.Equal(t, "DOWN", h.Components["service:mongo"].Details["actual_status"])
+
+	resp = do(http.MethodGet, "/maintenance", "")
+	defer resp.Body.Close()
+	var states []maintenance.State
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&states))
+	require.Len(t, states, 1)
+	assert.Equal(t, "mongo upgrade", states[0].Reason)
+	assert.True(t, states[0].Manual)
+
+	del := func(name string) int {
+		resp := do(http.MethodDelete, "/maintenance/"+name, "")
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNoContent, del("upgrade"))
+	assert.Equal(t, http.StatusNotFound, del("upgrade"))
+	_, h = health()
+	assert.Equal(t, "DOWN", h.Components["service:mongo"].Status)
+}
+
+func TestMaintenanceEndpoints_NoAuth(t *testing.T) {
+	maint, err := maintenance.New()
+	require.NoError(t, err)
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{}, Version: "v1", Maintenance: maint}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/maintenance", "application/json", strings.NewReader(`{"components": ["*"], "duration": "1h"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "changes require auth")
+
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/maintenance/blah", http.NoBody)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/maintenance")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "list is available")
+	assert.Empty(t, maint.States(time.Now()))
+}
+
+func TestMaintenanceEndpoints_Disabled(t *testing.T) {
+	srv := Rest{Listen: "localhost:54009", Status: &StatusMock{}, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/maintenance")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", string(body))
+
+	resp, err = http.Post(ts.URL+"/maintenance", "application/json", strings.NewReader(`{"components": ["cpu"], "duration": "1h"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,123 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+// ServicesMock is a mock implementation of Services.
+//
+//	func TestSomethingThatUsesServices(t *testing.T) {
+//
+//		// make and configure a mocked Services
+//		mockedServices := &ServicesMock{
+//			CheckNowFunc: func(ctx context.Context, name string) (external.Response, bool, error) {
+//				panic("mock out the CheckNow method")
+//			},
+//			ResponseFunc: func(name string) (external.Response, bool) {
+//				panic("mock out the Response method")
+//			},
+//		}
+//
+//		// use mockedServices in code that requires Services
+//		// and then make assertions.
+//
+//	}
+type ServicesMock struct {
+	// CheckNowFunc mocks the CheckNow method.
+	CheckNowFunc func(ctx context.Context, name string) (external.Response, bool, error)
+
+	// ResponseFunc mocks the Response method.
+	ResponseFunc func(name string) (external.Response, bool)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CheckNow holds details about calls to the CheckNow method.
+		CheckNow []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+		// Response holds details about calls to the Response method.
+		Response []struct {
+			// Name is the name argument value.
+			Name string
+		}
+	}
+	lockCheckNow sync.RWMutex
+	lockResponse sync.RWMutex
+}
+
+// CheckNow calls CheckNowFunc.
+func (mock *ServicesMock) CheckNow(ctx context.Context, name string) (external.Response, bool, error) {
+	if mock.CheckNowFunc == nil {
+		panic("ServicesMock.CheckNowFunc: method is nil but Services.CheckNow was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockCheckNow.Lock()
+	mock.calls.CheckNow = append(mock.calls.CheckNow, callInfo)
+	mock.lockCheckNow.Unlock()
+	return mock.CheckNowFunc(ctx, name)
+}
+
+// CheckNowCalls gets all the calls that were made to CheckNow.
+// Check the length with:
+//
+//	len(mockedServices.CheckNowCalls())
+func (mock *ServicesMock) CheckNowCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockCheckNow.RLock()
+	calls = mock.calls.CheckNow
+	mock.lockCheckNow.RUnlock()
+	return calls
+}
+
+// Response calls ResponseFunc.
+func (mock *ServicesMock) Response(name string) (external.Response, bool) {
+	if mock.ResponseFunc == nil {
+		panic("ServicesMock.ResponseFunc: method is nil but Services.Response was just called")
+	}
+	callInfo := struct {
+		Name string
+	}{
+		Name: name,
+	}
+	mock.lockResponse.Lock()
+	mock.calls.Response = append(mock.calls.Response, callInfo)
+	mock.lockResponse.Unlock()
+	return mock.ResponseFunc(name)
+}
+
+// ResponseCalls gets all the calls that were made to Response.
+// Check the length with:
+//
+//	len(mockedServices.ResponseCalls())
+func (mock *ServicesMock) ResponseCalls() []struct {
+	Name string
+} {
+	var calls []struct {
+		Name string
+	}
+	mock.lockResponse.RLock()
+	calls = mock.calls.Response
+	mock.lockResponse.RUnlock()
+	return calls
+}
@@ -0,0 +1,85 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/store"
+)
+
+// SLAReporterMock is a mock implementation of SLAReporter.
+//
+//	func TestSomethingThatUsesSLAReporter(t *testing.T) {
+//
+//		// make and configure a mocked SLAReporter
+//		mockedSLAReporter := &SLAReporterMock{
+//			SLAFunc: func(name string, period time.Duration, now time.Time) (store.SLA, error) {
+//				panic("mock out the SLA method")
+//			},
+//		}
+//
+//		// use mockedSLAReporter in code that requires SLAReporter
+//		// and then make assertions.
+//
+//	}
+type SLAReporterMock struct {
+	// SLAFunc mocks the SLA method.
+	SLAFunc func(name string, period time.Duration, now time.Time) (store.SLA, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// SLA holds details about calls to the SLA method.
+		SLA []struct {
+			// Name is the name argument value.
+			Name string
+			// Period is the period argument value.
+			Period time.Duration
+			// Now is the now argument value.
+			Now time.Time
+		}
+	}
+	lockSLA sync.RWMutex
+}
+
+// SLA calls SLAFunc.
+func (mock *SLAReporterMock) SLA(name string, period time.Duration, now time.Time) (store.SLA, error) {
+	if mock.SLAFunc == nil {
+		panic("SLAReporterMock.SLAFunc: method is nil but SLAReporter.SLA was just called")
+	}
+	callInfo := struct {
+		Name   string
+		Period time.Duration
+		Now    time.Time
+	}{
+		Name:   name,
+		Period: period,
+		Now:    now,
+	}
+	mock.lockSLA.Lock()
+	mock.calls.SLA = append(mock.calls.SLA, callInfo)
+	mock.lockSLA.Unlock()
+	return mock.SLAFunc(name, period, now)
+}
+
+// SLACalls gets all the calls that were made to SLA.
+// Check the length with:
+//
+//	len(mockedSLAReporter.SLACalls())
+func (mock *SLAReporterMock) SLACalls() []struct {
+	Name   string
+	Period time.Duration
+	Now    time.Time
+} {
+	var calls []struct {
+		Name   string
+		Period time.Duration
+		Now    time.Time
+	}
+	mock.lockSLA.RLock()
+	calls = mock.calls.SLA
+	mock.lockSLA.RUnlock()
+	return calls
+}
@@ -18,6 +18,9 @@ import (
 //			GetFunc: func() (*status.Info, error) {
 //				panic("mock out the Get method")
 //			},
+//			GetFilteredFunc: func(f status.Filter) (*status.Info, error) {
+//				panic("mock out the GetFiltered method")
+//			},
 //		}
 //
 //		// use mockedStatus in code that requires Status
@@ -28,13 +31,22 @@ type StatusMock struct {
 	// GetFunc mocks the Get method.
 	GetFunc func() (*status.Info, error)
 
+	// GetFilteredFunc mocks the GetFiltered method.
+	GetFilteredFunc func(f status.Filter) (*status.Info, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// Get holds details about calls to the Get method.
 		Get []struct {
 		}
+		// GetFiltered holds details about calls to the GetFiltered method.
+		GetFiltered []struct {
+			// F is the f argument value.
+			F status.Filter
+		}
 	}
-	lockGet sync.RWMutex
+	lockGet         sync.RWMutex
+	lockGetFiltered sync.RWMutex
 }
 
 // Get calls GetFunc.
@@ -63,3 +75,35 @@ func (mock *StatusMock) GetCalls() []struct {
 	mock.lockGet.RUnlock()
 	return calls
 }
+
+// GetFiltered calls GetFilteredFunc.
+func (mock *StatusMock) GetFiltered(f status.Filter) (*status.Info, error) {
+	if mock.GetFilteredFunc == nil {
+		panic("StatusMock.GetFilteredFunc: method is nil but Status.GetFiltered was just called")
+	}
+	callInfo := struct {
+		F status.Filter
+	}{
+		F: f,
+	}
+	mock.lockGetFiltered.Lock()
+	mock.calls.GetFiltered = append(mock.calls.GetFiltered, callInfo)
+	mock.lockGetFiltered.Unlock()
+	return mock.GetFilteredFunc(f)
+}
+
+// GetFilteredCalls gets all the calls that were made to GetFiltered.
+// Check the length with:
+//
+//	len(mockedStatus.GetFilteredCalls())
+func (mock *StatusMock) GetFilteredCalls() []struct {
+	F status.Filter
+} {
+	var calls []struct {
+		F status.Filter
+	}
+	mock.lockGetFiltered.RLock()
+	calls = mock.calls.GetFiltered
+	mock.lockGetFiltered.RUnlock()
+	return calls
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+const (
+	streamPattern      = "GET /status/stream"
+	maxStreams         = 100              // limit of concurrent streams, counted separately from other requests
+	streamKeep         = 1000             // number of recent events kept to resume streams
+	streamBuffer       = 100              // events buffered per stream, slower streams are closed to resume later
+	streamRetry        = 3 * time.Second  // reconnection delay suggested to clients
+	streamWriteTimeout = 10 * time.Second // timeout of each write to a stream
+)
+
+// streamEvent is an event of status stream with json encoded data
+type streamEvent struct {
+	id   string
+	name string
+	data []byte
+}
+
+// statusStream polls status while there are subscribers, and publishes changes of service responses,
+// volume usage and actuator component statuses as events. Recent events are kept to resume streams by Last-Event-ID.
+type statusStream struct {
+	source   Status
+	evaluate func(info *status.Info) *actuator.HealthResponse
+	interval time.Duration
+	epoch    string // start time of the stream, makes event ids unique across restarts
+
+	mu     sync.Mutex
+	seq    uint64
+	recent []streamEvent
+	info   *status.Info
+	health *actuator.HealthResponse
+	subs   map[chan streamEvent]struct{}
+	stop   chan struct{}
+}
+
+// streamRemoved is the data of "removed" event, for services, volumes and components not reported anymore
+type streamRemoved struct {
+	Kind string `json:"kind"` // service, volume or component
+	Name string `json:"name"`
+}
+
+// streamComponent is the data of "component" event, sent on change of the component status
+type streamComponent struct {
+	Name      string             `json:"name"`
+	OldStatus string             `json:"old_status,omitempty"`
+	Component actuator.Component `json:"component"`
+}
+
+func newStatusStream(source Status, evaluate func(*status.Info) *actuator.HealthResponse, interval time.Duration) *statusStream {
+	return &statusStream{source: source, evaluate: evaluate, interval: interval,
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36), subs: map[chan streamEvent]struct{}{}}
+}
+
+// subscribe registers a new subscriber and returns events to send first, with the channel of the following events.
+// The first events are ones after lastEventID if they are still kept, or the full snapshot otherwise.
+func (s *statusStream) subscribe(lastEventID string) ([]streamEvent, chan streamEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.poll(); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan streamEvent, streamBuffer)
+	s.subs[ch] = struct{}{}
+	if len(s.subs) == 1 {
+		s.stop = make(chan struct{})
+		go s.run(s.stop, s.interval)
+	}
+
+	if events, ok := s.eventsAfter(lastEventID); ok {
+		return events, ch, nil
+	}
+	data, err := json.Marshal(struct {
+		Status *status.Info             `json:"status"`
+		Health *actuator.HealthResponse `json:"health"`
+	}{s.info, s.health})
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't marshal snapshot: %w", err)
+	}
+	return []streamEvent{{id: s.eventID(s.seq), name: "snapshot", data: data}}, ch, nil
+}
+
+// unsubscribe removes the subscriber, polling stops with the last one
+func (s *statusStream) unsubscribe(ch chan streamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[ch]; !ok {
+		return // already dropped as slow
+	}
+	delete(s.subs, ch)
+	if len(s.subs) == 0 {
+		close(s.stop)
+	}
+}
+
+// run polls status every interval until stopped
+func (s *statusStream) run(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if err := s.poll(); err != nil {
+				log.Printf("[WARN] status stream, %v", err)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// poll gets status and publishes changes since the previous poll, should be called under lock
+func (s *statusStream) poll() error {
+	info, err := s.source.Get()
+	if err != nil {
+		return fmt.Errorf("can't get status: %w", err)
+	}
+	health := s.evaluate(info)
+	if s.info != nil {
+		s.publishChanges(info, health)
+	}
+	s.info, s.health = info, health
+	return nil
+}
+
+// publishChanges publishes changed service responses, volume usage and component statuses, and removed ones
+func (s *statusStream) publishChanges(info *status.Info, health *actuator.HealthResponse) {
+	for _, name := range slices.Sorted(maps.Keys(info.ExtServices)) {
+		prev, ok := s.info.ExtServices[name]
+		if !ok || serviceChanged(prev, info.ExtServices[name]) {
+			s.publish("service", info.ExtServices[name])
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(info.Volumes)) {
+		vol, ok := s.info.Volumes[name]
+		if !ok || vol.UsagePercent != info.Volumes[name].UsagePercent || vol.Path != info.Volumes[name].Path {
+			s.publish("volume", info.Volumes[name])
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(health.Components)) {
+		comp := health.Components[name]
+		prev, ok := s.health.Components[name]
+		if !ok || prev.Status != comp.Status {
+			s.publish("component", streamComponent{Name: name, OldStatus: prev.Status, Component: comp})
+		}
+	}
+
+	removed := func(kind string, names []string, exists func(name string) bool) {
+		for _, name := range names {
+			if !exists(name) {
+				s.publish("removed", streamRemoved{Kind: kind, Name: name})
+			}
+		}
+	}
+	removed("service", slices.Sorted(maps.Keys(s.info.ExtServices)),
+		func(name string) bool { _, ok := info.ExtServices[name]; return ok })
+	removed("volume", slices.Sorted(maps.Keys(s.info.Volumes)),
+		func(name string) bool { _, ok := info.Volumes[name]; return ok })
+	removed("component", slices.Sorted(maps.Keys(s.health.Components)),
+		func(name string) bool { _, ok := health.Components[name]; return ok })
+}
+
+// serviceChanged checks if the service response changed meaningfully, i.e. its health, status code, error or maintenance.
+// Time of the check, response time and body change on each check and are not compared.
+func serviceChanged(prev, resp external.Response) bool {
+	return prev.Health != resp.Health || prev.StatusCode != resp.StatusCode || prev.Error != resp.Error ||
+		prev.ErrorCategory != resp.ErrorCategory || prev.Maintenance != resp.Maintenance
+}
+
+// publish sends the event to all subscribers and keeps it for resume. Subscribers not keeping up are dropped,
+// their streams closed, so clients reconnect and resume from the last received event.
+func (s *statusStream) publish(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[WARN] can't marshal %s event, %v", name, err)
+		return
+	}
+	s.seq++
+	ev := streamEvent{id: s.eventID(s.seq), name: name, data: data}
+	s.recent = append(s.recent, ev)
+	if len(s.recent) > streamKeep {
+		s.recent = s.recent[len(s.recent)-streamKeep:]
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("[WARN] status stream is too slow, dropped")
+			delete(s.subs, ch)
+			close(ch)
+			if len(s.subs) == 0 {
+				close(s.stop)
+			}
+		}
+	}
+}
+
+// eventsAfter returns kept events after the event id, false if the id is unknown or its events are not kept
+func (s *statusStream) eventsAfter(lastEventID string) ([]streamEvent, bool) {
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != s.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || seq > s.seq {
+		return nil, false
+	}
+	first := s.seq - uint64(len(s.recent)) + 1 // sequence of the oldest kept event
+	if seq+1 < first {
+		return nil, false // some events after the id are not kept
+	}
+	return slices.Clone(s.recent[len(s.recent)-int(s.seq-seq):]), true
+}
+
+func (s *statusStream) eventID(seq uint64) string {
+	return s.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// streamHandler sends status events to the client until it disconnects, with heartbeat comments in between
+func (s *Rest) streamHandler(stream *statusStream, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, ch, err := stream.subscribe(r.Header.Get("Last-Event-ID"))
+		if err != nil {
+			rest.SendErrorJSON(w, r, log.Default(), http.StatusInternalServerError, err, "failed to get status")
+			return
+		}
+		defer stream.unsubscribe(ch)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // disable buffering by nginx
+		rc := http.NewResponseController(w)
+		write := func(format string, args ...any) error {
+			// server write timeout is for regular requests, each write to the stream has its own deadline
+			if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, format, args...); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		if err := write("retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+			log.Printf("[WARN] can't write to status stream, %v", err)
+			return
+		}
+		for _, ev := range events {
+			if err := write("id: %s\nevent: %s\ndata: %s\n\n", ev.id, ev.name, ev.data); err != nil {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev, ok := <-ch:
+				if !ok {
+					return // dropped as slow
+				}
+				if err := write("id: %s\nevent: %s\ndata: %s\n\n", ev.id, ev.name, ev.data); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := write(": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// skipStreams applies the middleware to all requests except status streams, i.e. to not count long-lived
+// streams in the limit of running requests
+func skipStreams(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Pattern == streamPattern {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
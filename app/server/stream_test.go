@@ -0,0 +1,263 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/actuator"
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestStatusStream(t *testing.T) {
+	var mu sync.Mutex
+	info := &status.Info{CPUPercent: 10, MemPercent: 20,
+		Volumes:     map[string]status.Volume{"root": {Name: "root", Path: "/", UsagePercent: 50}},
+		ExtServices: map[string]external.Response{"s1": {Name: "s1", StatusCode: 200, ResponseTime: 5, Age: 1}}}
+	setInfo := func(fn func(info *status.Info)) {
+		mu.Lock()
+		defer mu.Unlock()
+		res := *info
+		res.Volumes = map[string]status.Volume{}
+		for k, v := range info.Volumes {
+			res.Volumes[k] = v
+		}
+		res.ExtServices = map[string]external.Response{}
+		for k, v := range info.ExtServices {
+			res.ExtServices[k] = v
+		}
+		fn(&res)
+		info = &res
+	}
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return info, nil
+	}}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1",
+		StreamInterval: 10 * time.Millisecond, StreamHeartbeat: 50 * time.Millisecond}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	events, cancel := connectStream(t, ts.URL, "")
+	ev := <-events
+	assert.Equal(t, "snapshot", ev.name)
+	var snapshot struct {
+		Status status.Info             `json:"status"`
+		Health actuator.HealthResponse `json:"health"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &snapshot))
+	assert.Equal(t, 10, snapshot.Status.CPUPercent)
+	assert.Equal(t, "UP", snapshot.Health.Status)
+	assert.Contains(t, snapshot.Health.Components, "service:s1")
+
+	// age, time of the check, response time and cpu percent changes are not reported, heartbeat sent in between
+	setInfo(func(info *status.Info) {
+		info.CPUPercent = 15
+		s1 := info.ExtServices["s1"]
+		s1.Age, s1.CheckedAt, s1.ResponseTime = 100, time.Now(), 7
+		s1.Body = map[string]any{"text": "pong"}
+		info.ExtServices["s1"] = s1
+	})
+	ev = <-events
+	assert.Equal(t, "", ev.name)
+	assert.Equal(t, "heartbeat", ev.comment)
+
+	setInfo(func(info *status.Info) {
+		info.ExtServices["s1"] = external.Response{Name: "s1", StatusCode: 500,
+			Health: external.Health{Status: external.HealthDown, Reason: "status code 500"}}
+		info.Volumes["root"] = status.Volume{Name: "root", Path: "/", UsagePercent: 60}
+	})
+	ev = nextEvent(t, events)
+	assert.Equal(t, "service", ev.name)
+	assert.Contains(t, ev.data, `"status_code":500`)
+	serviceID := ev.id
+	ev = nextEvent(t, events)
+	assert.Equal(t, "volume", ev.name)
+	assert.Contains(t, ev.data, `"usage_percent":60`)
+	ev = nextEvent(t, events)
+	assert.Equal(t, "component", ev.name)
+	assert.JSONEq(t, `{"name":"service:s1","old_status":"UP","component":{"status":"DOWN",
+		"details":{"status_code":500,"response_time":0,"reason":"status code 500"}}}`, ev.data)
+
+	setInfo(func(info *status.Info) { delete(info.ExtServices, "s1") })
+	ev = nextEvent(t, events)
+	assert.Equal(t, "removed", ev.name)
+	assert.JSONEq(t, `{"kind":"service","name":"s1"}`, ev.data)
+	ev = nextEvent(t, events)
+	assert.Equal(t, "removed", ev.name)
+	assert.JSONEq(t, `{"kind":"component","name":"service:s1"}`, ev.data)
+	cancel()
+
+	// resume from the service event, the following events are sent instead of snapshot
+	events, cancel = connectStream(t, ts.URL, serviceID)
+	defer cancel()
+	for _, exp := range []string{"volume", "component", "removed", "removed"} {
+		assert.Equal(t, exp, nextEvent(t, events).name)
+	}
+
+	// unknown event id, i.e. after restart, gets snapshot
+	events, cancel2 := connectStream(t, ts.URL, "blah-1")
+	defer cancel2()
+	assert.Equal(t, "snapshot", nextEvent(t, events).name)
+}
+
+func TestStatusStream_eventsAfter(t *testing.T) {
+	s := newStatusStream(nil, nil, time.Second)
+	s.subs = map[chan streamEvent]struct{}{}
+	for i := range streamKeep + 10 {
+		s.publish("test", i)
+	}
+	id := func(seq string) string { return s.epoch + "-" + seq }
+
+	events, ok := s.eventsAfter(id("1005"))
+	require.True(t, ok)
+	require.Len(t, events, 5)
+	assert.Equal(t, id("1006"), events[0].id)
+	assert.Equal(t, "1005", string(events[0].data))
+
+	events, ok = s.eventsAfter(id("1010"))
+	require.True(t, ok)
+	assert.Empty(t, events)
+
+	events, ok = s.eventsAfter(id("10"))
+	require.True(t, ok, "the oldest kept is 11")
+	assert.Len(t, events, streamKeep)
+
+	for _, lastID := range []string{"", "blah", id("9"), id("1011"), "other-5", id("x")} {
+		_, ok = s.eventsAfter(lastID)
+		assert.False(t, ok, lastID)
+	}
+}
+
+func TestSkipStreams(t *testing.T) {
+	reject := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+	}
+	h := skipStreams(reject)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for pattern, code := range map[string]int{streamPattern: http.StatusOK, "GET /status": http.StatusServiceUnavailable} {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.Pattern = pattern
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, pattern)
+	}
+}
+
+func TestStatusStream_NotThrottled(t *testing.T) {
+	sts := &StatusMock{GetFunc: func() (*status.Info, error) { return &status.Info{}, nil }}
+	srv := Rest{Listen: "localhost:54009", Status: sts, Version: "v1"}
+	ts := httptest.NewServer(srv.router())
+	defer ts.Close()
+
+	// streams are not counted in the limit of 100 running requests, so the requests after them are not rejected
+	for range 5 {
+		events, cancel := connectStream(t, ts.URL, "")
+		defer cancel()
+		assert.Equal(t, "snapshot", nextEvent(t, events).name)
+	}
+	resp, err := http.Get(ts.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+type sseEvent struct {
+	id, name, data, comment string
+}
+
+// connectStream connects to status stream and returns channel of received events and comments
+func connectStream(t *testing.T, url, lastEventID string) (events <-chan sseEvent, cancel func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/status/stream", http.NoBody)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	ch := make(chan sseEvent, 100)
+	go func() {
+		defer resp.Body.Close()
+		defer close(ch)
+		scanner := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if ev != (sseEvent{}) {
+					ch <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, ": "):
+				ev.comment = strings.TrimPrefix(line, ": ")
+			case strings.HasPrefix(line, "id: "):
+				ev.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return ch, cancel
+}
+
+// nextEvent returns the next event, skipping heartbeats
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.comment != "" {
+				continue
+			}
+			return ev
+		case <-timeout:
+			t.Fatal("no event")
+			return sseEvent{}
+		}
+	}
+}
+
+func TestServiceChanged(t *testing.T) {
+	base := external.Response{Name: "s1", StatusCode: 200, ResponseTime: 5, Health: external.Health{Status: external.HealthUp},
+		CheckedAt: time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)}
+	tbl := []struct {
+		name string
+		fn   func(r *external.Response)
+		res  bool
+	}{
+		{"same", func(r *external.Response) {}, false},
+		{"new check", func(r *external.Response) { r.CheckedAt, r.ResponseTime, r.Age = time.Now(), 10, 100 }, false},
+		{"body", func(r *external.Response) { r.Body = map[string]any{"text": "pong"} }, false},
+		{"health", func(r *external.Response) { r.Health = external.Health{Status: external.HealthWarn, Reason: "slow"} }, true},
+		{"status code", func(r *external.Response) { r.StatusCode = 204 }, true},
+		{"error", func(r *external.Response) { r.Error, r.ErrorCategory = "failed", external.ErrorOther }, true},
+		{"maintenance", func(r *external.Response) { r.Maintenance = "upgrade" }, true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := base
+			tt.fn(&resp)
+			assert.Equal(t, tt.res, serviceChanged(base, resp))
+		})
+	}
+}
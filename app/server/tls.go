@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// tlsConfig makes tls config with the certificate reloaded on change of the files,
+// and client certificates verified against the CA bundle if set
+func (s *Rest) tlsConfig() (*tls.Config, error) {
+	certs := &certReloader{certFile: s.TLSCert, keyFile: s.TLSKey}
+	if err := certs.reload(); err != nil {
+		return nil, err
+	}
+	res := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.getCertificate}
+
+	if s.TLSClientCA != "" {
+		data, err := os.ReadFile(s.TLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("can't read client CA %s: %w", s.TLSClientCA, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in client CA %s", s.TLSClientCA)
+		}
+		res.ClientCAs, res.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	}
+	return res, nil
+}
+
+// certReloader keeps the certificate loaded from cert and key files, reloads it if any file changed
+type certReloader struct {
+	certFile, keyFile string
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	loaded [2]time.Time // modification times of cert and key files, when the certificate was loaded
+}
+
+// getCertificate returns the current certificate, reloaded if files changed.
+// The previous certificate is kept if the new one can't be loaded, i.e. the key is not written yet.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if modTimes, err := c.modTimes(); err == nil {
+		c.mu.RLock()
+		changed := modTimes != c.loaded
+		c.mu.RUnlock()
+		if changed {
+			if err := c.reload(); err != nil {
+				log.Printf("[WARN] can't reload certificate, keep the previous one, %v", err)
+				c.mu.Lock()
+				c.loaded = modTimes // don't retry until the next change
+				c.mu.Unlock()
+			}
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// reload loads certificate from the files
+func (c *certReloader) reload() error {
+	modTimes, err := c.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("can't load certificate: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil {
+		log.Printf("[INFO] certificate %s reloaded", c.certFile)
+	}
+	c.cert, c.loaded = &cert, modTimes
+	return nil
+}
+
+// modTimes returns modification times of cert and key files
+func (c *certReloader) modTimes() ([2]time.Time, error) {
+	var res [2]time.Time
+	for i, fname := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(fname)
+		if err != nil {
+			return res, fmt.Errorf("can't stat %s: %w", fname, err)
+		}
+		res[i] = fi.ModTime()
+	}
+	return res, nil
+}
+
+// redirectHandler redirects http requests to https on the port of listen address
+func redirectHandler(listen string) http.Handler {
+	_, port, _ := net.SplitHostPort(listen)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
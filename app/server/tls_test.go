@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRest_RunTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "server1")
+
+	port, redirectPort := freePort(t), freePort(t)
+	srv := Rest{Listen: fmt.Sprintf("127.0.0.1:%d", port), Version: "v1", TLSCert: certFile, TLSKey: keyFile,
+		RedirectListen: fmt.Sprintf("127.0.0.1:%d", redirectPort)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- srv.Run(ctx) }()
+	defer func() {
+		cancel()
+		assert.EqualError(t, <-done, "http: Server closed")
+	}()
+
+	client := http.Client{Timeout: time.Second, Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, DisableKeepAlives: true}} //nolint:gosec // self-signed
+	serverName := func() string {
+		t.Helper()
+		resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/ping", port))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	waitFor(t, func() bool {
+		resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/ping", port))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err == nil
+	})
+	assert.Equal(t, "server1", serverName())
+
+	// certificate reloaded on change
+	time.Sleep(10 * time.Millisecond) // make sure modification time changed
+	writeCert(t, certFile, keyFile, "server2")
+	assert.Equal(t, "server2", serverName())
+
+	// broken certificate is ignored, the previous one is kept
+	require.NoError(t, os.WriteFile(keyFile, []byte("bad key"), 0o600))
+	assert.Equal(t, "server2", serverName())
+
+	// plain http requests redirected to https
+	noRedirect := http.Client{Timeout: time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(fmt.Sprintf("http://127.0.0.1:%d/status?x=1", redirectPort))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, fmt.Sprintf("https://127.0.0.1:%d/status?x=1", port), resp.Header.Get("Location"))
+}
+
+func TestRest_RunMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := makeCert(t, "ca", nil, nil)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caCert.Raw)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "server")
+
+	port := freePort(t)
+	srv := Rest{Listen: fmt.Sprintf("127.0.0.1:%d", port), Version: "v1", TLSCert: certFile, TLSKey: keyFile,
+		TLSClientCA: filepath.Join(dir, "ca.pem")}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- srv.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	get := func(certs ...tls.Certificate) error {
+		client := http.Client{Timeout: time.Second, Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs}}} //nolint:gosec // self-signed
+		resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/ping", port))
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	waitFor(t, func() bool {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+
+	require.Error(t, get(), "client certificate required")
+
+	clientCert, clientKey := makeCert(t, "client", caCert, caKey)
+	require.NoError(t, get(tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}))
+
+	otherCert, otherKey := makeCert(t, "other", nil, nil)
+	require.Error(t, get(tls.Certificate{Certificate: [][]byte{otherCert.Raw}, PrivateKey: otherKey}),
+		"client certificate not signed by CA")
+}
+
+func TestRest_RunTLSErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	srv := Rest{Listen: "127.0.0.1:0", TLSCert: certFile, TLSKey: keyFile}
+	require.ErrorContains(t, srv.Run(context.Background()), "can't stat")
+
+	writeCert(t, certFile, keyFile, "server")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), []byte("blah"), 0o600))
+	srv.TLSClientCA = filepath.Join(dir, "ca.pem")
+	require.ErrorContains(t, srv.Run(context.Background()), "no certificates in client CA")
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tbl := []struct {
+		listen, host, exp string
+	}{
+		{":8443", "example.com:8080", "https://example.com:8443/status?a=1"},
+		{":443", "example.com:8080", "https://example.com/status?a=1"},
+		{"127.0.0.1:8443", "example.com", "https://example.com:8443/status?a=1"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.listen+" "+tt.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://"+tt.host+"/status?a=1", http.NoBody)
+			rr := httptest.NewRecorder()
+			redirectHandler(tt.listen).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, tt.exp, rr.Header().Get("Location"))
+		})
+	}
+}
+
+// makeCert makes certificate with the common name, signed by parent or self-signed if parent is nil
+func makeCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{SerialNumber: serial, Subject: pkix.Name{CommonName: cn},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}, BasicConstraintsValid: true, IsCA: parent == nil}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writeCert writes self-signed certificate and its key to files
+func writeCert(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	cert, key := makeCert(t, cn, nil, nil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	writePEM(t, certFile, "CERTIFICATE", cert.Raw)
+}
+
+func writePEM(t *testing.T, fname, typ string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(fname, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	t.Helper()
+	for range 100 {
+		if fn() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
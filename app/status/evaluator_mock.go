@@ -0,0 +1,70 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package status
+
+import (
+	"sync"
+)
+
+// EvaluatorMock is a mock implementation of Evaluator.
+//
+//	func TestSomethingThatUsesEvaluator(t *testing.T) {
+//
+//		// make and configure a mocked Evaluator
+//		mockedEvaluator := &EvaluatorMock{
+//			EvaluateFunc: func(info *Info) map[string]Record {
+//				panic("mock out the Evaluate method")
+//			},
+//		}
+//
+//		// use mockedEvaluator in code that requires Evaluator
+//		// and then make assertions.
+//
+//	}
+type EvaluatorMock struct {
+	// EvaluateFunc mocks the Evaluate method.
+	EvaluateFunc func(info *Info) map[string]Record
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Evaluate holds details about calls to the Evaluate method.
+		Evaluate []struct {
+			// Info is the info argument value.
+			Info *Info
+		}
+	}
+	lockEvaluate sync.RWMutex
+}
+
+// Evaluate calls EvaluateFunc.
+func (mock *EvaluatorMock) Evaluate(info *Info) map[string]Record {
+	if mock.EvaluateFunc == nil {
+		panic("EvaluatorMock.EvaluateFunc: method is nil but Evaluator.Evaluate was just called")
+	}
+	callInfo := struct {
+		Info *Info
+	}{
+		Info: info,
+	}
+	mock.lockEvaluate.Lock()
+	mock.calls.Evaluate = append(mock.calls.Evaluate, callInfo)
+	mock.lockEvaluate.Unlock()
+	return mock.EvaluateFunc(info)
+}
+
+// EvaluateCalls gets all the calls that were made to Evaluate.
+// Check the length with:
+//
+//	len(mockedEvaluator.EvaluateCalls())
+func (mock *EvaluatorMock) EvaluateCalls() []struct {
+	Info *Info
+} {
+	var calls []struct {
+		Info *Info
+	}
+	mock.lockEvaluate.RLock()
+	calls = mock.calls.Evaluate
+	mock.lockEvaluate.RUnlock()
+	return calls
+}
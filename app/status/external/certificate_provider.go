@@ -2,7 +2,9 @@ package external
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 )
@@ -12,11 +14,39 @@ type CertificateProvider struct {
 	TimeOut time.Duration
 }
 
+// certificateConfig is an entry of "certificate" services section
+type certificateConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// Spec returns spec of the provider for cert urls and "certificate" config section
+func (c *CertificateProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "certificate",
+		Schemes: []string{"cert"},
+		ParseURL: func(name, u string) (Request, error) {
+			if trimSchemes(u, "cert") == "" {
+				return Request{}, errors.New("host is required")
+			}
+			return Request{Name: name, URL: u}, nil
+		},
+		ParseConfig: configParser(func(cfg certificateConfig) (Request, error) {
+			host := trimSchemes(cfg.URL, "https", "http")
+			if host == "" {
+				return Request{}, errors.New("url is required")
+			}
+			return Request{Name: cfg.Name, URL: "cert://" + host}, nil
+		}),
+	}
+}
+
 // Status url looks like: cert://example.com. It will try to get SSL certificate and check if it is valid and not going to expire soon
 func (c *CertificateProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
 	addr := strings.TrimPrefix(req.URL, "cert://") + ":443"
-	conn, err := tls.Dial("tcp", addr, &tls.Config{}) //nolint:gosec // we don't care about cert version
+	dialer := &net.Dialer{Timeout: req.timeout(c.TimeOut)}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{}) //nolint:gosec // we don't care about cert version
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
@@ -34,24 +64,33 @@ func (c *CertificateProvider) Status(req Request) (*Response, error) {
 	}
 
 	daysLeft := int(time.Until(earlierCert).Hours() / 24)
+	status, health := c.health(earlierCert, time.Now())
 	body := map[string]any{
 		"expire":    earlierCert.Format(time.RFC3339),
 		"days_left": daysLeft,
 		"host":      strings.Replace(req.URL, "cert://", "https://", 1),
-		"status":    "ok",
-	}
-	if daysLeft < 5 {
-		body["status"] = fmt.Sprintf("expiring soon, in %d days", daysLeft)
-	}
-	if earlierCert.Before(time.Now()) {
-		body["status"] = "expired"
+		"status":    status,
 	}
 
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   200,
 		Body:         body,
+		Health:       health,
 		ResponseTime: time.Since(st).Milliseconds(),
 	}
 	return &result, nil
 }
+
+// health returns body status and health verdict for the certificate expiration time.
+// certificate expiring in less than 5 days is WARN, expired certificate is DOWN
+func (c *CertificateProvider) health(expire, now time.Time) (string, Health) {
+	if expire.Before(now) {
+		return "expired", Health{Status: HealthDown, Reason: "certificate expired"}
+	}
+	if daysLeft := int(expire.Sub(now).Hours() / 24); daysLeft < 5 {
+		status := fmt.Sprintf("expiring soon, in %d days", daysLeft)
+		return status, Health{Status: HealthWarn, Reason: "certificate " + status}
+	}
+	return "ok", Health{Status: HealthUp}
+}
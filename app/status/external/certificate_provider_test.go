@@ -24,6 +24,31 @@ func TestCertificateProvider_Status(t *testing.T) {
 	t.Logf("expire: %+v", exp)
 }
 
+func TestCertificateProvider_health(t *testing.T) {
+	cp := CertificateProvider{}
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tbl := []struct {
+		name   string
+		expire time.Time
+		status string
+		health Health
+	}{
+		{"valid", now.Add(30 * 24 * time.Hour), "ok", Health{Status: HealthUp}},
+		{"expiring soon", now.Add(3*24*time.Hour + time.Hour), "expiring soon, in 3 days",
+			Health{Status: HealthWarn, Reason: "certificate expiring soon, in 3 days"}},
+		{"expired", now.Add(-time.Hour), "expired", Health{Status: HealthDown, Reason: "certificate expired"}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			status, health := cp.health(tt.expire, now)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.health, health)
+		})
+	}
+}
+
 func TestCertificateProvider_StatusFailed(t *testing.T) {
 	cp := CertificateProvider{TimeOut: time.Minute}
 	_, err := cp.Status(Request{Name: "test", URL: "cert://127.0.0.1"})
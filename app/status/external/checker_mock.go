@@ -0,0 +1,107 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package external
+
+import (
+	"sync"
+)
+
+// CheckerMock is a mock implementation of Checker.
+//
+//	func TestSomethingThatUsesChecker(t *testing.T) {
+//
+//		// make and configure a mocked Checker
+//		mockedChecker := &CheckerMock{
+//			CheckFunc: func(req Request) Response {
+//				panic("mock out the Check method")
+//			},
+//			RequestsFunc: func() []Request {
+//				panic("mock out the Requests method")
+//			},
+//		}
+//
+//		// use mockedChecker in code that requires Checker
+//		// and then make assertions.
+//
+//	}
+type CheckerMock struct {
+	// CheckFunc mocks the Check method.
+	CheckFunc func(req Request) Response
+
+	// RequestsFunc mocks the Requests method.
+	RequestsFunc func() []Request
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Check holds details about calls to the Check method.
+		Check []struct {
+			// Req is the req argument value.
+			Req Request
+		}
+		// Requests holds details about calls to the Requests method.
+		Requests []struct {
+		}
+	}
+	lockCheck    sync.RWMutex
+	lockRequests sync.RWMutex
+}
+
+// Check calls CheckFunc.
+func (mock *CheckerMock) Check(req Request) Response {
+	if mock.CheckFunc == nil {
+		panic("CheckerMock.CheckFunc: method is nil but Checker.Check was just called")
+	}
+	callInfo := struct {
+		Req Request
+	}{
+		Req: req,
+	}
+	mock.lockCheck.Lock()
+	mock.calls.Check = append(mock.calls.Check, callInfo)
+	mock.lockCheck.Unlock()
+	return mock.CheckFunc(req)
+}
+
+// CheckCalls gets all the calls that were made to Check.
+// Check the length with:
+//
+//	len(mockedChecker.CheckCalls())
+func (mock *CheckerMock) CheckCalls() []struct {
+	Req Request
+} {
+	var calls []struct {
+		Req Request
+	}
+	mock.lockCheck.RLock()
+	calls = mock.calls.Check
+	mock.lockCheck.RUnlock()
+	return calls
+}
+
+// Requests calls RequestsFunc.
+func (mock *CheckerMock) Requests() []Request {
+	if mock.RequestsFunc == nil {
+		panic("CheckerMock.RequestsFunc: method is nil but Checker.Requests was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockRequests.Lock()
+	mock.calls.Requests = append(mock.calls.Requests, callInfo)
+	mock.lockRequests.Unlock()
+	return mock.RequestsFunc()
+}
+
+// RequestsCalls gets all the calls that were made to Requests.
+// Check the length with:
+//
+//	len(mockedChecker.RequestsCalls())
+func (mock *CheckerMock) RequestsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockRequests.RLock()
+	calls = mock.calls.Requests
+	mock.lockRequests.RUnlock()
+	return calls
+}
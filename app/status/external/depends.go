@@ -0,0 +1,45 @@
+package external
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ValidateDependencies checks each dependency of the requests is a known service and dependencies make no cycles,
+// including a service depending on itself. Names of requests should be unique.
+func ValidateDependencies(reqs []Request) error {
+	byName := make(map[string]Request, len(reqs))
+	for _, r := range reqs {
+		byName[r.Name] = r
+	}
+
+	checked := map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if checked[name] {
+			return nil
+		}
+		path = append(path, name)
+		if slices.Contains(path[:len(path)-1], name) {
+			return fmt.Errorf("dependency cycle %s", strings.Join(path[slices.Index(path, name):], " -> "))
+		}
+		for _, dep := range byName[name].Options.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("service %q depends on unknown service %q", name, dep)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		checked[name] = true
+		return nil
+	}
+
+	for _, r := range reqs {
+		if err := visit(r.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package external
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	req := func(name string, deps ...string) Request {
+		return Request{Name: name, URL: "http://" + name, Options: Options{DependsOn: deps}}
+	}
+	tbl := []struct {
+		name string
+		reqs []Request
+		err  string
+	}{
+		{"no dependencies", []Request{req("s1"), req("s2")}, ""},
+		{"chain", []Request{req("s3", "s2"), req("s2", "s1"), req("s1")}, ""},
+		{"diamond", []Request{req("s1"), req("s2", "s1"), req("s3", "s1"), req("s4", "s2", "s3")}, ""},
+		{"unknown", []Request{req("s1", "blah")}, `service "s1" depends on unknown service "blah"`},
+		{"self", []Request{req("s1", "s1")}, "dependency cycle s1 -> s1"},
+		{"cycle", []Request{req("s0"), req("s1", "s3"), req("s2", "s1"), req("s3", "s2", "s0")},
+			"dependency cycle s1 -> s3 -> s2 -> s1"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDependencies(tt.reqs)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package external
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -19,9 +20,41 @@ type DockerProvider struct {
 	APIVersion string // docker API version, default "1.24"
 }
 
+// dockerConfig is an entry of "docker" services section
+type dockerConfig struct {
+	Name       string   `yaml:"name"`
+	URL        string   `yaml:"url"`
+	Containers []string `yaml:"containers"` // required containers
+}
+
+// Spec returns spec of the provider for docker urls and "docker" config section
+func (d *DockerProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "docker",
+		Schemes: []string{"docker"},
+		ParseURL: func(name, u string) (Request, error) {
+			u, params := cutQuery(u, "containers")
+			req := Request{Name: name, URL: u}
+			for c := range strings.SplitSeq(params["containers"], ":") {
+				if c = strings.TrimSpace(c); c != "" {
+					req.Options.Containers = append(req.Options.Containers, c)
+				}
+			}
+			return req, nil
+		},
+		ParseConfig: configParser(func(c dockerConfig) (Request, error) {
+			u := trimSchemes(c.URL, "https", "http", "tcp", "unix")
+			if u == "" {
+				return Request{}, errors.New("url is required")
+			}
+			return Request{Name: c.Name, URL: "docker://" + u, Options: Options{Containers: c.Containers}}, nil
+		}),
+	}
+}
+
 // Status the url looks like: docker:///var/run/docker.sock or docker://1.2.3.4:2375
-// optionally the url can contain a query param "required" with a comma separated list of required container names
-// i.e. docker:///var/run/docker.sock?containers=foo,bar
+// required containers are set by request options, on the command line with "containers" query param,
+// colon separated, i.e. docker:///var/run/docker.sock?containers=foo:bar
 func (d *DockerProvider) Status(req Request) (*Response, error) {
 
 	st := time.Now()
@@ -31,7 +64,7 @@ func (d *DockerProvider) Status(req Request) (*Response, error) {
 	}
 	uu, err := url.Parse(u)
 	if err != nil {
-		return nil, fmt.Errorf("docker url parse failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, withCategory(ErrorConfig, fmt.Errorf("docker url parse failed: %s: %w", req, err))
 	}
 
 	if uu.Scheme == "unix" { // for unix socket use path as host
@@ -44,7 +77,7 @@ func (d *DockerProvider) Status(req Request) (*Response, error) {
 				return net.Dial(uu.Scheme, uu.Host)
 			},
 		},
-		Timeout: d.TimeOut,
+		Timeout: req.timeout(d.TimeOut),
 	}
 
 	apiVersion := d.APIVersion
@@ -54,38 +87,40 @@ func (d *DockerProvider) Status(req Request) (*Response, error) {
 	dkURL := fmt.Sprintf("http://localhost/v%s/containers/json", apiVersion)
 	resp, err := client.Get(dkURL)
 	if err != nil {
-		return nil, fmt.Errorf("docker request failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, fmt.Errorf("docker request failed: %s: %w", req, err)
 	}
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
-			log.Printf("[WARN] docker response close failed: %s %s: %s", req.Name, req.URL, e)
+			log.Printf("[WARN] docker response close failed: %s: %s", req, e)
 		}
 	}()
 
-	var required []string
-	if uu.Query().Get("containers") != "" {
-		for r := range strings.SplitSeq(uu.Query().Get("containers"), ":") {
-			r = strings.TrimSpace(r)
-			if r == "" {
-				continue
-			}
-			required = append(required, r)
-		}
-	}
-	dkinfo, err := d.parseDockerResponse(resp.Body, required)
+	dkinfo, err := d.parseDockerResponse(resp.Body, req.Options.Containers)
 	if err != nil {
-		return nil, fmt.Errorf("docker parsing failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, withCategory(ErrorParse, fmt.Errorf("docker parsing failed: %s: %w", req, err))
 	}
 
 	result := Response{
 		Name:         req.Name,
 		StatusCode:   resp.StatusCode,
 		Body:         dkinfo,
+		Health:       d.health(resp.StatusCode, dkinfo),
 		ResponseTime: time.Since(st).Milliseconds(),
 	}
 	return &result, nil
 }
 
+// health returns DOWN if any of required containers is not running, WARN if any container is unhealthy
+func (d *DockerProvider) health(code int, dkinfo map[string]any) Health {
+	if required, ok := dkinfo["required"].(string); ok && required != "ok" {
+		return Health{Status: HealthDown, Reason: "required containers " + required}
+	}
+	if unhealthy, ok := dkinfo["unhealthy"].(int); ok && unhealthy > 0 {
+		return Health{Status: HealthWarn, Reason: fmt.Sprintf("%d unhealthy containers", unhealthy)}
+	}
+	return statusCodeHealth(code)
+}
+
 func (d *DockerProvider) parseDockerResponse(r io.Reader, required []string) (map[string]any, error) {
 	var dkResp []struct {
 		ID      string `json:"Id"`
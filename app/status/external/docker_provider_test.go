@@ -35,6 +35,7 @@ func TestDockerProvider_Status(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 200, resp.StatusCode)
 	assert.Greater(t, resp.ResponseTime, int64(1))
+	assert.Equal(t, Health{Status: HealthWarn, Reason: "1 unhealthy containers"}, resp.Health)
 }
 
 func TestDockerProvider_StatusWithRequired(t *testing.T) {
@@ -57,16 +58,17 @@ func TestDockerProvider_StatusWithRequired(t *testing.T) {
 	p := DockerProvider{TimeOut: time.Second, APIVersion: "1.22"}
 
 	{
-		resp, err := p.Status(
-			Request{Name: "d1", URL: strings.Replace(ts.URL, "http://", "tcp://", 1) + "?containers=c1:c2"})
+		resp, err := p.Status(Request{Name: "d1", URL: strings.Replace(ts.URL, "http://", "tcp://", 1),
+			Options: Options{Containers: []string{"c1", "c2"}}})
 		require.NoError(t, err)
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "failed: c1,c2", resp.Body["required"])
+		assert.Equal(t, Health{Status: HealthDown, Reason: "required containers failed: c1,c2"}, resp.Health)
 	}
 
 	{
-		resp, err := p.Status(
-			Request{Name: "d1", URL: strings.Replace(ts.URL, "http://", "tcp://", 1) + "?containers=nginx : weather"})
+		resp, err := p.Status(Request{Name: "d1", URL: strings.Replace(ts.URL, "http://", "tcp://", 1),
+			Options: Options{Containers: []string{"nginx", "weather"}}})
 		require.NoError(t, err)
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "ok", resp.Body["required"])
@@ -94,6 +96,29 @@ func TestDockerProvider_StatusDefaultAPIVersion(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 }
 
+func TestDockerProvider_health(t *testing.T) {
+	p := DockerProvider{}
+	tbl := []struct {
+		name   string
+		code   int
+		dkinfo map[string]any
+		exp    Health
+	}{
+		{"all good", 200, map[string]any{"required": "ok", "unhealthy": 0}, Health{Status: HealthUp}},
+		{"required failed", 200, map[string]any{"required": "failed: c1", "unhealthy": 2},
+			Health{Status: HealthDown, Reason: "required containers failed: c1"}},
+		{"unhealthy", 200, map[string]any{"required": "ok", "unhealthy": 2},
+			Health{Status: HealthWarn, Reason: "2 unhealthy containers"}},
+		{"bad status code", 500, map[string]any{"required": "ok", "unhealthy": 0},
+			Health{Status: HealthDown, Reason: "status code 500"}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, p.health(tt.code, tt.dkinfo))
+		})
+	}
+}
+
 func TestDockerProvider_parseDockerResponse(t *testing.T) {
 	fh, err := os.Open("testdata/containers.json")
 	require.NoError(t, err)
@@ -0,0 +1,98 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"syscall"
+)
+
+// error categories of failed checks, reported in Response.ErrorCategory
+const (
+	ErrorTimeout = "timeout" // check timed out
+	ErrorDNS     = "dns"     // host can't be resolved
+	ErrorConnect = "connect" // connection refused, reset or host unreachable
+	ErrorTLS     = "tls"     // tls handshake or certificate verification failed
+	ErrorAuth    = "auth"    // authentication or authorization failed
+	ErrorParse   = "parse"   // response or request can't be parsed
+	ErrorConfig  = "config"  // service misconfigured, i.e. unsupported protocol
+	ErrorOther   = "other"   // any other error
+)
+
+// categoryError is an error with category set by provider, for errors which can't be categorized by type
+type categoryError struct {
+	category string
+	err      error
+}
+
+func (e *categoryError) Error() string { return e.err.Error() }
+
+func (e *categoryError) Unwrap() error { return e.err }
+
+// withCategory sets category of the error, reported by ErrorCategory
+func withCategory(category string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categoryError{category: category, err: err}
+}
+
+// ErrorCategory returns category of the check error, one of Error* constants.
+// Category set by provider takes precedence, other errors are categorized by type.
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var catErr *categoryError
+	if errors.As(err, &catErr) {
+		return catErr.category
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return ErrorTLS
+	}
+
+	var opErr *net.OpError
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH) || errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorConnect
+	}
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		numErr    *strconv.NumError
+		urlErr    *url.Error
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &numErr) ||
+		errors.As(err, &urlErr) && urlErr.Op == "parse" {
+		return ErrorParse
+	}
+
+	return ErrorOther
+}
@@ -0,0 +1,43 @@
+package external
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCategory(t *testing.T) {
+	jsonErr := json.Unmarshal([]byte("{bad"), &map[string]any{})
+
+	tbl := []struct {
+		name string
+		err  error
+		res  string
+	}{
+		{"nil", nil, ""},
+		{"set by provider", withCategory(ErrorAuth, errors.New("denied")), ErrorAuth},
+		{"wrapped provider category", fmt.Errorf("check: %w", withCategory(ErrorParse, errors.New("bad"))), ErrorParse},
+		{"deadline", fmt.Errorf("check: %w", context.DeadlineExceeded), ErrorTimeout},
+		{"dns", &net.DNSError{Err: "no such host", Name: "blah.example.com", IsNotFound: true}, ErrorDNS},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, ErrorConnect},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, ErrorConnect},
+		{"unknown authority", fmt.Errorf("handshake: %w", x509.UnknownAuthorityError{}), ErrorTLS},
+		{"json", fmt.Errorf("decode: %w", jsonErr), ErrorParse},
+		{"other", errors.New("blah"), ErrorOther},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.res, ErrorCategory(tt.err))
+		})
+	}
+
+	assert.NoError(t, withCategory(ErrorAuth, nil))
+	assert.EqualError(t, withCategory(ErrorAuth, errors.New("denied")), "denied")
+}
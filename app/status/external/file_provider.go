@@ -20,6 +20,32 @@ type FileProvider struct {
 	}
 }
 
+// fileConfig is an entry of "file" services section
+type fileConfig struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// Spec returns spec of the provider for file urls and "file" config section
+func (f *FileProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "file",
+		Schemes: []string{"file"},
+		ParseURL: func(name, u string) (Request, error) {
+			if trimSchemes(u, "file") == "" {
+				return Request{}, errors.New("path is required")
+			}
+			return Request{Name: name, URL: u}, nil
+		},
+		ParseConfig: configParser(func(c fileConfig) (Request, error) {
+			if c.Path == "" {
+				return Request{}, errors.New("path is required")
+			}
+			return Request{Name: c.Name, URL: "file://" + c.Path}, nil
+		}),
+	}
+}
+
 // Status returns the status of the file
 // url looks like this: file://blah/foo.txt (relative path) or file:///blah/foo.txt (absolute path)
 func (f *FileProvider) Status(req Request) (*Response, error) {
@@ -46,6 +72,7 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 			Name:         req.Name,
 			StatusCode:   200,
 			Body:         map[string]any{"status": "not found"},
+			Health:       Health{Status: HealthDown, Reason: "file not found"},
 			ResponseTime: time.Since(st).Milliseconds(),
 		}
 		return &result, nil
@@ -83,6 +110,7 @@ func (f *FileProvider) Status(req Request) (*Response, error) {
 		Name:         req.Name,
 		StatusCode:   200,
 		Body:         body,
+		Health:       Health{Status: HealthUp},
 		ResponseTime: time.Since(st).Milliseconds(),
 	}
 	return &result, nil
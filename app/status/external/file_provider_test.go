@@ -69,5 +69,6 @@ func TestFileProvider_Status(t *testing.T) {
 		require.NoError(t, err)
 		t.Logf("%+v", resp)
 		assert.Equal(t, "not found", resp.Body["status"])
+		assert.Equal(t, Health{Status: HealthDown, Reason: "file not found"}, resp.Health)
 	}
 }
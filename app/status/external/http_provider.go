@@ -2,6 +2,7 @@ package external
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,23 +15,55 @@ type HTTPProvider struct {
 	http.Client
 }
 
-// Status returns the status of the external service via HTTP GET
+// httpConfig is an entry of "http" services section
+type httpConfig struct {
+	Name     string            `yaml:"name"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	Actuator bool              `yaml:"actuator"`
+}
+
+// Spec returns spec of the provider for http and https urls and "http" config section
+func (h *HTTPProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "http",
+		Schemes: []string{"http", "https"},
+		ParseConfig: configParser(func(c httpConfig) (Request, error) {
+			if c.URL == "" {
+				return Request{}, errors.New("url is required")
+			}
+			return Request{Name: c.Name, URL: c.URL, Options: Options{Headers: c.Headers, Actuator: c.Actuator}}, nil
+		}),
+	}
+}
+
+// Status returns the status of the external service via HTTP GET, with headers from request options.
+// With actuator option the status field of json response is used as the health verdict.
 func (h *HTTPProvider) Status(req Request) (*Response, error) {
 
 	st := time.Now()
-	resp, err := h.Get(req.URL)
+	httpReq, err := http.NewRequest(http.MethodGet, req.URL, http.NoBody)
+	if err != nil {
+		return nil, withCategory(ErrorConfig, fmt.Errorf("http request failed: %s: %w", req, err))
+	}
+	for k, v := range req.Options.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	client := h.Client
+	client.Timeout = req.timeout(h.Timeout)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, fmt.Errorf("http request failed: %s: %w", req, err)
 	}
 	defer func() {
 		if e := resp.Body.Close(); e != nil {
-			log.Printf("[WARN] http response close failed: %s %s: %s", req.Name, req.URL, e)
+			log.Printf("[WARN] http response close failed: %s: %s", req, e)
 		}
 	}()
 
 	bodyStr, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("http read failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, fmt.Errorf("http read failed: %s: %w", req, err)
 	}
 
 	var bodyJSON map[string]any
@@ -41,7 +74,36 @@ func (h *HTTPProvider) Status(req Request) (*Response, error) {
 		Name:         req.Name,
 		StatusCode:   resp.StatusCode,
 		Body:         bodyJSON,
+		Health:       statusCodeHealth(resp.StatusCode),
 		ResponseTime: time.Since(st).Milliseconds(),
 	}
+	if req.Options.Actuator {
+		if h, ok := actuatorHealth(resp.StatusCode, bodyJSON); ok {
+			result.Health = h
+		}
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		result.Error, result.ErrorCategory = resp.Status, ErrorAuth
+	}
 	return &result, nil
 }
+
+// actuatorHealth returns health reported by spring actuator compatible health endpoint, i.e. another sys-agent.
+// The body status is used for 2xx and 503 responses, if it is one of known statuses.
+func actuatorHealth(code int, body map[string]any) (Health, bool) {
+	if (code < 200 || code >= 300) && code != http.StatusServiceUnavailable {
+		return Health{}, false
+	}
+	st, ok := body["status"].(string)
+	if !ok {
+		return Health{}, false
+	}
+	switch st {
+	case HealthUp:
+		return Health{Status: st}, true
+	case HealthDown, HealthWarn, HealthUnknown, HealthOutOfService:
+		return Health{Status: st, Reason: "reported " + st}, true
+	default:
+		return Health{}, false
+	}
+}
@@ -27,6 +27,99 @@ func TestHttpProvider_Status(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode)
 	assert.Positive(t, resp.ResponseTime)
 	assert.Equal(t, map[string]any{"foo": "bar", "status": "ok"}, resp.Body)
+	assert.Equal(t, Health{Status: HealthUp}, resp.Health)
+}
+
+func TestHttpProvider_StatusNot2xx(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+	resp, err := p.Status(Request{Name: "r1", URL: ts.URL})
+	require.NoError(t, err)
+	assert.Equal(t, 502, resp.StatusCode)
+	assert.Equal(t, Health{Status: HealthDown, Reason: "status code 502"}, resp.Health)
+	assert.Empty(t, resp.Error)
+}
+
+func TestHttpProvider_StatusActuator(t *testing.T) {
+	tbl := []struct {
+		code     int
+		body     string
+		exp      Health
+		actuator bool
+	}{
+		{200, `{"status": "UP"}`, Health{Status: HealthUp}, true},
+		{200, `{"status": "WARN"}`, Health{Status: HealthWarn, Reason: "reported WARN"}, true},
+		{200, `{"status": "UNKNOWN"}`, Health{Status: HealthUnknown, Reason: "reported UNKNOWN"}, true},
+		{503, `{"status": "OUT_OF_SERVICE"}`, Health{Status: HealthOutOfService, Reason: "reported OUT_OF_SERVICE"}, true},
+		{503, `{"status": "DOWN"}`, Health{Status: HealthDown, Reason: "reported DOWN"}, true},
+		{500, `{"status": "UP"}`, Health{Status: HealthDown, Reason: "status code 500"}, true},
+		{200, `{"status": "ok"}`, Health{Status: HealthUp}, true},
+		{200, `{"status": 1}`, Health{Status: HealthUp}, true},
+		{200, `{"status": "DOWN"}`, Health{Status: HealthUp}, false},
+		{503, `{"status": "UP"}`, Health{Status: HealthDown, Reason: "status code 503"}, false},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.body, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				_, e := w.Write([]byte(tt.body))
+				assert.NoError(t, e)
+			}))
+			defer ts.Close()
+			p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+			resp, err := p.Status(Request{Name: "r1", URL: ts.URL, Options: Options{Actuator: tt.actuator}})
+			require.NoError(t, err)
+			assert.Equal(t, tt.exp, resp.Health)
+		})
+	}
+}
+
+func TestHttpProvider_StatusErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	tls := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tls.Close()
+	p := HTTPProvider{Client: http.Client{Timeout: time.Second}}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		resp, err := p.Status(Request{Name: "r1", URL: ts.URL})
+		require.NoError(t, err)
+		assert.Equal(t, "401 Unauthorized", resp.Error)
+		assert.Equal(t, ErrorAuth, resp.ErrorCategory)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := p.Status(Request{Name: "r1", URL: ts.URL + "/slow", Options: Options{Timeout: 10 * time.Millisecond}})
+		require.Error(t, err)
+		assert.Equal(t, ErrorTimeout, ErrorCategory(err))
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		_, err := p.Status(Request{Name: "r1", URL: tls.URL})
+		require.Error(t, err)
+		assert.Equal(t, ErrorTLS, ErrorCategory(err))
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		addr := tls.Listener.Addr().String()
+		tls.Close()
+		_, err := p.Status(Request{Name: "r1", URL: "http://" + addr})
+		require.Error(t, err)
+		assert.Equal(t, ErrorConnect, ErrorCategory(err))
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		_, err := p.Status(Request{Name: "r1", URL: "http://127.0.0.1:port"})
+		require.Error(t, err)
+		assert.Equal(t, ErrorConfig, ErrorCategory(err))
+	})
 }
 
 func TestHttpProvider_StatusHttpNoJson(t *testing.T) {
@@ -2,10 +2,11 @@ package external
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/url"
 	"strings"
 	"text/template"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mdrv "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
 
 	mopt "go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -25,41 +27,76 @@ type MongoProvider struct {
 	now func() time.Time // for testing
 }
 
+// mongoConfig is an entry of "mongo" services section
+type mongoConfig struct {
+	Name          string        `yaml:"name"`
+	URL           string        `yaml:"url"`
+	OplogMaxDelta time.Duration `yaml:"oplog_max_delta"`
+	Collection    string        `yaml:"collection"`
+	DB            string        `yaml:"db"`
+	CountQuery    string        `yaml:"count_query"`
+}
+
+// Spec returns spec of the provider for mongodb urls and "mongo" config section
+func (m *MongoProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "mongo",
+		Schemes: []string{"mongodb"},
+		ParseURL: func(name, u string) (Request, error) {
+			// countQuery is the same as count, set by urls made from config files by older versions
+			u, params := cutQuery(u, "oplogMaxDelta", "db", "collection", "count", "countQuery")
+			req := Request{Name: name, URL: u, Options: Options{DB: params["db"], Collection: params["collection"],
+				CountQuery: cmp.Or(params["count"], params["countQuery"])}}
+			if params["oplogMaxDelta"] != "" {
+				d, err := time.ParseDuration(params["oplogMaxDelta"])
+				if err != nil {
+					return Request{}, fmt.Errorf("can't parse oplogMaxDelta: %w", err)
+				}
+				req.Options.OplogMaxDelta = d
+			}
+			return req, nil
+		},
+		ParseConfig: configParser(func(c mongoConfig) (Request, error) {
+			if c.URL == "" {
+				return Request{}, errors.New("url is required")
+			}
+			return Request{Name: c.Name, URL: c.URL, Options: Options{OplogMaxDelta: c.OplogMaxDelta, DB: c.DB,
+				Collection: c.Collection, CountQuery: c.CountQuery}}, nil
+		}),
+	}
+}
+
 // Status returns status of mongo, checks if connection established and ping is ok
-// request URL looks like mongo:mongodb://172.17.42.1:27017/test?oplogMaxDelta=30m
-// oplogMaxDelta is optional, if set, checks if oplog is not too far behind
+// request URL looks like mongodb://172.17.42.1:27017/test, oplogMaxDelta is set by request options
+// if oplogMaxDelta is set, checks if oplog is not too far behind
 func (m *MongoProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), m.TimeOut)
+	timeout := req.timeout(m.TimeOut)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if m.now == nil {
 		m.now = time.Now
 	}
 
-	client, _, err := mongo.Connect(ctx, mopt.Client().SetAppName("sys-agent").SetConnectTimeout(m.TimeOut), req.URL)
+	client, _, err := mongo.Connect(ctx, mopt.Client().SetAppName("sys-agent").SetConnectTimeout(timeout), req.URL)
 	if err != nil {
-		return nil, fmt.Errorf("mongo connect failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, mongoError(fmt.Errorf("mongo connect failed: %s: %w", req, err))
 	}
 	defer func() {
 		if e := client.Disconnect(ctx); e != nil {
-			log.Printf("[WARN] mongo disconnect failed: %s %s: %v", req.Name, req.URL, e)
+			log.Printf("[WARN] mongo disconnect failed: %s: %v", req, e)
 		}
 	}()
 
-	uu, err := url.Parse(req.URL)
-	if err != nil {
-		return nil, fmt.Errorf("mongo url parse failed: %s %s: %w", req.Name, req.URL, err)
-	}
-
-	rs, err := m.replStatus(ctx, client, uu)
+	rs, err := m.replStatus(ctx, client, req.Options.OplogMaxDelta)
 	if err != nil {
-		return nil, fmt.Errorf("mongo repl status failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, mongoError(fmt.Errorf("mongo repl status failed: %s: %w", req, err))
 	}
 
-	count, err := m.countQuery(ctx, client, uu)
+	count, err := m.countQuery(ctx, client, req.Options)
 	if err != nil {
-		return nil, fmt.Errorf("mongo count query failed: %s %s: %w", req.Name, req.URL, err)
+		return nil, mongoError(fmt.Errorf("mongo count query failed: %s: %w", req, err))
 	}
 
 	result := Response{
@@ -71,15 +108,41 @@ func (m *MongoProvider) Status(req Request) (*Response, error) {
 	if rs != nil {
 		result.Body["rs"] = rs
 	}
+	result.Health = m.health(rs)
 	if count >= 0 {
 		result.Body["count"] = count
 	}
 	return &result, nil
 }
 
+// mongoError sets auth category for authentication and authorization errors of mongo
+func mongoError(err error) error {
+	var authErr *auth.Error
+	var cmdErr mdrv.CommandError
+	if errors.As(err, &authErr) || errors.As(err, &cmdErr) && (cmdErr.Code == 13 || cmdErr.Code == 18) { // Unauthorized, AuthenticationFailed
+		return withCategory(ErrorAuth, err)
+	}
+	return err
+}
+
+// health returns DOWN if replica set is in invalid state or oplog of any secondary is too far behind.
+// standalone mongo (nil replica set) is UP as connection is established already
+func (m *MongoProvider) health(rs *replSet) Health {
+	if rs == nil {
+		return Health{Status: HealthUp}
+	}
+	if rs.Status != "ok" {
+		return Health{Status: HealthDown, Reason: "replica set " + rs.Status}
+	}
+	if rs.OptimeStatus != "ok" {
+		return Health{Status: HealthDown, Reason: "replica set optime " + rs.OptimeStatus}
+	}
+	return Health{Status: HealthUp}
+}
+
 // replStatus gets replica set status if mongo configured as replica set
 // for standalone mongo returns nil map
-func (m *MongoProvider) replStatus(ctx context.Context, client *mdrv.Client, req *url.URL) (*replSet, error) {
+func (m *MongoProvider) replStatus(ctx context.Context, client *mdrv.Client, oplogMaxDelta time.Duration) (*replSet, error) {
 	rs := client.Database("admin").RunCommand(ctx, bson.M{"replSetGetStatus": 1})
 	if rs.Err() != nil {
 		if !strings.Contains(rs.Err().Error(), "NoReplicationEnabled") {
@@ -90,12 +153,12 @@ func (m *MongoProvider) replStatus(ctx context.Context, client *mdrv.Client, req
 
 	rr := bson.M{}
 	if err := rs.Decode(&rr); err != nil {
-		return nil, fmt.Errorf("mongo replset info can't be decoded: %w", err)
+		return nil, withCategory(ErrorParse, fmt.Errorf("mongo replset info can't be decoded: %w", err))
 	}
 
-	rsInfo, err := m.parseReplStatus(req, rr)
+	rsInfo, err := m.parseReplStatus(oplogMaxDelta, rr)
 	if err != nil {
-		return nil, fmt.Errorf("mongo replset info can't be parsed: %w", err)
+		return nil, withCategory(ErrorParse, fmt.Errorf("mongo replset info can't be parsed: %w", err))
 	}
 	return rsInfo, nil
 }
@@ -114,8 +177,9 @@ type replSetMember struct {
 }
 
 // parseReplStatus parses replSet status bson.M and returns replSet struct
-// it supports multiple flavors of replSet status returned by various mongo versions
-func (m *MongoProvider) parseReplStatus(req *url.URL, data bson.M) (res *replSet, err error) {
+// it supports multiple flavors of replSet status returned by various mongo versions.
+// oplogMaxDelta is the max allowed optime difference of secondary, 1 minute if not set.
+func (m *MongoProvider) parseReplStatus(oplogMaxDelta time.Duration, data bson.M) (res *replSet, err error) {
 	defer func() {
 		// the code below doing type assertions.
 		// even if each case is covered/checked we better have recover, just in case
@@ -124,13 +188,8 @@ func (m *MongoProvider) parseReplStatus(req *url.URL, data bson.M) (res *replSet
 		}
 	}()
 
-	oplogMaxDelta := time.Minute
-	if req.Query().Get("oplogMaxDelta") != "" {
-		d, err := time.ParseDuration(req.Query().Get("oplogMaxDelta"))
-		if err != nil {
-			return nil, fmt.Errorf("can't parse oplogMaxDelta: %s: %w", req.Host, err)
-		}
-		oplogMaxDelta = d
+	if oplogMaxDelta <= 0 {
+		oplogMaxDelta = time.Minute
 	}
 	members, ok := data["members"].(primitive.A)
 	if !ok {
@@ -187,23 +246,22 @@ func (m *MongoProvider) parseReplStatus(req *url.URL, data bson.M) (res *replSet
 }
 
 // countQuery returns number of documents in the collection matching the filter
-// request URL looks like this:	mongo:mongodb://blah:27017/test?db=test&collection=users&count={"status":"active"}
-func (m *MongoProvider) countQuery(ctx context.Context, client *mdrv.Client, req *url.URL) (int64, error) {
-	countQuery := req.Query().Get("count")
+// db, collection and count query are set by request options, i.e. db=test, collection=users, count={"status":"active"}
+func (m *MongoProvider) countQuery(ctx context.Context, client *mdrv.Client, opts Options) (int64, error) {
+	countQuery := opts.CountQuery
 	if countQuery == "" {
 		return -1, nil // no count filter requested
 	}
 	dt := NewDayTemplate(m.now())
 	countQuery = dt.Parse(countQuery) // replace date templates with actual dates, i.e. {"date": "[[.YYYYMMDD]]:00:00:00Z"}
 
-	collection := req.Query().Get("collection")
-	db := req.Query().Get("db")
+	collection, db := opts.Collection, opts.DB
 	if collection == "" || db == "" {
-		return 0, fmt.Errorf("collection and db should be provided for count query")
+		return 0, withCategory(ErrorConfig, errors.New("collection and db should be provided for count query"))
 	}
 	filter := bson.M{}
 	if err := bson.UnmarshalExtJSON([]byte(countQuery), false, &filter); err != nil {
-		return 0, fmt.Errorf("mongo filter can't be parsed: %w", err)
+		return 0, withCategory(ErrorParse, fmt.Errorf("mongo filter can't be parsed: %w", err))
 	}
 	coll := client.Database(db).Collection(collection)
 	count, err := coll.CountDocuments(ctx, filter)
@@ -3,7 +3,6 @@ package external
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"testing"
 	"time"
 
@@ -36,13 +35,20 @@ func TestMongoProvider_Status(t *testing.T) {
 	})
 }
 
+func TestMongoProvider_health(t *testing.T) {
+	p := MongoProvider{}
+	assert.Equal(t, Health{Status: HealthUp}, p.health(nil))
+	assert.Equal(t, Health{Status: HealthUp}, p.health(&replSet{Status: "ok", OptimeStatus: "ok"}))
+	assert.Equal(t, Health{Status: HealthDown, Reason: "replica set failed, invalid state RECOVERING for node2"},
+		p.health(&replSet{Status: "failed, invalid state RECOVERING for node2", OptimeStatus: "ok"}))
+	assert.Equal(t, Health{Status: HealthDown, Reason: "replica set optime failed, optime difference for node2 is 2m0s"},
+		p.health(&replSet{Status: "ok", OptimeStatus: "failed, optime difference for node2 is 2m0s"}))
+}
+
 func TestMongoProvider_parseReplStatus(t *testing.T) {
 	p := MongoProvider{TimeOut: time.Second}
 
 	t.Run("allowed optime difference", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"set": "rs0", "members": primitive.A{
 			bson.M{"name": "node1", "stateStr": "PRIMARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908344, I: 123}}},
 			bson.M{"name": "node2", "stateStr": "SECONDARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908384, I: 456}}},
@@ -50,7 +56,7 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 			bson.M{"name": "node4", "stateStr": "ARBITER"},
 		}}
 
-		res, err := p.parseReplStatus(uu, data)
+		res, err := p.parseReplStatus(55*time.Second, data)
 		require.NoError(t, err)
 		assert.Equal(t, "ok", res.Status)
 		assert.Equal(t, "ok", res.OptimeStatus)
@@ -63,9 +69,6 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 	})
 
 	t.Run("large optime difference", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"set": "rs0", "members": primitive.A{
 			bson.M{"name": "node1", "stateStr": "PRIMARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908344, I: 123}}},
 			bson.M{"name": "node2", "stateStr": "SECONDARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514905384, I: 456}}},
@@ -73,7 +76,7 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 			bson.M{"name": "node4", "stateStr": "ARBITER"},
 		}}
 
-		res, err := p.parseReplStatus(uu, data)
+		res, err := p.parseReplStatus(55*time.Second, data)
 		require.NoError(t, err)
 		assert.Equal(t, "ok", res.Status)
 		assert.Equal(t, "failed, optime difference for node2 is 49m19.999999667s", res.OptimeStatus)
@@ -81,38 +84,17 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 		t.Logf("%+v", res)
 	})
 
-	t.Run("invalid oplogMaxDelta", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55xx")
-		require.NoError(t, err)
-
-		data := bson.M{"set": "rs0", "members": primitive.A{
-			bson.M{"name": "node1", "stateStr": "PRIMARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908344, I: 123}}},
-			bson.M{"name": "node2", "stateStr": "SECONDARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908384, I: 456}}},
-			bson.M{"name": "node3", "stateStr": "SECONDARY", "optime": time.Date(2018, time.January, 2, 15, 51, 33, 0, time.UTC)},
-			bson.M{"name": "node4", "stateStr": "ARBITER"},
-		}}
-
-		_, err = p.parseReplStatus(uu, data)
-		require.EqualError(t, err, `can't parse oplogMaxDelta: localhost:27017: time: unknown unit "xx" in duration "55xx"`)
-	})
-
 	t.Run("no members", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"set": "rs0"}
-		_, err = p.parseReplStatus(uu, data)
+		_, err := p.parseReplStatus(55*time.Second, data)
 		require.EqualError(t, err, "mongo replset members can't be extracted: <nil>")
 
 		data = bson.M{"set": "rs0", "members": primitive.A{}}
-		_, err = p.parseReplStatus(uu, data)
+		_, err = p.parseReplStatus(55*time.Second, data)
 		require.EqualError(t, err, "mongo replset is empty")
 	})
 
 	t.Run("no replica set", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"members": primitive.A{
 			bson.M{"name": "node1", "stateStr": "PRIMARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908344, I: 123}}},
 			bson.M{"name": "node2", "stateStr": "SECONDARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908384, I: 456}}},
@@ -120,24 +102,18 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 			bson.M{"name": "node4", "stateStr": "ARBITER"},
 		}}
 
-		_, err = p.parseReplStatus(uu, data)
+		_, err := p.parseReplStatus(55*time.Second, data)
 		assert.EqualError(t, err, `mongo replset set can't be extracted: <nil>`)
 	})
 
 	t.Run("members wrong type", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"set": "rs0", "members": 123}
 
-		_, err = p.parseReplStatus(uu, data)
+		_, err := p.parseReplStatus(55*time.Second, data)
 		require.EqualError(t, err, `mongo replset members can't be extracted: 123`)
 	})
 
 	t.Run("member state extraction failed", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{"set": "rs0", "members": primitive.A{
 			bson.M{"name": "node1", "stateStr": 1234, "optime": bson.M{"ts": primitive.Timestamp{T: 1514908344, I: 123}}},
 			bson.M{"name": "node2", "stateStr": "SECONDARY", "optime": bson.M{"ts": primitive.Timestamp{T: 1514908384, I: 456}}},
@@ -145,16 +121,13 @@ func TestMongoProvider_parseReplStatus(t *testing.T) {
 			bson.M{"name": "node4", "stateStr": "ARBITER"},
 		}}
 
-		_, err = p.parseReplStatus(uu, data)
+		_, err := p.parseReplStatus(55*time.Second, data)
 		require.EqualError(t, err, `mongo replset member state can't be extracted: map[name:node1 optime:map[ts:{T:1514908344 I:123}] stateStr:1234]`)
 	})
 
 	t.Run("empty rs data", func(t *testing.T) {
-		uu, err := url.Parse("mongodb://localhost:27017?oplogMaxDelta=55s")
-		require.NoError(t, err)
-
 		data := bson.M{}
-		_, err = p.parseReplStatus(uu, data)
+		_, err := p.parseReplStatus(55*time.Second, data)
 		require.EqualError(t, err, `mongo replset members can't be extracted: <nil>`)
 	})
 }
@@ -173,7 +146,7 @@ func TestMongoProvider_count(t *testing.T) {
 	t.Run("valid count query", func(t *testing.T) {
 		mongoURL := fmt.Sprintf("mongodb://localhost:27017/admin?db=test&collection=%s&count={\"status\":\"active\"}",
 			coll.Name())
-		resp, err := p.Status(Request{Name: "test", URL: mongoURL})
+		resp, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.NoError(t, err)
 		t.Logf("%+v", resp)
 	})
@@ -181,19 +154,19 @@ func TestMongoProvider_count(t *testing.T) {
 	t.Run("invalid count query", func(t *testing.T) {
 		mongoURL := fmt.Sprintf("mongodb://localhost:27017/admin?db=test&collection=%s&count={\"status:\"active\"}",
 			coll.Name())
-		_, err := p.Status(Request{Name: "test", URL: mongoURL})
+		_, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.Error(t, err)
 	})
 
 	t.Run("collection missed", func(t *testing.T) {
 		mongoURL := "mongodb://localhost:27017/admin?db=test&count={\"status\":\"active\"}"
-		_, err := p.Status(Request{Name: "test", URL: mongoURL})
+		_, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.ErrorContains(t, err, "collection and db should be provided for count query")
 	})
 
 	t.Run("db missed", func(t *testing.T) {
 		mongoURL := "mongodb://localhost:27017/admin?collection=test&count={\"status\":\"active\"}"
-		_, err := p.Status(Request{Name: "test", URL: mongoURL})
+		_, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.ErrorContains(t, err, "collection and db should be provided for count query")
 	})
 }
@@ -223,7 +196,7 @@ func TestMongoProvider_countWithDate(t *testing.T) {
 
 		mongoURL := fmt.Sprintf("mongodb://localhost:27017/admin?db=test&collection=%s&count=%s", coll.Name(), query)
 		t.Logf("url: %s", mongoURL)
-		resp, err := p.Status(Request{Name: "test", URL: mongoURL})
+		resp, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.NoError(t, err)
 		t.Logf("%+v", resp)
 		assert.Equal(t, int64(3), resp.Body["count"])
@@ -236,7 +209,7 @@ func TestMongoProvider_countWithDate(t *testing.T) {
 
 		mongoURL := fmt.Sprintf("mongodb://localhost:27017/admin?db=test&collection=%s&count=%s", coll.Name(), query)
 		t.Logf("url: %s", mongoURL)
-		resp, err := p.Status(Request{Name: "test", URL: mongoURL})
+		resp, err := p.Status(urlRequest(t, &p, "test", mongoURL))
 		require.NoError(t, err)
 		t.Logf("%+v", resp)
 		assert.Equal(t, int64(2), resp.Body["count"])
@@ -16,6 +16,27 @@ type NginxProvider struct {
 	lastHandled int
 }
 
+// nginxConfig is an entry of "nginx" services section
+type nginxConfig struct {
+	Name      string `yaml:"name"`
+	StatusURL string `yaml:"status_url"`
+}
+
+// Spec returns spec of the provider for nginx urls and "nginx" config section
+func (n *NginxProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "nginx",
+		Schemes: []string{"nginx"},
+		ParseConfig: configParser(func(c nginxConfig) (Request, error) {
+			u := trimSchemes(c.StatusURL, "http", "https")
+			if u == "" {
+				return Request{}, errors.New("status_url is required")
+			}
+			return Request{Name: c.Name, URL: "nginx://" + u}, nil
+		}),
+	}
+}
+
 // Status url looks like: nginx://example.com/nginx_status. It will try https first and if it fails http
 // nginx response looks like this:
 //
@@ -27,7 +48,7 @@ func (n *NginxProvider) Status(req Request) (*Response, error) {
 
 	st := time.Now()
 	result := &Response{Name: req.Name}
-	client := http.Client{Timeout: n.TimeOut}
+	client := http.Client{Timeout: req.timeout(n.TimeOut)}
 
 	u := strings.Replace(req.URL, "nginx://", "https://", 1)
 
@@ -36,11 +57,12 @@ func (n *NginxProvider) Status(req Request) (*Response, error) {
 		u = strings.Replace(req.URL, "nginx://", "http://", 1)
 		resp, err = client.Get(u)
 		if err != nil {
-			return nil, fmt.Errorf("both https and http failed for %s: %w", req.URL, err)
+			return nil, fmt.Errorf("both https and http failed for %s: %w", req, err)
 		}
 	}
 	defer resp.Body.Close() // nolint
 	result.StatusCode = resp.StatusCode
+	result.Health = statusCodeHealth(resp.StatusCode)
 	result.ResponseTime = time.Since(st).Milliseconds()
 
 	if resp.StatusCode != 200 {
@@ -49,7 +71,7 @@ func (n *NginxProvider) Status(req Request) (*Response, error) {
 
 	ngStats, err := n.parseResponse(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse nginx response for %s: %w", req.URL, err)
+		return nil, withCategory(ErrorParse, fmt.Errorf("failed to parse nginx response for %s: %w", req, err))
 	}
 	result.Body = ngStats
 	return result, nil
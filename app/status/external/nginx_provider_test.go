@@ -31,6 +31,7 @@ func TestNginxProvider_Status(t *testing.T) {
 	exp := map[string]any{"accepts": 1377590, "active_connections": 125, "change_handled": 1377590, "handled": 1377590,
 		"reading": 2, "requests": 1873302, "waiting": 10, "writing": 115}
 	assert.Equal(t, exp, res.Body)
+	assert.Equal(t, Health{Status: HealthUp}, res.Health)
 }
 
 func TestNginxProvider_StatusFailedTooShort(t *testing.T) {
@@ -49,4 +50,5 @@ func TestNginxProvider_StatusFailedTooShort(t *testing.T) {
 	_, err := provider.Status(Request{Name: "nginx-test", URL: ts.URL + "/nginx_status"})
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "response is too short")
+	assert.Equal(t, ErrorParse, ErrorCategory(err))
 }
@@ -3,6 +3,7 @@ package external
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -17,28 +18,54 @@ type ProgramProvider struct {
 	TimeOut   time.Duration
 }
 
+// programConfig is an entry of "program" services section
+type programConfig struct {
+	Name string   `yaml:"name"`
+	Path string   `yaml:"path"`
+	Args []string `yaml:"args"`
+}
+
+// Spec returns spec of the provider for program urls and "program" config section
+func (p *ProgramProvider) Spec() ProviderSpec {
+	return ProviderSpec{
+		Section: "program",
+		Schemes: []string{"program"},
+		ParseURL: func(name, u string) (Request, error) {
+			u, params := cutQuery(u, "args")
+			if trimSchemes(u, "program") == "" {
+				return Request{}, errors.New("program path is required")
+			}
+			// args on the command line are space separated, use config for args with spaces
+			return Request{Name: name, URL: u, Options: Options{Args: strings.Fields(strings.Trim(params["args"], `"`))}}, nil
+		},
+		ParseConfig: configParser(func(c programConfig) (Request, error) {
+			if c.Path == "" {
+				return Request{}, errors.New("path is required")
+			}
+			return Request{Name: c.Name, URL: "program://" + c.Path, Options: Options{Args: c.Args}}, nil
+		}),
+	}
+}
+
 // Status returns the status of the execution of the command from the request.
-// url looks like this: program://cat?args=/tmp/foo
+// url looks like this: program://cat, arguments are set by request options
 func (p *ProgramProvider) Status(req Request) (*Response, error) {
 	st := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), p.TimeOut)
+	ctx, cancel := context.WithTimeout(context.Background(), req.timeout(p.TimeOut))
 	defer cancel()
 
 	resp := Response{
 		Name:       req.Name,
 		StatusCode: 200,
+		Health:     Health{Status: HealthUp},
 	}
 
 	command := strings.TrimPrefix(req.URL, "program://")
-	args := ""
-	if strings.Contains(command, "?args=") {
-		elems := strings.Split(command, "?args=")
-		command, args = elems[0], elems[1]
-	}
+	args := strings.Join(req.Options.Args, " ")
 
-	log.Printf("[DEBUG] command: %s %s", command, args)
+	log.Printf("[DEBUG] command: %s %q", command, req.Options.Args)
 
-	cmd := exec.CommandContext(ctx, command, args) //nolint:gosec // we trust the command as it comes from the config
+	cmd := exec.CommandContext(ctx, command, req.Options.Args...) //nolint:gosec // we trust the command as it comes from the config
 	if p.WithShell {
 		command = fmt.Sprintf("sh -c %q", command+" "+args)
 	}
@@ -60,6 +87,14 @@ func (p *ProgramProvider) Status(req Request) (*Response, error) {
 	if err != nil {
 		res["status"] = err.Error()
 		resp.StatusCode = 500
+		resp.Health = Health{Status: HealthDown, Reason: "program failed, " + err.Error()}
+		resp.Error, resp.ErrorCategory = err.Error(), ErrorOther
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			resp.ErrorCategory = ErrorTimeout
+		case errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist):
+			resp.ErrorCategory = ErrorConfig
+		}
 	}
 
 	resp.Body = res
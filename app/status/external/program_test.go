@@ -12,17 +12,18 @@ func TestProgram_StatusWithShell(t *testing.T) {
 	p := ProgramProvider{WithShell: true, TimeOut: time.Second}
 
 	{
-		req := Request{Name: "test", URL: `program://ls?args=-la`}
+		req := urlRequest(t, &p, "test", `program://ls?args=-la`)
 		resp, err := p.Status(req)
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
 		assert.Equal(t, 200, resp.StatusCode)
 		assert.Equal(t, "ok", resp.Body["status"])
 		assert.Contains(t, resp.Body["stdout"], "program.go")
+		assert.Equal(t, Health{Status: HealthUp}, resp.Health)
 		t.Logf("%+v", resp)
 	}
 	{
-		req := Request{Name: "test", URL: `program://testdata/test.sh`}
+		req := urlRequest(t, &p, "test", `program://testdata/test.sh`)
 		resp, err := p.Status(req)
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
@@ -31,12 +32,13 @@ func TestProgram_StatusWithShell(t *testing.T) {
 		assert.Contains(t, resp.Body["stdout"], "Hello, World!")
 	}
 	{
-		req := Request{Name: "test", URL: `program://blah?args=-la`}
+		req := urlRequest(t, &p, "test", `program://blah?args=-la`)
 		resp, err := p.Status(req)
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
 		assert.Equal(t, 500, resp.StatusCode)
 		assert.Contains(t, resp.Body["status"], "file not found")
+		assert.Equal(t, ErrorConfig, resp.ErrorCategory)
 	}
 }
 
@@ -44,7 +46,7 @@ func TestProgram_StatusWithoutShell(t *testing.T) {
 	p := ProgramProvider{WithShell: true, TimeOut: time.Second}
 
 	{
-		req := Request{Name: "test", URL: `program://cat?args=program.go`}
+		req := urlRequest(t, &p, "test", `program://cat?args=program.go`)
 		resp, err := p.Status(req)
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
@@ -53,12 +55,23 @@ func TestProgram_StatusWithoutShell(t *testing.T) {
 		assert.Contains(t, resp.Body["stdout"], "CommandContext")
 	}
 	{
-		req := Request{Name: "test", URL: `program://cat?args=blah`}
+		req := urlRequest(t, &p, "test", `program://cat?args=blah`)
 		resp, err := p.Status(req)
 		require.NoError(t, err)
 		assert.Equal(t, "test", resp.Name)
 		assert.Equal(t, 500, resp.StatusCode)
 		assert.Contains(t, resp.Body["status"], "exit status 1", resp.Body["status"])
+		assert.Equal(t, HealthDown, resp.Health.Status)
+		assert.Equal(t, "program failed, exit status 1", resp.Health.Reason)
+		assert.Equal(t, "exit status 1", resp.Error)
+		assert.Equal(t, ErrorOther, resp.ErrorCategory)
 		t.Logf("%+v", resp)
 	}
+	{
+		req := urlRequest(t, &p, "test", `program://sleep?args=1&timeout=10ms`)
+		resp, err := p.Status(req)
+		require.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+		assert.Equal(t, ErrorTimeout, resp.ErrorCategory)
+	}
 }
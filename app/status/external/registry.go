@@ -0,0 +1,309 @@
+package external
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/umputun/sys-agent/app/tagset"
+)
+
+//go:generate moq -out registry_mock.go -skip-ensure -fmt goimports . Provider
+
+// Provider is a StatusProvider which can be registered in Registry
+type Provider interface {
+	StatusProvider
+	Spec() ProviderSpec
+}
+
+// ProviderSpec describes how services checked by a provider are defined
+type ProviderSpec struct {
+	Section string   // name of yaml section under "services" with services of the provider, i.e. "docker"
+	Schemes []string // url schemes handled by the provider, i.e. "docker"
+
+	// ParseURL makes a request from the service name and url, as set on the command line.
+	// Provider options passed as url query parameters should be moved to request options.
+	// Optional, by default the url is checked to be valid and kept as is.
+	ParseURL func(name, u string) (Request, error)
+
+	// ParseConfig makes a request with options from a single entry of the yaml section.
+	// Common options (timeout, cron, interval, retries and retry_interval) are set by registry.
+	ParseConfig func(node *yaml.Node) (Request, error)
+}
+
+// Registry keeps providers by url scheme and config section.
+// Adding a new check type is implementing Provider and registering it.
+type Registry struct {
+	providers []Provider          // in order of registration
+	schemes   map[string]Provider // scheme to provider
+	sections  map[string]Provider // config section to provider
+}
+
+// NewRegistry makes a registry with the given providers.
+// Returns error if a provider has no schemes or section, or reuses scheme or section of another provider.
+func NewRegistry(providers ...Provider) (*Registry, error) {
+	res := &Registry{schemes: map[string]Provider{}, sections: map[string]Provider{}}
+	for _, p := range providers {
+		if err := res.Register(p); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Register adds provider to the registry
+func (r *Registry) Register(p Provider) error {
+	spec := p.Spec()
+	if spec.Section == "" || len(spec.Schemes) == 0 || spec.ParseConfig == nil {
+		return fmt.Errorf("provider %T should define section, schemes and config parser", p)
+	}
+	if _, ok := r.sections[spec.Section]; ok {
+		return fmt.Errorf("provider %T: section %q already registered", p, spec.Section)
+	}
+	for _, scheme := range spec.Schemes {
+		if _, ok := r.schemes[scheme]; ok {
+			return fmt.Errorf("provider %T: scheme %q already registered", p, scheme)
+		}
+	}
+
+	r.sections[spec.Section] = p
+	for _, scheme := range spec.Schemes {
+		r.schemes[scheme] = p
+	}
+	r.providers = append(r.providers, p)
+	return nil
+}
+
+// Lookup returns provider for the scheme of the url
+func (r *Registry) Lookup(u string) (Provider, bool) {
+	scheme, _, ok := strings.Cut(u, "://")
+	if !ok {
+		return nil, false
+	}
+	p, ok := r.schemes[scheme]
+	return p, ok
+}
+
+// limits of retries of failed checks, so a failing service can't keep retrying for long
+const (
+	maxRetries       = 10
+	maxRetryInterval = time.Minute
+)
+
+// commonOptions are options supported by all services, in config entries and url query parameters
+type commonOptions struct {
+	Timeout       time.Duration `yaml:"timeout"`
+	Cron          string        `yaml:"cron"`
+	Interval      time.Duration `yaml:"interval"`
+	Retries       int           `yaml:"retries"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+
+	Tags   []string          `yaml:"tags"`
+	Labels map[string]string `yaml:"labels"`
+
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// ParseServices makes requests from "name:url" pairs, i.e. health:http://localhost:8080/health
+// Common options are taken from timeout, cron, interval, retries, retry_interval, tags, labels and depends_on
+// query parameters and removed from the url,
+// cron expression uses "_" instead of spaces, i.e. cron=*/5_*_*_*_*,
+// tags, labels and dependencies are comma separated, i.e. tags=payments,critical&labels=team:billing,env:prod
+// Returns error for invalid pairs and urls with unknown schemes.
+func (r *Registry) ParseServices(svcs []string) ([]Request, error) {
+	res := make([]Request, 0, len(svcs))
+	for _, svc := range svcs {
+		name, u, ok := strings.Cut(svc, ":")
+		if !ok || name == "" || u == "" {
+			return nil, fmt.Errorf("invalid service %q, should be <name>:<url>", svc)
+		}
+		p, ok := r.Lookup(u)
+		if !ok {
+			return nil, fmt.Errorf("service %q: unsupported url %q", name, u)
+		}
+
+		u, params := cutQuery(u, "timeout", "cron", "interval", "retries", "retry_interval", "tags", "labels", "depends_on")
+		common := commonOptions{Cron: strings.ReplaceAll(strings.TrimSpace(params["cron"]), "_", " ")}
+		if params["tags"] != "" {
+			common.Tags = strings.Split(params["tags"], ",")
+		}
+		if params["depends_on"] != "" {
+			common.DependsOn = strings.Split(params["depends_on"], ",")
+		}
+		for label := range strings.SplitSeq(params["labels"], ",") {
+			if label == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(label, ":")
+			if !ok {
+				return nil, fmt.Errorf("service %q: invalid label %q, should be name:value", name, label)
+			}
+			if common.Labels == nil {
+				common.Labels = map[string]string{}
+			}
+			common.Labels[k] = v
+		}
+		if params["retries"] != "" {
+			var err error
+			if common.Retries, err = strconv.Atoi(params["retries"]); err != nil {
+				return nil, fmt.Errorf("service %q: invalid retries: %w", name, err)
+			}
+		}
+		durations := map[string]*time.Duration{"timeout": &common.Timeout, "interval": &common.Interval,
+			"retry_interval": &common.RetryInterval}
+		for key, d := range durations {
+			if params[key] == "" {
+				continue
+			}
+			var err error
+			if *d, err = time.ParseDuration(params[key]); err != nil {
+				return nil, fmt.Errorf("service %q: invalid %s: %w", name, key, err)
+			}
+		}
+
+		parse := p.Spec().ParseURL
+		if parse == nil {
+			parse = parseURL
+		}
+		req, err := parse(name, u)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		if req, err = withCommon(req, common); err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		res = append(res, req)
+	}
+	return res, nil
+}
+
+// ParseConfig makes requests from services sections of the config, in order of providers registration.
+// Returns error for unknown sections and invalid entries.
+func (r *Registry) ParseConfig(sections map[string][]yaml.Node) ([]Request, error) {
+	for section := range sections {
+		if _, ok := r.sections[section]; !ok {
+			return nil, fmt.Errorf("unknown services section %q", section)
+		}
+	}
+
+	res := []Request{}
+	sectionOf := map[string]string{} // section of service by name, names should be unique across sections
+	for _, p := range r.providers {
+		spec := p.Spec()
+		for i, node := range sections[spec.Section] {
+			req, err := spec.ParseConfig(&node)
+			if err != nil {
+				return nil, fmt.Errorf("%s service #%d: %w", spec.Section, i+1, err)
+			}
+			var common commonOptions
+			if err = node.Decode(&common); err != nil {
+				return nil, fmt.Errorf("%s service #%d: can't decode: %w", spec.Section, i+1, err)
+			}
+			if req, err = withCommon(req, common); err != nil {
+				return nil, fmt.Errorf("%s service %q: %w", spec.Section, req.Name, err)
+			}
+			if req.Name == "" {
+				return nil, fmt.Errorf("%s service #%d: name is required", spec.Section, i+1)
+			}
+			if strings.Contains(req.Name, ":") {
+				return nil, fmt.Errorf("%s service %q: name can't contain ':'", spec.Section, req.Name)
+			}
+			if section, ok := sectionOf[req.Name]; ok {
+				return nil, fmt.Errorf("%s service %q: duplicate name, already used in %s section", spec.Section, req.Name, section)
+			}
+			sectionOf[req.Name] = spec.Section
+			res = append(res, req)
+		}
+	}
+	return res, nil
+}
+
+// withCommon sets common options of the request, checks they are valid
+func withCommon(req Request, common commonOptions) (Request, error) {
+	if common.Timeout < 0 || common.Interval < 0 {
+		return req, fmt.Errorf("negative timeout or interval")
+	}
+	if common.Retries < 0 || common.RetryInterval < 0 {
+		return req, fmt.Errorf("negative retries or retry_interval")
+	}
+	if common.Retries > maxRetries || common.RetryInterval > maxRetryInterval {
+		return req, fmt.Errorf("retries should be up to %d and retry_interval up to %v", maxRetries, maxRetryInterval)
+	}
+	if common.Cron != "" {
+		if _, err := cronParser.Parse(common.Cron); err != nil {
+			return req, fmt.Errorf("invalid cron expression %q: %w", common.Cron, err)
+		}
+	}
+	if err := tagset.Validate(common.Tags, common.Labels); err != nil {
+		return req, err
+	}
+	for _, dep := range common.DependsOn {
+		if dep == "" || strings.Contains(dep, ":") {
+			return req, fmt.Errorf("invalid dependency %q, should be a service name", dep)
+		}
+	}
+	req.Options.Timeout, req.Options.Cron, req.Options.Interval = common.Timeout, common.Cron, common.Interval
+	req.Options.Retries, req.Options.RetryInterval = common.Retries, common.RetryInterval
+	req.Options.Tags, req.Options.Labels = common.Tags, common.Labels
+	req.Options.DependsOn = common.DependsOn
+	return req, nil
+}
+
+// parseURL is the default url parser, checks the url is valid and keeps it as is
+func parseURL(name, u string) (Request, error) {
+	if _, err := url.Parse(u); err != nil {
+		return Request{}, fmt.Errorf("invalid url: %w", err)
+	}
+	return Request{Name: name, URL: u}, nil
+}
+
+// configParser makes ParseConfig function decoding yaml entry to T and converting it to the request with fn
+func configParser[T any](fn func(v T) (Request, error)) func(node *yaml.Node) (Request, error) {
+	return func(node *yaml.Node) (Request, error) {
+		var v T
+		if err := node.Decode(&v); err != nil {
+			return Request{}, fmt.Errorf("can't decode: %w", err)
+		}
+		return fn(v)
+	}
+}
+
+// cutQuery removes query parameters with given keys from the url and returns them unescaped.
+// The rest of the url is kept as is, without re-encoding.
+func cutQuery(u string, keys ...string) (string, map[string]string) {
+	res := map[string]string{}
+	base, query, ok := strings.Cut(u, "?")
+	if !ok {
+		return u, res
+	}
+
+	kept := []string{}
+	for param := range strings.SplitSeq(query, "&") {
+		key, value, _ := strings.Cut(param, "=")
+		if !slices.Contains(keys, key) {
+			kept = append(kept, param)
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		res[key] = value
+	}
+	if len(kept) == 0 {
+		return base, res
+	}
+	return base + "?" + strings.Join(kept, "&"), res
+}
+
+// trimSchemes removes any of the given scheme prefixes from the url
+func trimSchemes(u string, schemes ...string) string {
+	for _, s := range schemes {
+		u = strings.TrimPrefix(u, s+"://")
+	}
+	return u
+}
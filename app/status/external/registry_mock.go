@@ -0,0 +1,107 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package external
+
+import (
+	"sync"
+)
+
+// ProviderMock is a mock implementation of Provider.
+//
+//	func TestSomethingThatUsesProvider(t *testing.T) {
+//
+//		// make and configure a mocked Provider
+//		mockedProvider := &ProviderMock{
+//			SpecFunc: func() ProviderSpec {
+//				panic("mock out the Spec method")
+//			},
+//			StatusFunc: func(req Request) (*Response, error) {
+//				panic("mock out the Status method")
+//			},
+//		}
+//
+//		// use mockedProvider in code that requires Provider
+//		// and then make assertions.
+//
+//	}
+type ProviderMock struct {
+	// SpecFunc mocks the Spec method.
+	SpecFunc func() ProviderSpec
+
+	// StatusFunc mocks the Status method.
+	StatusFunc func(req Request) (*Response, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Spec holds details about calls to the Spec method.
+		Spec []struct {
+		}
+		// Status holds details about calls to the Status method.
+		Status []struct {
+			// Req is the req argument value.
+			Req Request
+		}
+	}
+	lockSpec   sync.RWMutex
+	lockStatus sync.RWMutex
+}
+
+// Spec calls SpecFunc.
+func (mock *ProviderMock) Spec() ProviderSpec {
+	if mock.SpecFunc == nil {
+		panic("ProviderMock.SpecFunc: method is nil but Provider.Spec was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockSpec.Lock()
+	mock.calls.Spec = append(mock.calls.Spec, callInfo)
+	mock.lockSpec.Unlock()
+	return mock.SpecFunc()
+}
+
+// SpecCalls gets all the calls that were made to Spec.
+// Check the length with:
+//
+//	len(mockedProvider.SpecCalls())
+func (mock *ProviderMock) SpecCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockSpec.RLock()
+	calls = mock.calls.Spec
+	mock.lockSpec.RUnlock()
+	return calls
+}
+
+// Status calls StatusFunc.
+func (mock *ProviderMock) Status(req Request) (*Response, error) {
+	if mock.StatusFunc == nil {
+		panic("ProviderMock.StatusFunc: method is nil but Provider.Status was just called")
+	}
+	callInfo := struct {
+		Req Request
+	}{
+		Req: req,
+	}
+	mock.lockStatus.Lock()
+	mock.calls.Status = append(mock.calls.Status, callInfo)
+	mock.lockStatus.Unlock()
+	return mock.StatusFunc(req)
+}
+
+// StatusCalls gets all the calls that were made to Status.
+// Check the length with:
+//
+//	len(mockedProvider.StatusCalls())
+func (mock *ProviderMock) StatusCalls() []struct {
+	Req Request
+} {
+	var calls []struct {
+		Req Request
+	}
+	mock.lockStatus.RLock()
+	calls = mock.calls.Status
+	mock.lockStatus.RUnlock()
+	return calls
+}
@@ -0,0 +1,260 @@
+package external
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewRegistry(t *testing.T) {
+	t.Run("builtin providers", func(t *testing.T) {
+		reg, err := NewRegistry(builtinProviders()...)
+		require.NoError(t, err)
+		for _, u := range []string{"http://example.com", "https://example.com", "mongodb://example.com", "docker:///var/run/docker.sock",
+			"program://ls", "nginx://example.com", "cert://example.com", "file:///tmp/blah", "rmq://example.com/v1/q1"} {
+			_, ok := reg.Lookup(u)
+			assert.True(t, ok, u)
+		}
+		_, ok := reg.Lookup("bad://example.com")
+		assert.False(t, ok)
+		_, ok = reg.Lookup("example.com")
+		assert.False(t, ok)
+	})
+
+	t.Run("duplicate scheme", func(t *testing.T) {
+		_, err := NewRegistry(mockProvider("http", 200, "http", "http"), mockProvider("web", 200, "web", "https", "http"))
+		require.EqualError(t, err, `provider *external.ProviderMock: scheme "http" already registered`)
+	})
+
+	t.Run("duplicate section", func(t *testing.T) {
+		_, err := NewRegistry(mockProvider("http", 200, "http", "http"), mockProvider("http", 200, "http", "https"))
+		require.EqualError(t, err, `provider *external.ProviderMock: section "http" already registered`)
+	})
+
+	t.Run("incomplete spec", func(t *testing.T) {
+		_, err := NewRegistry(mockProvider("http", 200, "http"))
+		require.EqualError(t, err, "provider *external.ProviderMock should define section, schemes and config parser")
+	})
+}
+
+func TestRegistry_ParseServices(t *testing.T) {
+	reg, err := NewRegistry(builtinProviders()...)
+	require.NoError(t, err)
+
+	tbl := []struct {
+		name string
+		svcs []string
+		res  []Request
+		err  string
+	}{
+		{"valid", []string{"s1:http://127.0.0.1/ping", "s2:docker:///var/blah", "s3:mongodb://127.0.0.1:27017"},
+			[]Request{{Name: "s1", URL: "http://127.0.0.1/ping"}, {Name: "s2", URL: "docker:///var/blah"},
+				{Name: "s3", URL: "mongodb://127.0.0.1:27017"}}, ""},
+		{"program with args", []string{`p1:program://ls?args="-la /tmp"`},
+			[]Request{{Name: "p1", URL: "program://ls", Options: Options{Args: []string{"-la", "/tmp"}}}}, ""},
+		{"docker with containers", []string{"d1:docker:///var/blah?containers=c1:c2"},
+			[]Request{{Name: "d1", URL: "docker:///var/blah", Options: Options{Containers: []string{"c1", "c2"}}}}, ""},
+		{"mongo with options", []string{`m1:mongodb://127.0.0.1:27017/admin?authSource=admin&oplogMaxDelta=30s&db=test&collection=c1&count={"status":"active"}`},
+			[]Request{{Name: "m1", URL: "mongodb://127.0.0.1:27017/admin?authSource=admin", Options: Options{OplogMaxDelta: 30 * time.Second,
+				DB: "test", Collection: "c1", CountQuery: `{"status":"active"}`}}}, ""},
+		{"common options", []string{"s1:http://127.0.0.1/ping?timeout=5s&cron=*/5_*_*_*_*&q=1", "s2:file:///tmp/blah?interval=1m",
+			"s3:http://127.0.0.1/ping?retries=3&retry_interval=2s"},
+			[]Request{{Name: "s1", URL: "http://127.0.0.1/ping?q=1", Options: Options{Timeout: 5 * time.Second, Cron: "*/5 * * * *"}},
+				{Name: "s2", URL: "file:///tmp/blah", Options: Options{Interval: time.Minute}},
+				{Name: "s3", URL: "http://127.0.0.1/ping", Options: Options{Retries: 3, RetryInterval: 2 * time.Second}}}, ""},
+		{"tags and labels", []string{"s1:http://127.0.0.1/ping?tags=payments,critical&labels=team:billing,env:prod&q=1"},
+			[]Request{{Name: "s1", URL: "http://127.0.0.1/ping?q=1", Options: Options{Tags: []string{"payments", "critical"},
+				Labels: map[string]string{"team": "billing", "env": "prod"}}}}, ""},
+		{"depends on", []string{"s1:http://127.0.0.1/ping?depends_on=d1,d2&q=1"},
+			[]Request{{Name: "s1", URL: "http://127.0.0.1/ping?q=1", Options: Options{DependsOn: []string{"d1", "d2"}}}}, ""},
+		{"invalid dependency", []string{"s1:http://127.0.0.1/ping?depends_on=d1,"}, nil,
+			`service "s1": invalid dependency "", should be a service name`},
+		{"empty", []string{}, []Request{}, ""},
+		{"no url", []string{"s1:http://127.0.0.1/ping", "s2"}, nil, `invalid service "s2", should be <name>:<url>`},
+		{"no name", []string{":http://127.0.0.1/ping"}, nil, `invalid service ":http://127.0.0.1/ping", should be <name>:<url>`},
+		{"unknown scheme", []string{"s1:blah://127.0.0.1"}, nil, `service "s1": unsupported url "blah://127.0.0.1"`},
+		{"no scheme", []string{"1:1"}, nil, `service "1": unsupported url "1"`},
+		{"invalid url", []string{"s1:http://127.0.0.1:port"}, nil, `service "s1": invalid url: parse "http://127.0.0.1:port": invalid port ":port" after host`},
+		{"cert without host", []string{"c1:cert://"}, nil, `service "c1": host is required`},
+		{"file without path", []string{"f1:file://"}, nil, `service "f1": path is required`},
+		{"program without path", []string{"p1:program://?args=1"}, nil, `service "p1": program path is required`},
+		{"invalid interval", []string{"s1:http://127.0.0.1/ping?interval=1x"}, nil,
+			`service "s1": invalid interval: time: unknown unit "x" in duration "1x"`},
+		{"negative timeout", []string{"s1:http://127.0.0.1/ping?timeout=-1s"}, nil, `service "s1": negative timeout or interval`},
+		{"invalid retries", []string{"s1:http://127.0.0.1/ping?retries=many"}, nil,
+			`service "s1": invalid retries: strconv.Atoi: parsing "many": invalid syntax`},
+		{"negative retries", []string{"s1:http://127.0.0.1/ping?retries=-1"}, nil, `service "s1": negative retries or retry_interval`},
+		{"too many retries", []string{"s1:http://127.0.0.1/ping?retries=100"}, nil,
+			`service "s1": retries should be up to 10 and retry_interval up to 1m0s`},
+		{"long retry interval", []string{"s1:http://127.0.0.1/ping?retries=1&retry_interval=1h"}, nil,
+			`service "s1": retries should be up to 10 and retry_interval up to 1m0s`},
+		{"invalid label", []string{"s1:http://127.0.0.1/ping?labels=team"}, nil, `service "s1": invalid label "team", should be name:value`},
+		{"reserved label", []string{"s1:http://127.0.0.1/ping?labels=service:x"}, nil, `service "s1": label name "service" is reserved`},
+		{"invalid cron", []string{"s1:http://127.0.0.1/ping?cron=blah"}, nil,
+			`service "s1": invalid cron expression "blah": expected exactly 5 fields, found 1: [blah]`},
+		{"mongo with countQuery", []string{`m1:mongodb://127.0.0.1:27017/admin?db=test&collection=c1&countQuery={"status":"active"}`},
+			[]Request{{Name: "m1", URL: "mongodb://127.0.0.1:27017/admin", Options: Options{DB: "test", Collection: "c1",
+				CountQuery: `{"status":"active"}`}}}, ""},
+		{"invalid oplogMaxDelta", []string{"m1:mongodb://127.0.0.1:27017?oplogMaxDelta=55xx"}, nil,
+			`service "m1": can't parse oplogMaxDelta: time: unknown unit "xx" in duration "55xx"`},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reg.ParseServices(tt.svcs)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestRegistry_ParseConfig(t *testing.T) {
+	reg, err := NewRegistry(builtinProviders()...)
+	require.NoError(t, err)
+
+	parse := func(t *testing.T, data string) ([]Request, error) {
+		t.Helper()
+		var sections map[string][]yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(data), &sections))
+		return reg.ParseConfig(sections)
+	}
+
+	t.Run("all sections", func(t *testing.T) {
+		res, err := parse(t, `
+mongo:
+  - {name: dev, url: mongodb://example.com:27017, oplog_max_delta: 30m}
+certificate:
+  - {name: prim_cert, url: https://example1.com}
+  - {name: second_cert, url: https://example2.com}
+docker:
+  - {name: docker1, url: unix:///var/run/docker.sock, containers: [reproxy, mattermost, postgres]}
+  - {name: docker2, url: tcp://192.168.1.1:4080}
+file:
+  - {name: first_file, path: /tmp/example1.txt, depends_on: [docker1]}
+  - {name: second_file, path: /tmp/example2.txt}
+http:
+  - {name: first, url: https://example1.com, headers: {Authorization: Bearer token}, actuator: true}
+  - {name: second, url: https://example2.com, timeout: 5s, cron: "*/5 * * * *", interval: 1m, retries: 2, retry_interval: 3s,
+     tags: [payments], labels: {team: billing}}
+program:
+  - {name: first_program, path: /usr/bin/example1, args: [arg1, arg2]}
+  - {name: second_program, path: /usr/bin/example2}
+nginx:
+  - {name: nginx, status_url: http://example.com:80}
+rmq:
+  - {name: rmqtest, url: http://example.com:15672, vhost: v1, queue: q1, user: guest, pass: passwd}
+`)
+		require.NoError(t, err)
+		exp := []Request{
+			{Name: "first", URL: "https://example1.com", Options: Options{Headers: map[string]string{"Authorization": "Bearer token"},
+				Actuator: true}},
+			{Name: "second", URL: "https://example2.com", Options: Options{Timeout: 5 * time.Second, Cron: "*/5 * * * *",
+				Interval: time.Minute, Retries: 2, RetryInterval: 3 * time.Second, Tags: []string{"payments"},
+				Labels: map[string]string{"team": "billing"}}},
+			{Name: "prim_cert", URL: "cert://example1.com"}, {Name: "second_cert", URL: "cert://example2.com"},
+			{Name: "docker1", URL: "docker:///var/run/docker.sock", Options: Options{Containers: []string{"reproxy", "mattermost", "postgres"}}},
+			{Name: "docker2", URL: "docker://192.168.1.1:4080"},
+			{Name: "first_file", URL: "file:///tmp/example1.txt", Options: Options{DependsOn: []string{"docker1"}}},
+			{Name: "second_file", URL: "file:///tmp/example2.txt"},
+			{Name: "dev", URL: "mongodb://example.com:27017", Options: Options{OplogMaxDelta: 30 * time.Minute}},
+			{Name: "nginx", URL: "nginx://example.com:80"},
+			{Name: "first_program", URL: "program:///usr/bin/example1", Options: Options{Args: []string{"arg1", "arg2"}}},
+			{Name: "second_program", URL: "program:///usr/bin/example2"},
+			{Name: "rmqtest", URL: "rmq://example.com:15672", Options: Options{User: "guest", Password: "passwd", Vhost: "v1", Queue: "q1"}},
+		}
+		assert.Equal(t, exp, res)
+	})
+
+	t.Run("mongo with query params", func(t *testing.T) {
+		res, err := parse(t, `
+mongo:
+  - {name: dev, url: "mongodb://example.com:27017/admin?foo=bar&blah=blah", oplog_max_delta: 30m}
+`)
+		require.NoError(t, err)
+		assert.Equal(t, []Request{{Name: "dev", URL: "mongodb://example.com:27017/admin?foo=bar&blah=blah",
+			Options: Options{OplogMaxDelta: 30 * time.Minute}}}, res)
+	})
+
+	t.Run("mongo with count params", func(t *testing.T) {
+		res, err := parse(t, `
+mongo:
+  - {name: dev, url: "mongodb://example.com:27017/admin", collection: coll, db: test, count_query: '{"status":"active"}'}
+`)
+		require.NoError(t, err)
+		assert.Equal(t, []Request{{Name: "dev", URL: "mongodb://example.com:27017/admin",
+			Options: Options{Collection: "coll", DB: "test", CountQuery: `{"status":"active"}`}}}, res)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tbl := []struct {
+			data string
+			err  string
+		}{
+			{"blah:\n  - {name: n1, url: http://example.com}", `unknown services section "blah"`},
+			{"http:\n  - {url: http://example.com}", "http service #1: name is required"},
+			{"http:\n  - {name: n1}", "http service #1: url is required"},
+			{"http:\n  - {name: 'n:1', url: http://example.com}", `http service "n:1": name can't contain ':'`},
+			{"http:\n  - {name: n1, url: http://example.com}\n  - {name: n1, url: http://example.org}",
+				`http service "n1": duplicate name, already used in http section`},
+			{"http:\n  - {name: n1, url: http://example.com}\nfile:\n  - {name: n1, path: /tmp/blah}",
+				`file service "n1": duplicate name, already used in http section`},
+			{"file:\n  - {name: f1}\n  - {name: f2}", "file service #1: path is required"},
+			{"rmq:\n  - {name: r1, url: http://example.com}", "rmq service #1: url, vhost and queue are required"},
+			{"http:\n  - {name: n1, url: http://example.com, cron: blah}",
+				`http service "n1": invalid cron expression "blah": expected exactly 5 fields, found 1: [blah]`},
+			{"http:\n  - {name: n1, url: http://example.com, interval: -1m}", `http service "n1": negative timeout or interval`},
+			{"http:\n  - {name: n1, url: http://example.com, retry_interval: -1s}", `http service "n1": negative retries or retry_interval`},
+			{"http:\n  - {name: n1, url: http://example.com, tags: ['']}",
+				`http service "n1": invalid tag "", should be non-empty and without commas`},
+			{"docker:\n  - {name: d1, containers: blah}", "docker service #1: can't decode: yaml: unmarshal errors:\n" +
+				"  line 2: cannot unmarshal !!str `blah` into []string"},
+		}
+		for _, tt := range tbl {
+			_, err := parse(t, tt.data)
+			require.EqualError(t, err, tt.err)
+		}
+	})
+}
+
+// builtinProviders returns all providers of the package, as registered by main
+func builtinProviders() []Provider {
+	return []Provider{&HTTPProvider{}, &CertificateProvider{}, &DockerProvider{}, &FileProvider{}, &MongoProvider{},
+		&NginxProvider{}, &ProgramProvider{}, &RMQProvider{}}
+}
+
+// urlRequest makes request for the provider from the service url, as set on the command line
+func urlRequest(t *testing.T, p Provider, name, u string) Request {
+	t.Helper()
+	reg, err := NewRegistry(p)
+	require.NoError(t, err)
+	reqs, err := reg.ParseServices([]string{name + ":" + u})
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	return reqs[0]
+}
+
+func TestCutQuery(t *testing.T) {
+	tbl := []struct {
+		u, res string
+		params map[string]string
+	}{
+		{"http://example.com", "http://example.com", map[string]string{}},
+		{"http://example.com?a=1&b=2", "http://example.com?b=2", map[string]string{"a": "1"}},
+		{"http://example.com?a=1&c=x%20y", "http://example.com", map[string]string{"a": "1", "c": "x y"}},
+		{`http://example.com?b={"k":"v"}&c=*/5_*`, `http://example.com?b={"k":"v"}`, map[string]string{"c": "*/5_*"}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.u, func(t *testing.T) {
+			res, params := cutQuery(tt.u, "a", "c")
+			assert.Equal(t, tt.res, res)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
@@ -48,6 +48,7 @@ func TestRMQ_Status(t *testing.T) {
 		assert.Equal(t, 3771, resp.Body["messages_ready_ram"])
 		assert.Equal(t, 13847734, resp.Body["publish"])
 		assert.Equal(t, 56178, resp.Body["messages_delta"])
+		assert.Equal(t, Health{Status: HealthUp}, resp.Health)
 	}
 
 	{
@@ -59,6 +60,38 @@ func TestRMQ_Status(t *testing.T) {
 	}
 }
 
+func TestRMQ_StatusWithOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/queues/%2F/notification.queue", r.URL.EscapedPath())
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "guest", user)
+		assert.Equal(t, "passwd", pass)
+		body, err := os.ReadFile("testdata/rmq.json")
+		assert.NoError(t, err)
+		_, err = w.Write(body)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	rmq := RMQProvider{TimeOut: time.Second}
+	req := Request{Name: "rmq-test", URL: strings.Replace(ts.URL, "http://", "rmq://", 1),
+		Options: Options{User: "guest", Password: "passwd", Vhost: "/", Queue: "notification.queue"}}
+	resp, err := rmq.Status(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "notification.queue", resp.Body["name"])
+}
+
+func TestRMQ_health(t *testing.T) {
+	rmq := RMQProvider{}
+	assert.Equal(t, Health{Status: HealthUp}, rmq.health("running"))
+	assert.Equal(t, Health{Status: HealthUp}, rmq.health("idle"))
+	assert.Equal(t, Health{Status: HealthUp}, rmq.health(""))
+	assert.Equal(t, Health{Status: HealthWarn, Reason: "queue is in flow control"}, rmq.health("flow"))
+	assert.Equal(t, Health{Status: HealthDown, Reason: "queue state crashed"}, rmq.health("crashed"))
+}
+
 func TestRMQ_StatusFailed(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/queues/feeds/notification.queue", r.URL.Path)
@@ -72,4 +105,19 @@ func TestRMQ_StatusFailed(t *testing.T) {
 	_, err := rmq.Status(Request{Name: "rmq-test", URL: u})
 	require.ErrorContains(t, err, "failed to get RabbitMQ response")
 	require.ErrorContains(t, err, "500 Internal Server Error")
+	assert.Equal(t, ErrorOther, ErrorCategory(err))
+}
+
+func TestRMQ_StatusUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	rmq := RMQProvider{TimeOut: time.Second}
+	req := Request{Name: "rmq-test", URL: strings.Replace(ts.URL, "http://", "rmq://", 1),
+		Options: Options{User: "guest", Password: "bad", Vhost: "v1", Queue: "q1"}}
+	_, err := rmq.Status(req)
+	require.ErrorContains(t, err, "401 Unauthorized")
+	assert.Equal(t, ErrorAuth, ErrorCategory(err))
 }
@@ -0,0 +1,387 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+//go:generate moq -out checker_mock.go -skip-ensure -fmt goimports . Checker
+
+// Scheduler runs each request in background on its own schedule and keeps the latest response for each.
+// Consumers get the last known responses from Status() without triggering any checks.
+//
+// The schedule of a request is defined by its options:
+//   - Cron runs the check when the time matches the cron expression, i.e. "*/5 * * * *"
+//   - Interval runs the check with the given interval, i.e. 10s
+//
+// Requests without schedule options run with the default interval. Failed (DOWN) checks are retried up to Options.Retries
+// times, with backoff starting from Options.RetryInterval, and the response is kept after the last attempt. The first check of a request waits for the first
+// checks of services it depends on, so dependents are checked in dependency order. Dependents failed on their last check
+// are reported as UNKNOWN while a service they depend on is failing, even if it failed after the check of the dependent.
+type Scheduler struct {
+	// OnResponse is called with each response kept as the last one, when its check is completed, optional.
+	// Should be set before Run.
+	OnResponse func(resp Response)
+
+	checker     Checker
+	interval    time.Duration
+	concurrency int
+
+	lastResponses struct {
+		cache map[string]Response // by request key
+		mu    sync.RWMutex
+	}
+
+	workers struct {
+		ctx     context.Context               // context of Run, nil if scheduler is not running
+		sema    chan struct{}                 // limits the number of running checks
+		cancels map[string]context.CancelFunc // by request key
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+	}
+	nowFn func() time.Time // for testing
+}
+
+// maxRetryDelay limits the backoff between retries of failed checks
+const maxRetryDelay = 5 * time.Minute
+
+// dependencyPoll is the interval of checking if services the request depends on got their first responses
+const dependencyPoll = 50 * time.Millisecond
+
+// cronParser parses standard 5 fields cron expressions
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Checker runs a single check for the request, implemented by Service
+type Checker interface {
+	Requests() []Request
+	Check(req Request) Response
+}
+
+// NewScheduler makes a scheduler for all requests of the checker.
+// interval is the default interval between checks, concurrency limits the number of checks running at the same time.
+func NewScheduler(checker Checker, interval time.Duration, concurrency int) *Scheduler {
+	res := &Scheduler{checker: checker, interval: interval, concurrency: concurrency, nowFn: time.Now}
+	res.lastResponses.cache = make(map[string]Response)
+	res.workers.cancels = make(map[string]context.CancelFunc)
+	return res
+}
+
+// Run starts background checks for all requests and blocks until context is canceled
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("[INFO] start scheduler for %d services, default interval %v", len(s.checker.Requests()), s.interval)
+	s.workers.mu.Lock()
+	s.workers.ctx = ctx
+	s.workers.sema = make(chan struct{}, max(s.concurrency, 1))
+	s.workers.mu.Unlock()
+
+	s.Reload()
+	<-ctx.Done()
+
+	s.workers.mu.Lock()
+	s.workers.ctx = nil
+	s.workers.cancels = make(map[string]context.CancelFunc)
+	s.workers.mu.Unlock()
+	s.workers.wg.Wait()
+	log.Printf("[INFO] scheduler stopped")
+}
+
+// Reload syncs running checks with the current requests of the checker. Checks of requests with unchanged
+// name, url and options keep running with their last responses, new requests start, and removed requests stop
+// and their responses dropped. Does nothing if the scheduler is not running.
+func (s *Scheduler) Reload() {
+	s.workers.mu.Lock()
+	defer s.workers.mu.Unlock()
+	if s.workers.ctx == nil || s.workers.ctx.Err() != nil {
+		return
+	}
+
+	reqs := map[string]Request{}
+	for _, req := range s.checker.Requests() {
+		reqs[req.key()] = req
+	}
+
+	removed, added := 0, 0
+	for key, cancel := range s.workers.cancels {
+		if _, ok := reqs[key]; ok {
+			continue
+		}
+		cancel()
+		delete(s.workers.cancels, key)
+		s.lastResponses.mu.Lock()
+		delete(s.lastResponses.cache, key)
+		s.lastResponses.mu.Unlock()
+		removed++
+	}
+
+	for key, req := range reqs {
+		if _, ok := s.workers.cancels[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(s.workers.ctx)
+		s.workers.cancels[key] = cancel
+		sema := s.workers.sema
+		s.workers.wg.Go(func() { s.worker(ctx, req, sema) })
+		added++
+	}
+	log.Printf("[DEBUG] scheduler reloaded, %d services running, %d added, %d removed", len(s.workers.cancels), added, removed)
+}
+
+// Status returns the last known responses for all requests checked at least once, sorted by name.
+// Age of each response is set to the time passed since the check.
+func (s *Scheduler) Status() []Response {
+	now := s.nowFn()
+	s.lastResponses.mu.RLock()
+	res := make([]Response, 0, len(s.lastResponses.cache))
+	for _, r := range s.lastResponses.cache {
+		r.Age = now.Sub(r.CheckedAt).Milliseconds()
+		res = append(res, r)
+	}
+	s.lastResponses.mu.RUnlock()
+	s.gateDependents(res)
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Response returns the last known response of the named service, false if it is unknown or not checked yet
+func (s *Scheduler) Response(name string) (Response, bool) {
+	for _, r := range s.Status() {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Response{}, false
+}
+
+// gateDependents reports DOWN responses of services depending on a failing (DOWN or UNKNOWN) service as UNKNOWN
+// with 424 status code, the same way Service.Check reports dependents checked while the dependency is failing.
+// This covers dependencies failed after the last check of the dependent, so the dependent is not DOWN till its next check.
+func (s *Scheduler) gateDependents(res []Response) {
+	deps := map[string][]string{}
+	for _, r := range s.checker.Requests() {
+		if len(r.Options.DependsOn) > 0 {
+			deps[r.Name] = r.Options.DependsOn
+		}
+	}
+	if len(deps) == 0 {
+		return
+	}
+
+	idx := make(map[string]int, len(res))
+	for i, r := range res {
+		idx[r.Name] = i
+	}
+	gated := map[string]bool{}
+	var gate func(name string)
+	gate = func(name string) {
+		i, ok := idx[name]
+		if !ok || gated[name] {
+			return
+		}
+		gated[name] = true // set before dependencies are gated, to stop on cycles
+		for _, dep := range deps[name] {
+			gate(dep) // dependency can be UNKNOWN because of its own dependencies
+			j, ok := idx[dep]
+			if !ok || res[i].Health.Status != HealthDown {
+				continue
+			}
+			if st := res[j].Health.Status; st == HealthDown || st == HealthUnknown {
+				res[i].StatusCode = http.StatusFailedDependency
+				res[i].Health = Health{Status: HealthUnknown, Reason: fmt.Sprintf("dependency %s %s", dep, strings.ToLower(st))}
+				return
+			}
+		}
+	}
+	for _, r := range res {
+		gate(r.Name)
+	}
+}
+
+// CheckNow runs a single attempt of the check of the named service immediately, without retries, within the concurrency
+// limit of scheduled checks, and keeps the response as the last known one. Returns false if there is no request with the name,
+// and error if the context is done before the check is completed. The check started is completed in background then.
+func (s *Scheduler) CheckNow(ctx context.Context, name string) (Response, bool, error) {
+	var req Request
+	found := false
+	for _, r := range s.checker.Requests() {
+		if r.Name == name {
+			req, found = r, true
+			break
+		}
+	}
+	if !found {
+		return Response{}, false, nil
+	}
+
+	s.workers.mu.Lock()
+	sema := s.workers.sema
+	s.workers.mu.Unlock()
+	if sema != nil {
+		select {
+		case sema <- struct{}{}:
+		case <-ctx.Done():
+			return Response{}, true, fmt.Errorf("no free slot to check %s: %w", name, ctx.Err())
+		}
+	}
+
+	done := make(chan Response, 1)
+	go func() {
+		resp := s.checker.Check(req)
+		if sema != nil {
+			<-sema
+		}
+		// keep the response only for running requests, removed ones are not reported anymore
+		s.workers.mu.Lock()
+		_, running := s.workers.cancels[req.key()]
+		if running {
+			s.lastResponses.mu.Lock()
+			s.lastResponses.cache[req.key()] = resp
+			s.lastResponses.mu.Unlock()
+		}
+		s.workers.mu.Unlock()
+		if running && s.OnResponse != nil {
+			s.OnResponse(resp)
+		}
+		done <- resp
+	}()
+
+	select {
+	case resp := <-done:
+		return resp, true, nil
+	case <-ctx.Done():
+		return Response{}, true, fmt.Errorf("check of %s not completed: %w", name, ctx.Err())
+	}
+}
+
+// worker runs checks for a single request until context is canceled.
+// Requests scheduled with interval run immediately, cron-scheduled requests wait for the first matching time.
+func (s *Scheduler) worker(ctx context.Context, req Request, sema chan struct{}) {
+	s.waitDependencies(ctx, req)
+	next := s.nowFn()
+	if sch, err := s.cronSchedule(req.Options.Cron); err == nil && sch != nil {
+		next = sch.Next(next)
+	}
+
+	attempt := 1
+	for {
+		timer := time.NewTimer(next.Sub(s.nowFn()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case sema <- struct{}{}:
+		}
+		resp := s.checker.Check(req)
+		<-sema
+
+		// failed check is retried after the delay, without holding the concurrency slot while waiting
+		if resp.Health.Status == HealthDown && attempt <= req.Options.Retries {
+			delay := retryDelay(req.Options.RetryInterval, attempt)
+			log.Printf("[DEBUG] retry service %s in %v, attempt %d of %d", req.Name, delay, attempt+1, req.Options.Retries+1)
+			attempt++
+			next = s.nowFn().Add(delay)
+			continue
+		}
+		resp.Attempts, attempt = attempt, 1
+
+		s.lastResponses.mu.Lock()
+		if ctx.Err() != nil { // request removed or scheduler stopped during the check, drop the response
+			s.lastResponses.mu.Unlock()
+			return
+		}
+		s.lastResponses.cache[req.key()] = resp
+		s.lastResponses.mu.Unlock()
+		if s.OnResponse != nil {
+			s.OnResponse(resp)
+		}
+
+		next = s.next(req, s.nowFn())
+	}
+}
+
+// waitDependencies waits until services the request depends on are checked at least once, or context is canceled.
+// Dependencies scheduled by cron are not waited for, as their first check can be far away.
+func (s *Scheduler) waitDependencies(ctx context.Context, req Request) {
+	if len(req.Options.DependsOn) == 0 {
+		return
+	}
+	ticker := time.NewTicker(dependencyPoll)
+	defer ticker.Stop()
+	for {
+		pending := slices.ContainsFunc(s.checker.Requests(), func(r Request) bool {
+			if !slices.Contains(req.Options.DependsOn, r.Name) || strings.TrimSpace(r.Options.Cron) != "" {
+				return false
+			}
+			_, ok := s.Response(r.Name)
+			return !ok
+		})
+		if !pending {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryDelay returns the delay before the retry following the attempt, starting from interval (1s if not set)
+// and doubled for each next retry, up to maxRetryDelay
+func retryDelay(interval time.Duration, attempt int) time.Duration {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for range attempt - 1 {
+		if interval >= maxRetryDelay/2 {
+			return maxRetryDelay
+		}
+		interval *= 2
+	}
+	return min(interval, maxRetryDelay)
+}
+
+// next returns the time of the next check for the request after the given time
+func (s *Scheduler) next(req Request, after time.Time) time.Time {
+	sch, err := s.cronSchedule(req.Options.Cron)
+	if err != nil {
+		log.Printf("[WARN] failed to parse cron expression for service %s: %v", req.Name, err)
+	}
+	if sch != nil {
+		return sch.Next(after)
+	}
+
+	if req.Options.Interval > 0 {
+		return after.Add(req.Options.Interval)
+	}
+	return after.Add(s.interval)
+}
+
+// cronSchedule returns the schedule for cron expression.
+// If no cron expression is set, returns nil schedule and nil error.
+func (s *Scheduler) cronSchedule(expr string) (cron.Schedule, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil // no cron expression is set
+	}
+	return ParseCron(expr)
+}
+
+// ParseCron parses standard 5 fields cron expression, the same way as cron of service checks,
+// i.e. for maintenance windows
+func ParseCron(expr string) (cron.Schedule, error) {
+	return cronParser.Parse(strings.TrimSpace(expr))
+}
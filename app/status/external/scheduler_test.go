@@ -0,0 +1,491 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	var count atomic.Int32
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{
+				{Name: "s1", URL: "http://example.com", Options: Options{Interval: 10 * time.Millisecond}},
+				{Name: "s2", URL: "http://example.org"},
+				{Name: "s3", URL: "http://example.net", Options: Options{Cron: "0 0 1 1 *"}}, // once a year, never runs in the test
+			}
+		},
+		CheckFunc: func(req Request) Response {
+			count.Add(1)
+			return Response{Name: req.Name, StatusCode: 200, CheckedAt: time.Now()}
+		},
+	}
+
+	s := NewScheduler(checker, time.Hour, 2)
+	assert.Empty(t, s.Status(), "no responses before the first check")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return count.Load() >= 5 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	res := s.Status()
+	require.Len(t, res, 2, "cron-scheduled s3 is not checked yet")
+	assert.Equal(t, "s1", res[0].Name)
+	assert.Equal(t, "s2", res[1].Name)
+
+	calls := map[string]int{}
+	for _, c := range checker.CheckCalls() {
+		calls[c.Req.Name]++
+	}
+	assert.Equal(t, 1, calls["s2"], "s2 runs once with default hourly interval")
+	assert.GreaterOrEqual(t, calls["s1"], 4, "s1 runs every 10ms")
+	assert.Zero(t, calls["s3"])
+}
+
+func TestScheduler_Reload(t *testing.T) {
+	var mu sync.Mutex
+	reqs := []Request{{Name: "s1", URL: "http://example.com"}, {Name: "s2", URL: "http://example.org"}}
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			mu.Lock()
+			defer mu.Unlock()
+			return reqs
+		},
+		CheckFunc: func(req Request) Response {
+			return Response{Name: req.Name, StatusCode: 200, CheckedAt: time.Now()}
+		},
+	}
+
+	s := NewScheduler(checker, time.Hour, 2)
+	s.Reload() // not running, nothing happens
+	assert.Empty(t, checker.CheckCalls())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(s.Status()) == 2 }, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	reqs = []Request{{Name: "s1", URL: "http://example.com"}, {Name: "s3", URL: "http://example.net"}}
+	mu.Unlock()
+	s.Reload()
+	require.Eventually(t, func() bool { return len(s.Status()) == 2 && s.Status()[1].Name == "s3" }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	res := s.Status()
+	require.Len(t, res, 2)
+	assert.Equal(t, "s1", res[0].Name)
+	assert.Equal(t, "s3", res[1].Name)
+
+	calls := map[string]int{}
+	for _, c := range checker.CheckCalls() {
+		calls[c.Req.Name]++
+	}
+	assert.Equal(t, map[string]int{"s1": 1, "s2": 1, "s3": 1}, calls, "unchanged s1 is not restarted")
+}
+
+func TestScheduler_Retries(t *testing.T) {
+	var calls atomic.Int32
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{
+				{Name: "s1", URL: "http://example.com", Options: Options{Retries: 3, RetryInterval: 50 * time.Millisecond}},
+				{Name: "s2", URL: "http://example.org", Options: Options{Interval: 10 * time.Millisecond}},
+			}
+		},
+		CheckFunc: func(req Request) Response {
+			if req.Name == "s2" {
+				return Response{Name: req.Name, StatusCode: 200, Health: Health{Status: HealthUp}, CheckedAt: time.Now()}
+			}
+			if calls.Add(1) <= 2 {
+				return Response{Name: req.Name, StatusCode: 500, Health: Health{Status: HealthDown}, CheckedAt: time.Now()}
+			}
+			return Response{Name: req.Name, StatusCode: 200, Health: Health{Status: HealthUp}, CheckedAt: time.Now()}
+		},
+	}
+
+	s := NewScheduler(checker, time.Hour, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { _, ok := s.Response("s1"); return ok }, time.Second, 5*time.Millisecond)
+	resp, _ := s.Response("s1")
+	assert.Equal(t, 200, resp.StatusCode, "failed attempts are not reported")
+	assert.Equal(t, 3, resp.Attempts, "retried twice and succeeded")
+	cancel()
+	<-done
+
+	s2 := 0
+	for _, c := range checker.CheckCalls() {
+		if c.Req.Name == "s2" {
+			s2++
+		}
+	}
+	assert.Greater(t, s2, 5, "other checks not blocked by retries with concurrency 1")
+}
+
+func TestScheduler_RetriesCanceled(t *testing.T) {
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{{Name: "s1", URL: "http://example.com", Options: Options{Retries: 3, RetryInterval: time.Minute}}}
+		},
+		CheckFunc: func(req Request) Response {
+			return Response{Name: req.Name, StatusCode: 500, Health: Health{Status: HealthDown}, CheckedAt: time.Now()}
+		},
+	}
+	s := NewScheduler(checker, time.Hour, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(checker.CheckCalls()) == 1 }, time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler not stopped while waiting for retry")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tbl := []struct {
+		interval time.Duration
+		attempt  int
+		res      time.Duration
+	}{
+		{0, 1, time.Second},
+		{0, 3, 4 * time.Second},
+		{time.Minute, 1, time.Minute},
+		{time.Minute, 3, 4 * time.Minute},
+		{time.Minute, 4, maxRetryDelay},
+		{time.Minute, 100, maxRetryDelay},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.res, retryDelay(tt.interval, tt.attempt), "%v %d", tt.interval, tt.attempt)
+	}
+}
+
+func TestScheduler_Dependencies(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{
+				{Name: "web", URL: "http://example.com", Options: Options{DependsOn: []string{"docker1", "cron1"}}},
+				{Name: "docker1", URL: "docker:///var/run/docker.sock"},
+				{Name: "cron1", URL: "http://example.org", Options: Options{Cron: "0 0 1 1 *"}}, // never runs in the test
+			}
+		},
+		CheckFunc: func(req Request) Response {
+			if req.Name == "docker1" {
+				time.Sleep(100 * time.Millisecond) // slow check, dependent waits for it
+			}
+			mu.Lock()
+			order = append(order, req.Name)
+			mu.Unlock()
+			return Response{Name: req.Name, StatusCode: 200, CheckedAt: time.Now()}
+		},
+	}
+
+	s := NewScheduler(checker, time.Hour, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return len(s.Status()) == 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"docker1", "web"}, order, "web checked after docker1, cron dependency not waited for")
+}
+
+func TestScheduler_ServiceDependencies(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	p := &ProviderMock{
+		StatusFunc: func(r Request) (*Response, error) {
+			mu.Lock()
+			order = append(order, r.Name)
+			mu.Unlock()
+			return &Response{StatusCode: 200, Name: r.Name}, nil
+		},
+		SpecFunc: func() ProviderSpec {
+			return ProviderSpec{Section: "http", Schemes: []string{"http"}, ParseConfig: nopConfig}
+		},
+	}
+	reg, err := NewRegistry(p)
+	require.NoError(t, err)
+	svc := NewService(reg,
+		Request{Name: "web", URL: "http://127.0.0.1/web", Options: Options{DependsOn: []string{"api"}}},
+		Request{Name: "api", URL: "http://127.0.0.1/api", Options: Options{DependsOn: []string{"docker1"}}},
+		Request{Name: "docker1", URL: "http://127.0.0.1/docker"},
+	)
+
+	s := NewScheduler(svc, time.Hour, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(s.Status()) == 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"docker1", "api", "web"}, order, "checked in dependency order")
+	for _, r := range s.Status() {
+		assert.Equal(t, HealthUp, r.Health.Status, r.Name)
+	}
+}
+
+func TestScheduler_DependencyFailedAfterStart(t *testing.T) {
+	var dbDown atomic.Bool
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{
+				{Name: "db", URL: "http://db"},
+				{Name: "api", URL: "http://api", Options: Options{DependsOn: []string{"db"}}},
+				{Name: "web", URL: "http://web", Options: Options{DependsOn: []string{"api"}}},
+			}
+		},
+		CheckFunc: func(req Request) Response {
+			if dbDown.Load() {
+				return Response{Name: req.Name, StatusCode: 500, Health: Health{Status: HealthDown, Reason: "failed"},
+					CheckedAt: time.Now()}
+			}
+			return Response{Name: req.Name, StatusCode: 200, Health: Health{Status: HealthUp}, CheckedAt: time.Now()}
+		},
+	}
+	s := NewScheduler(checker, time.Hour, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	require.Eventually(t, func() bool { return len(s.Status()) == 3 }, time.Second, 5*time.Millisecond)
+
+	// db fails, dependents are checked before db gets its next check
+	dbDown.Store(true)
+	for _, name := range []string{"web", "api"} {
+		resp, ok, err := s.CheckNow(context.Background(), name)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, HealthDown, resp.Health.Status, "db is not known as failed yet")
+	}
+
+	_, _, err := s.CheckNow(context.Background(), "db")
+	require.NoError(t, err)
+	res := s.Status()
+	require.Len(t, res, 3)
+	assert.Equal(t, "api", res[0].Name)
+	assert.Equal(t, Health{Status: HealthUnknown, Reason: "dependency db down"}, res[0].Health)
+	assert.Equal(t, http.StatusFailedDependency, res[0].StatusCode)
+	assert.Equal(t, Health{Status: HealthDown, Reason: "failed"}, res[1].Health, "db")
+	assert.Equal(t, Health{Status: HealthUnknown, Reason: "dependency api unknown"}, res[2].Health, "web")
+
+	resp, ok := s.Response("web")
+	require.True(t, ok)
+	assert.Equal(t, HealthUnknown, resp.Health.Status)
+
+	// db recovered, failures of dependents are reported as is
+	dbDown.Store(false)
+	_, _, err = s.CheckNow(context.Background(), "db")
+	require.NoError(t, err)
+	resp, ok = s.Response("api")
+	require.True(t, ok)
+	assert.Equal(t, HealthDown, resp.Health.Status)
+}
+
+func TestScheduler_StatusAge(t *testing.T) {
+	s := NewScheduler(&CheckerMock{RequestsFunc: func() []Request { return nil }}, time.Minute, 1)
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.nowFn = func() time.Time { return now }
+	s.lastResponses.cache[Request{Name: "s2"}.key()] = Response{Name: "s2", StatusCode: 200, CheckedAt: now.Add(-1500 * time.Millisecond)}
+	s.lastResponses.cache[Request{Name: "s1"}.key()] = Response{Name: "s1", StatusCode: 500, CheckedAt: now.Add(-time.Minute)}
+
+	res := s.Status()
+	require.Len(t, res, 2)
+	assert.Equal(t, "s1", res[0].Name)
+	assert.Equal(t, int64(60000), res[0].Age)
+	assert.Equal(t, "s2", res[1].Name)
+	assert.Equal(t, int64(1500), res[1].Age)
+}
+
+func TestScheduler_next(t *testing.T) {
+	s := NewScheduler(&CheckerMock{}, time.Minute, 1)
+	ts := time.Date(2023, 1, 1, 11, 59, 59, 0, time.UTC)
+
+	tbl := []struct {
+		name string
+		opts Options
+		exp  time.Time
+	}{
+		{"default interval", Options{}, ts.Add(time.Minute)},
+		{"custom interval", Options{Interval: 10 * time.Second}, ts.Add(10 * time.Second)},
+		{"negative interval", Options{Interval: -5 * time.Second}, ts.Add(time.Minute)},
+		{"cron", Options{Cron: "0 12 * * *"}, time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"cron every 5 minutes", Options{Cron: "*/5 * * * *"}, time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"cron next day", Options{Cron: "0 11 * * *"}, time.Date(2023, 1, 2, 11, 0, 0, 0, time.UTC)},
+		{"cron has priority over interval", Options{Cron: "0 16 * * *", Interval: 10 * time.Second},
+			time.Date(2023, 1, 1, 16, 0, 0, 0, time.UTC)},
+		{"invalid cron uses default interval", Options{Cron: "invalid"}, ts.Add(time.Minute)},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, s.next(Request{Name: "s1", URL: "http://example.com", Options: tt.opts}, ts))
+		})
+	}
+}
+
+func TestScheduler_cronSchedule(t *testing.T) {
+	s := NewScheduler(&CheckerMock{}, time.Minute, 1)
+
+	t.Run("no cron expression", func(t *testing.T) {
+		sch, err := s.cronSchedule("")
+		require.NoError(t, err)
+		assert.Nil(t, sch)
+	})
+
+	t.Run("invalid cron expression", func(t *testing.T) {
+		_, err := s.cronSchedule("invalid")
+		require.Error(t, err)
+	})
+
+	t.Run("valid cron expression", func(t *testing.T) {
+		sch, err := s.cronSchedule(" 0 12 * * *")
+		require.NoError(t, err)
+		require.NotNil(t, sch)
+		assert.Equal(t, time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), sch.Next(time.Date(2023, 1, 1, 11, 59, 0, 0, time.UTC)))
+	})
+}
+
+func TestScheduler_ResponseAndCheckNow(t *testing.T) {
+	var count atomic.Int32
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request {
+			return []Request{{Name: "s1", URL: "http://example.com"}, {Name: "s2", URL: "http://example.org"}}
+		},
+		CheckFunc: func(req Request) Response {
+			n := count.Add(1)
+			return Response{Name: req.Name, StatusCode: 200, ResponseTime: int64(n), CheckedAt: time.Now()}
+		},
+	}
+	s := NewScheduler(checker, time.Hour, 2)
+	var saved atomic.Int32
+	s.OnResponse = func(Response) { saved.Add(1) }
+	_, ok := s.Response("s1")
+	assert.False(t, ok, "not checked yet")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	require.Eventually(t, func() bool { return len(s.Status()) == 2 }, time.Second, 5*time.Millisecond)
+
+	resp, ok := s.Response("s1")
+	require.True(t, ok)
+	assert.Equal(t, "s1", resp.Name)
+	assert.LessOrEqual(t, resp.ResponseTime, int64(2), "scheduled check")
+
+	resp, ok, err := s.CheckNow(context.Background(), "s1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), resp.ResponseTime, "checked now")
+	resp, ok = s.Response("s1")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), resp.ResponseTime, "kept as the last response")
+	assert.Equal(t, int32(3), saved.Load(), "scheduled and immediate checks passed to OnResponse")
+	assert.Len(t, s.Status(), 2)
+
+	_, ok, err = s.CheckNow(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok = s.Response("unknown")
+	assert.False(t, ok)
+}
+
+func TestScheduler_CheckNowDeadline(t *testing.T) {
+	release := make(chan struct{})
+	checker := &CheckerMock{
+		RequestsFunc: func() []Request { return []Request{{Name: "s1", URL: "http://example.com"}} },
+		CheckFunc: func(req Request) Response {
+			<-release
+			return Response{Name: req.Name, StatusCode: 200}
+		},
+	}
+	s := NewScheduler(checker, time.Hour, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	require.Eventually(t, func() bool { return len(checker.CheckCalls()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// the only slot is taken by the scheduled check
+	checkCtx, checkCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer checkCancel()
+	_, ok, err := s.CheckNow(checkCtx, "s1")
+	assert.True(t, ok)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, checker.CheckCalls(), 1, "not checked without a slot")
+
+	// the check is started, but not completed in time
+	close(release)
+	require.Eventually(t, func() bool { return len(s.Status()) == 1 }, time.Second, 5*time.Millisecond)
+	release = make(chan struct{})
+	checkCtx, checkCancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer checkCancel()
+	_, ok, err = s.CheckNow(checkCtx, "s1")
+	assert.True(t, ok)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, checker.CheckCalls(), 2)
+	close(release)
+}
@@ -4,42 +4,28 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
-	"sort"
+	"slices"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/go-pkgz/syncs"
-	"github.com/robfig/cron/v3"
 )
 
 //go:generate moq -out provider_mock.go -skip-ensure -fmt goimports . StatusProvider
 
-// Service wraps multiple StatusProvider and multiplex their Status() calls
+// Service wraps multiple StatusProvider and multiplex their Status() calls by url scheme
 type Service struct {
-	requests    []Request
-	concurrency int
-	providers   Providers
+	// Timeout is the default timeout of checks, used for requests without their own timeout. Zero means no default.
+	// The check is limited by request timeout even if the provider doesn't enforce it.
+	Timeout time.Duration
 
-	lastResponses struct {
-		cache map[Request]Response
-		mu    sync.RWMutex
-	}
-	nowFn func() time.Time // for testing
-}
+	registry *Registry
 
-// Providers is a list of StatusProvider
-type Providers struct {
-	HTTP        StatusProvider
-	Mongo       StatusProvider
-	Docker      StatusProvider
-	Program     StatusProvider
-	Nginx       StatusProvider
-	Certificate StatusProvider
-	File        StatusProvider
-	RMQ         StatusProvider
+	mu       sync.RWMutex
+	requests []Request
+	health   map[string]string // health status of the last check by service name, for dependent services
 }
 
 // StatusProvider is an interface for getting status from external services
@@ -49,148 +35,269 @@ type StatusProvider interface {
 
 // Request is a name and request to external service
 type Request struct {
-	Name string
-	URL  string
+	Name    string
+	URL     string  // target of the check, url scheme defines the provider
+	Options Options // set from config, or parsed from url query for command line services
+}
+
+// Options are structured per-service options. Common options used for all services,
+// the rest are used by specific providers only.
+type Options struct {
+	Timeout  time.Duration // timeout of the check, overrides the default timeout of provider
+	Cron     string        // cron expression of check schedule, i.e. "*/5 * * * *"
+	Interval time.Duration // interval between checks, overrides the default interval
+	Retries  int           // number of retries of failed check
+
+	// RetryInterval is a delay before the first retry, doubled for each next retry. 1s if not set
+	RetryInterval time.Duration
+
+	Tags   []string          // tags of the service, i.e. payments, reported in responses and used to select services
+	Labels map[string]string // labels of the service, i.e. team: billing, reported in responses and metrics
+
+	// DependsOn are names of services the service depends on, it is not checked while any of them is failing
+	DependsOn []string
+
+	Headers       map[string]string // http: request headers
+	Actuator      bool              // http: status of actuator compatible json response is the health verdict
+	Containers    []string          // docker: required containers
+	Args          []string          // program: arguments
+	OplogMaxDelta time.Duration     // mongo: max oplog time difference between primary and secondary
+	DB            string            // mongo: database of count query
+	Collection    string            // mongo: collection of count query
+	CountQuery    string            // mongo: count query filter
+	User          string            // rmq: user
+	Password      string            // rmq: password
+	Vhost         string            // rmq: virtual host
+	Queue         string            // rmq: queue name
+}
+
+// key returns a unique key of the request, changed if name, url or any option changed
+func (r Request) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%+v", r.Name, r.URL, r.Options)
+}
+
+// String returns name and url of the request, with password of the url redacted.
+// Options are not included, as headers and passwords of options can be secrets.
+func (r Request) String() string {
+	return r.Name + ":" + redactURL(r.URL)
+}
+
+// redactURL returns the url with password replaced by "xxxxx", as url.URL.Redacted does
+func redactURL(u string) string {
+	if pu, err := url.Parse(u); err == nil {
+		return pu.Redacted()
+	}
+	if passwd := urlPassword(u); passwd != "" {
+		return strings.Replace(u, ":"+passwd+"@", ":xxxxx@", 1)
+	}
+	return u
+}
+
+// urlPassword returns password of the url, empty if not set.
+// Urls with multiple hosts, i.e. mongo replica set, can't be parsed by url.Parse, so userinfo is cut from the url.
+func urlPassword(u string) string {
+	if pu, err := url.Parse(u); err == nil {
+		passwd, _ := pu.User.Password()
+		return passwd
+	}
+	_, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		return ""
+	}
+	userinfo, _, ok := strings.Cut(rest, "@")
+	if !ok || strings.Contains(userinfo, "/") {
+		return ""
+	}
+	_, passwd, _ := strings.Cut(userinfo, ":")
+	return passwd
+}
+
+// redactError returns message of the error with password of the request url redacted. Providers report the request
+// without password, but errors of libraries they use, i.e. url parsing, can include the url.
+func redactError(r Request, err error) string {
+	msg := err.Error()
+	if passwd := urlPassword(r.URL); passwd != "" {
+		msg = strings.ReplaceAll(msg, passwd, "xxxxx")
+		if escaped := url.PathEscape(passwd); escaped != passwd {
+			msg = strings.ReplaceAll(msg, escaped, "xxxxx")
+		}
+	}
+	return msg
+}
+
+// timeout returns timeout of the request if set, def otherwise
+func (r Request) timeout(def time.Duration) time.Duration {
+	if r.Options.Timeout > 0 {
+		return r.Options.Timeout
+	}
+	return def
 }
 
 // Response contains extended service information
 type Response struct {
-	Name         string         `json:"name"`
-	StatusCode   int            `json:"status_code"`
-	ResponseTime int64          `json:"response_time"` // milliseconds
-	Body         map[string]any `json:"body,omitempty"`
-}
-
-// NewService creates new external service supporting multiple providers
-// reqs are requests to external services presented as pairs of name and url, i.e. health:http://localhost:8080/health
-func NewService(providers Providers, concurrency int, reqs ...string) *Service {
-	result := &Service{
-		concurrency: concurrency,
-		providers:   providers,
-		nowFn:       time.Now,
-	}
-	result.lastResponses.cache = make(map[Request]Response)
-
-	for _, r := range reqs {
-		var req Request
-		if i := strings.Index(r, ":"); i > 0 {
-			req.Name = r[:i]
-			req.URL = r[i+1:]
-
-			if req.Name != "" && req.URL != "" {
-				result.requests = append(result.requests, req)
-			}
-			log.Printf("[DEBUG] service: name:%s, url:%s", req.Name, req.URL)
+	Name          string         `json:"name"`
+	StatusCode    int            `json:"status_code"`
+	ResponseTime  int64          `json:"response_time"` // milliseconds
+	Body          map[string]any `json:"body,omitempty"`
+	Health        Health         `json:"health,omitzero"`          // normalized verdict made by provider
+	Error         string         `json:"error,omitempty"`          // error of failed check
+	ErrorCategory string         `json:"error_category,omitempty"` // category of the error, one of Error* constants
+	CheckedAt     time.Time      `json:"checked_at,omitzero"`      // time of the check completion
+	Attempts      int            `json:"attempts,omitempty"`       // number of attempts made, more than 1 if retried
+	Age           int64          `json:"age"`                      // milliseconds since the check, set by Scheduler
+
+	Tags        []string          `json:"tags,omitempty"`        // tags of the request
+	Labels      map[string]string `json:"labels,omitempty"`      // labels of the request
+	Maintenance string            `json:"maintenance,omitempty"` // name of maintenance window active at the check
+}
+
+// health statuses reported by providers, match actuator statuses
+const (
+	HealthUp           = "UP"
+	HealthDown         = "DOWN"
+	HealthWarn         = "WARN"
+	HealthUnknown      = "UNKNOWN"
+	HealthOutOfService = "OUT_OF_SERVICE"
+)
+
+// Health is a normalized health verdict of the check. Providers know how to interpret their own body,
+// i.e. failed required containers or expired certificate, and report it here, so consumers don't need to parse bodies.
+type Health struct {
+	Status string `json:"status"`           // one of Health* statuses
+	Reason string `json:"reason,omitempty"` // explanation for non-UP status
+}
+
+// statusCodeHealth returns UP health for 2xx status codes and DOWN for everything else
+func statusCodeHealth(code int) Health {
+	if code < 200 || code >= 300 {
+		return Health{Status: HealthDown, Reason: fmt.Sprintf("status code %d", code)}
+	}
+	return Health{Status: HealthUp}
+}
+
+// NewService creates new external service supporting all providers of the registry.
+// reqs are requests to external services, made by registry from command line and config.
+func NewService(registry *Registry, reqs ...Request) *Service {
+	return &Service{
+		registry: registry,
+		requests: reqs,
+		health:   map[string]string{},
+	}
+}
+
+// Update replaces the list of requests. Provider state, like deltas of file and nginx checks, is kept.
+func (s *Service) Update(reqs ...Request) {
+	s.mu.Lock()
+	s.requests = reqs
+	maps.DeleteFunc(s.health, func(name string, _ string) bool {
+		return !slices.ContainsFunc(reqs, func(r Request) bool { return r.Name == name })
+	})
+	s.mu.Unlock()
+}
+
+// Requests returns the list of requests to external services
+func (s *Service) Requests() []Request {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Check runs a single attempt of the request with the provider matching request url and returns the response with tags
+// and labels of the request. Failed requests and unsupported protocols reported as responses with 500 status code,
+// error and its category. Failed checks are retried by Scheduler, so the wait between attempts doesn't hold the check.
+// Requests depending on a service failing (DOWN or UNKNOWN) at its last check are not run, and reported as UNKNOWN
+// with 424 status code, so a failure of the service doesn't make all its dependents DOWN.
+func (s *Service) Check(r Request) Response {
+	var resp Response
+	if dep, status, failed := s.failedDependency(r); failed {
+		resp = Response{Name: r.Name, StatusCode: http.StatusFailedDependency, CheckedAt: time.Now(),
+			Health: Health{Status: HealthUnknown, Reason: fmt.Sprintf("dependency %s %s", dep, strings.ToLower(status))}}
+	} else {
+		resp = s.run(r)
+	}
+	resp.Tags, resp.Labels = r.Options.Tags, r.Options.Labels
+
+	s.mu.Lock()
+	if s.health == nil {
+		s.health = map[string]string{}
+	}
+	s.health[r.Name] = resp.Health.Status
+	s.mu.Unlock()
+	return resp
+}
+
+// failedDependency returns the first dependency of the request failing at its last check, with its status.
+// Dependencies not checked yet are not failing.
+func (s *Service) failedDependency(r Request) (name, status string, failed bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, dep := range r.Options.DependsOn {
+		if st := s.health[dep]; st == HealthDown || st == HealthUnknown {
+			return dep, st, true
 		}
 	}
-	return result
-}
-
-// Status returns extended service information, runs concurrently
-func (s *Service) Status() []Response {
-	if len(s.requests) == 0 {
-		return nil
-	}
-	res := make([]Response, 0, len(s.requests))
-	wg := syncs.NewSizedGroup(s.concurrency, syncs.Preemptive)
-	ch := make(chan Response, len(s.requests))
-	for _, req := range s.requests {
-		r := req
-
-		wg.Go(func(context.Context) {
-			cronOk, cronErr := s.cronFilter(r.URL)
-			if cronErr != nil {
-				log.Printf("[WARN] failed to parse cron expression for service %s: %v", r.Name, cronErr)
-			}
-			if !cronOk && cronErr == nil {
-				log.Printf("[DEBUG] skipping service %s, cron expression does not match", r.Name)
-				s.lastResponses.mu.RLock()
-				if lastResp, ok := s.lastResponses.cache[r]; ok {
-					ch <- lastResp // respond with last response if cron expression does not match
-				}
-				s.lastResponses.mu.RUnlock()
-				return
-			}
-
-			var (
-				resp *Response
-				err  error
-			)
-
-			st := time.Now()
-			switch {
-			case strings.HasPrefix(r.URL, "http://") || strings.HasPrefix(r.URL, "https://"):
-				resp, err = s.providers.HTTP.Status(r)
-			case strings.HasPrefix(r.URL, "mongodb://"):
-				resp, err = s.providers.Mongo.Status(r)
-			case strings.HasPrefix(r.URL, "docker://"):
-				resp, err = s.providers.Docker.Status(r)
-			case strings.HasPrefix(r.URL, "program://"):
-				resp, err = s.providers.Program.Status(r)
-			case strings.HasPrefix(r.URL, "nginx://"):
-				resp, err = s.providers.Nginx.Status(r)
-			case strings.HasPrefix(r.URL, "cert://"):
-				resp, err = s.providers.Certificate.Status(r)
-			case strings.HasPrefix(r.URL, "file://"):
-				resp, err = s.providers.File.Status(r)
-			case strings.HasPrefix(r.URL, "rmq://"):
-				resp, err = s.providers.RMQ.Status(r)
-			default:
-				log.Printf("[WARN] unsupported protocol for service, %s %s", r.Name, r.URL)
-				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds()}
-				return
-			}
-
-			if err != nil {
-				log.Printf("[WARN] service request failed: %s %s: %v", r.Name, r.URL, err)
-				ch <- Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds()}
-				return
-			}
-
-			resp.ResponseTime = time.Since(st).Milliseconds()
-			ch <- *resp
-
-			// storing last response to cache, will be used to respond to cron-excluded requests
-			s.lastResponses.mu.Lock()
-			s.lastResponses.cache[r] = *resp
-			s.lastResponses.mu.Unlock()
-
-			log.Printf("[DEBUG] service response: %s:%s %+v", r.Name, r.URL, *resp)
-		})
-	}
-	wg.Wait()
-	close(ch)
-
-	for r := range ch {
-		res = append(res, r)
-	}
-	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
-	return res
-}
-
-// cronFilter checks if the current time matches the cron expression in the URL
-// If no cron expression is set, always return true
-func (s *Service) cronFilter(reqURL string) (bool, error) {
-	parsedURL, err := url.Parse(reqURL)
+	return "", "", false
+}
+
+// run makes a single attempt of the request with the provider
+func (s *Service) run(r Request) Response {
+	provider, ok := s.registry.Lookup(r.URL)
+	if !ok { // requests made by registry always have a provider
+		log.Printf("[WARN] unsupported protocol for service, %s", r)
+		return Response{Name: r.Name, StatusCode: http.StatusInternalServerError,
+			Health: Health{Status: HealthDown, Reason: "unsupported protocol"}, CheckedAt: time.Now(), Attempts: 1,
+			Error: "unsupported protocol", ErrorCategory: ErrorConfig}
+	}
+	resp := s.check(provider, r)
+	resp.Attempts = 1
+	return resp
+}
+
+// check runs a single attempt of the request with the provider
+func (s *Service) check(provider StatusProvider, r Request) Response {
+	st := time.Now()
+	resp, err := s.status(provider, r)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse URL: %w", err)
+		msg := redactError(r, err)
+		log.Printf("[WARN] service request failed: %s: %s", r, msg)
+		return Response{Name: r.Name, StatusCode: http.StatusInternalServerError, ResponseTime: time.Since(st).Milliseconds(),
+			Health: Health{Status: HealthDown, Reason: msg}, CheckedAt: time.Now(),
+			Error: msg, ErrorCategory: ErrorCategory(err)}
 	}
 
-	cronExpr := parsedURL.Query().Get("cron")
-	if cronExpr == "" {
-		return true, nil // no cron expression is set, return true
+	resp.ResponseTime = time.Since(st).Milliseconds()
+	resp.CheckedAt = time.Now()
+	if resp.Health.Status == "" { // provider didn't make a verdict, use status code
+		resp.Health = statusCodeHealth(resp.StatusCode)
 	}
-	cronExpr = strings.TrimSpace(cronExpr)
-	cronExpr = strings.ReplaceAll(cronExpr, "_", " ")
+	log.Printf("[DEBUG] service response: %s %+v", r, *resp)
+	return *resp
+}
 
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
-	if err != nil {
-		return false, err
+// status gets status from the provider, failing if it is not done within request timeout.
+// Providers can't be canceled, so the timed out call completes in background and its result is dropped.
+func (s *Service) status(provider StatusProvider, r Request) (*Response, error) {
+	timeout := r.timeout(s.Timeout)
+	if timeout <= 0 {
+		return provider.Status(r)
 	}
 
-	now := s.nowFn()
-	nextTime := schedule.Next(now)
-	diff := nextTime.Sub(now)
-	return now.Before(nextTime) && diff <= time.Minute, nil
+	type result struct {
+		resp *Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := provider.Status(r)
+		done <- result{resp: resp, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.resp, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("check timed out after %v: %w", timeout, context.DeadlineExceeded)
+	}
 }
@@ -0,0 +1,76 @@
+package status
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/tagset"
+)
+
+// Sections lists json keys of Info which can be selected by Filter
+var Sections = []string{"hostname", "procs", "host_id", "cpu_percent", "cpu_cores", "mem_percent", "uptime",
+	"volumes", "load_average", "services"}
+
+// Filter selects parts of Info to collect, zero Filter selects everything
+type Filter struct {
+	Sections []string // json keys of Info, i.e. volumes or services, all if empty
+	Services []string // glob patterns of service names, see path.Match, all services if empty
+	Tags     []string // tags or "name:value" labels, selects services and volumes with any of them, all if empty
+	NoBodies bool     // strip bodies of service responses
+}
+
+// Validate checks sections are known and service patterns are valid
+func (f Filter) Validate() error {
+	for _, s := range f.Sections {
+		if !slices.Contains(Sections, s) {
+			return fmt.Errorf("unknown section %q, should be one of %s", s, strings.Join(Sections, ", "))
+		}
+	}
+	for _, p := range f.Services {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid service pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// includes returns true if any of sections is selected
+func (f Filter) includes(sections ...string) bool {
+	if len(f.Sections) == 0 {
+		return true
+	}
+	for _, s := range sections {
+		if slices.Contains(f.Sections, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagged returns true if there are no tags in the filter, or any of them is one of tags or labels
+func (f Filter) tagged(tags []string, labels map[string]string) bool {
+	if len(f.Tags) == 0 {
+		return true
+	}
+	joined := tagset.Join(tags, labels)
+	return slices.ContainsFunc(f.Tags, func(t string) bool { return slices.Contains(joined, t) })
+}
+
+// service returns true if the service response is selected, by name and tags
+func (f Filter) service(r external.Response) bool {
+	if !f.tagged(r.Tags, r.Labels) {
+		return false
+	}
+	if len(f.Services) == 0 {
+		return true
+	}
+	for _, p := range f.Services {
+		if ok, err := path.Match(p, r.Name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
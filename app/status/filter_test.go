@@ -0,0 +1,59 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestFilter_service(t *testing.T) {
+	pay := external.Response{Name: "pay", Tags: []string{"payments"}, Labels: map[string]string{"team": "billing"}}
+	docker := external.Response{Name: "docker"}
+
+	tbl := []struct {
+		name   string
+		filter Filter
+		pay    bool
+		docker bool
+	}{
+		{name: "empty", filter: Filter{}, pay: true, docker: true},
+		{name: "by tag", filter: Filter{Tags: []string{"payments"}}, pay: true},
+		{name: "by label", filter: Filter{Tags: []string{"blah", "team:billing"}}, pay: true},
+		{name: "by name", filter: Filter{Services: []string{"dock*"}}, docker: true},
+		{name: "by name and tag", filter: Filter{Services: []string{"dock*"}, Tags: []string{"payments"}}},
+		{name: "unknown tag", filter: Filter{Tags: []string{"team"}}},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.pay, tt.filter.service(pay))
+			assert.Equal(t, tt.docker, tt.filter.service(docker))
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	tbl := []struct {
+		name   string
+		filter Filter
+		err    string
+	}{
+		{name: "empty", filter: Filter{}},
+		{name: "valid", filter: Filter{Sections: []string{"volumes", "services"}, Services: []string{"docker*", "mongo"}}},
+		{name: "unknown section", filter: Filter{Sections: []string{"volumes", "blah"}}, err: `unknown section "blah"`},
+		{name: "bad pattern", filter: Filter{Services: []string{"[docker"}}, err: `invalid service pattern "[docker"`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package status
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+//go:generate moq -out evaluator_mock.go -skip-ensure -fmt goimports . Evaluator
+//go:generate moq -out storage_mock.go -skip-ensure -fmt goimports . Storage
+
+// History keeps a bounded ring of past results per service and per system metric.
+// It is used to report state transitions, time spent in each state and flapping.
+type History struct {
+	size        int // max number of records kept for each name
+	flapWindow  int // number of last records checked for flapping
+	flapChanges int // number of state changes within flapWindow making the component flapping
+
+	mu    sync.RWMutex
+	rings map[string]*ring
+}
+
+// Record is a single result kept in history
+type Record struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`           // actuator status, i.e. UP, WARN or DOWN
+	Value  float64   `json:"value"`            // percent for cpu, memory and disk, load per core, response time for services
+	Reason string    `json:"reason,omitempty"` // explanation of non-UP status
+}
+
+// Transition is a change of state between two consecutive records
+type Transition struct {
+	Time time.Time `json:"time"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+// Report is the history of a single service or metric with computed transitions and time in each state
+type Report struct {
+	Name        string           `json:"name"`
+	Status      string           `json:"status"`        // status of the last record
+	Since       time.Time        `json:"since"`         // time of the last transition, or the first record if no transitions
+	Flapping    bool             `json:"flapping"`      // state changed too often recently
+	TimeInState map[string]int64 `json:"time_in_state"` // milliseconds spent in each state within the history
+	Transitions []Transition     `json:"transitions"`
+	Records     []Record         `json:"records"`
+}
+
+// NewHistory makes history keeping up to size records per name. A name is flapping if its state changed
+// at least flapChanges times within the last flapWindow records, zero flapChanges disables flapping detection.
+func NewHistory(size, flapWindow, flapChanges int) *History {
+	return &History{size: max(size, 1), flapWindow: flapWindow, flapChanges: flapChanges, rings: map[string]*ring{}}
+}
+
+// Add appends the record to the history of the name. Records not newer than the last record of the name are ignored,
+// as the same service check can be reported multiple times. Returns true if the record was added.
+func (h *History) Add(name string, rec Record) bool {
+	if rec.Time.IsZero() {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rings[name]
+	if !ok {
+		r = &ring{buf: make([]Record, 0, h.size)}
+		h.rings[name] = r
+	}
+	if last, ok := r.last(); ok && !rec.Time.After(last.Time) {
+		return false
+	}
+	r.push(rec)
+	return true
+}
+
+// Report returns history of the name, with time in the current state counted up to now.
+// Returns false if there is no history for the name.
+func (h *History) Report(name string, now time.Time) (Report, bool) {
+	h.mu.RLock()
+	r, ok := h.rings[name]
+	var recs []Record
+	if ok {
+		recs = r.records()
+	}
+	h.mu.RUnlock()
+	if !ok || len(recs) == 0 {
+		return Report{}, false
+	}
+
+	res := Report{Name: name, Records: recs, TimeInState: map[string]int64{}, Transitions: []Transition{},
+		Status: recs[len(recs)-1].Status, Since: recs[0].Time}
+	for i, rec := range recs {
+		end := now
+		if i < len(recs)-1 {
+			end = recs[i+1].Time
+		}
+		res.TimeInState[rec.Status] += max(end.Sub(rec.Time), 0).Milliseconds()
+		if i > 0 && recs[i-1].Status != rec.Status {
+			res.Transitions = append(res.Transitions, Transition{Time: rec.Time, From: recs[i-1].Status, To: rec.Status})
+			res.Since = rec.Time
+		}
+	}
+	res.Flapping = h.flapping(recs)
+	return res, true
+}
+
+// Flapping returns names with state changed too often recently
+func (h *History) Flapping() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	res := map[string]bool{}
+	for name, r := range h.rings {
+		if h.flapping(r.records()) {
+			res[name] = true
+		}
+	}
+	return res
+}
+
+// flapping checks if the number of state changes within the last flapWindow records reached flapChanges
+func (h *History) flapping(recs []Record) bool {
+	if h.flapChanges <= 0 {
+		return false
+	}
+	if len(recs) > h.flapWindow {
+		recs = recs[len(recs)-h.flapWindow:]
+	}
+	changes := 0
+	for i := 1; i < len(recs); i++ {
+		if recs[i].Status != recs[i-1].Status {
+			changes++
+		}
+	}
+	return changes >= h.flapChanges
+}
+
+// ring is a fixed size buffer of records, overwriting the oldest record when full
+type ring struct {
+	buf  []Record
+	next int // position of the next record when the buffer is full
+}
+
+func (r *ring) push(rec Record) {
+	if len(r.buf) < cap(r.buf) {
+		r.buf = append(r.buf, rec)
+		return
+	}
+	r.buf[r.next] = rec
+	r.next = (r.next + 1) % len(r.buf)
+}
+
+// records returns a copy of records, from the oldest to the newest
+func (r *ring) records() []Record {
+	res := make([]Record, 0, len(r.buf))
+	res = append(res, r.buf[r.next:]...)
+	return append(res, r.buf[:r.next]...)
+}
+
+func (r *ring) last() (Record, bool) {
+	if len(r.buf) == 0 {
+		return Record{}, false
+	}
+	return r.buf[(r.next+len(r.buf)-1)%len(r.buf)], true
+}
+
+// Evaluator makes history records of all health components from the status info,
+// with services recorded at the time of their last check
+type Evaluator interface {
+	Evaluate(info *Info) map[string]Record
+}
+
+// Storage persists status info samples and service responses, implemented by store.Store
+type Storage interface {
+	Save(info *Info, now time.Time) error
+	SaveResponse(resp external.Response) error
+}
+
+// Recorder periodically gets status info and records it to the history, and samples of it to the storage if set.
+// Service responses are saved to the storage by SaveResponse, on each check.
+type Recorder struct {
+	Status    *Service
+	Evaluator Evaluator
+	History   *History
+	Storage   Storage // optional
+	Interval  time.Duration
+}
+
+// Run records status each interval, until context is canceled.
+// Service checks run by the scheduler are recorded once each, at the time of the check.
+func (r *Recorder) Run(ctx context.Context) {
+	log.Printf("[INFO] start history recorder, interval %v", r.Interval)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.record()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Recorder) record() {
+	info, err := r.Status.Get()
+	if err != nil {
+		log.Printf("[WARN] can't get status for history, %v", err)
+		return
+	}
+	for name, rec := range r.Evaluator.Evaluate(info) {
+		r.History.Add(name, rec)
+	}
+	if r.Storage != nil {
+		if err := r.Storage.Save(info, time.Now()); err != nil {
+			log.Printf("[WARN] can't save status, %v", err)
+		}
+	}
+}
+
+// SaveResponse saves response of the service check to the storage, with maintenance window active at the check.
+// Called by the scheduler on each completed check, so all checks are saved, including ones made more often than Interval.
+func (r *Recorder) SaveResponse(resp external.Response) {
+	if r.Storage == nil {
+		return
+	}
+	resp.Maintenance = r.Status.maintenance("service:"+resp.Name, resp.Tags, resp.Labels, resp.CheckedAt)
+	if err := r.Storage.SaveResponse(resp); err != nil {
+		log.Printf("[WARN] can't save response of %s, %v", resp.Name, err)
+	}
+}
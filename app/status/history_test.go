@@ -0,0 +1,165 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestHistory_Add(t *testing.T) {
+	h := NewHistory(3, 10, 4)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	for i := range 5 {
+		assert.True(t, h.Add("cpu", Record{Time: ts.Add(time.Duration(i) * time.Second), Status: "UP", Value: float64(i)}))
+	}
+	assert.False(t, h.Add("cpu", Record{Time: ts.Add(4 * time.Second), Status: "DOWN"}), "same time, already recorded")
+	assert.False(t, h.Add("cpu", Record{Time: ts, Status: "DOWN"}), "older record")
+	assert.False(t, h.Add("cpu", Record{Status: "DOWN"}), "no time, i.e. service not checked yet")
+	assert.True(t, h.Add("service:s1", Record{Time: ts, Status: "UP"}))
+
+	rep, ok := h.Report("cpu", ts.Add(5*time.Second))
+	require.True(t, ok)
+	require.Len(t, rep.Records, 3, "only last 3 records kept")
+	assert.InDelta(t, 2, rep.Records[0].Value, 0.001)
+	assert.InDelta(t, 3, rep.Records[1].Value, 0.001)
+	assert.InDelta(t, 4, rep.Records[2].Value, 0.001)
+
+	rep, ok = h.Report("service:s1", ts)
+	require.True(t, ok)
+	assert.Len(t, rep.Records, 1)
+	_, ok = h.Report("memory", ts)
+	assert.False(t, ok)
+}
+
+func TestHistory_Report(t *testing.T) {
+	h := NewHistory(100, 10, 4)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	h.Add("s1", Record{Time: ts, Status: "UP"})
+	h.Add("s1", Record{Time: ts.Add(10 * time.Second), Status: "UP"})
+	h.Add("s1", Record{Time: ts.Add(20 * time.Second), Status: "DOWN", Reason: "status code 500"})
+	h.Add("s1", Record{Time: ts.Add(30 * time.Second), Status: "DOWN", Reason: "status code 500"})
+	h.Add("s1", Record{Time: ts.Add(40 * time.Second), Status: "WARN"})
+
+	rep, ok := h.Report("s1", ts.Add(45*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, "s1", rep.Name)
+	assert.Equal(t, "WARN", rep.Status)
+	assert.Equal(t, ts.Add(40*time.Second), rep.Since)
+	assert.False(t, rep.Flapping)
+	assert.Equal(t, []Transition{
+		{Time: ts.Add(20 * time.Second), From: "UP", To: "DOWN"},
+		{Time: ts.Add(40 * time.Second), From: "DOWN", To: "WARN"},
+	}, rep.Transitions)
+	assert.Equal(t, map[string]int64{"UP": 20000, "DOWN": 20000, "WARN": 5000}, rep.TimeInState)
+	assert.Len(t, rep.Records, 5)
+
+	h.Add("s2", Record{Time: ts, Status: "UP"})
+	rep, ok = h.Report("s2", ts.Add(time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, ts, rep.Since, "no transitions, since the first record")
+	assert.Empty(t, rep.Transitions)
+	assert.Equal(t, map[string]int64{"UP": 60000}, rep.TimeInState)
+}
+
+func TestHistory_Flapping(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	tbl := []struct {
+		name     string
+		statuses []string
+		changes  int
+		res      bool
+	}{
+		{"stable", []string{"UP", "UP", "UP", "UP", "UP"}, 4, false},
+		{"single failure", []string{"UP", "UP", "DOWN", "DOWN", "DOWN", "DOWN"}, 4, false},
+		{"toggling", []string{"UP", "DOWN", "UP", "DOWN", "UP"}, 4, true},
+		{"toggling outside of window", []string{"UP", "DOWN", "UP", "DOWN", "UP", "UP", "UP", "UP", "UP", "UP", "UP", "UP"}, 4, false},
+		{"disabled", []string{"UP", "DOWN", "UP", "DOWN", "UP"}, 0, false},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHistory(100, 6, tt.changes)
+			for i, st := range tt.statuses {
+				h.Add("s1", Record{Time: ts.Add(time.Duration(i) * time.Second), Status: st})
+			}
+			h.Add("s2", Record{Time: ts, Status: "UP"})
+			rep, ok := h.Report("s1", ts.Add(time.Minute))
+			require.True(t, ok)
+			assert.Equal(t, tt.res, rep.Flapping)
+			assert.Equal(t, tt.res, h.Flapping()["s1"])
+			assert.False(t, h.Flapping()["s2"])
+		})
+	}
+}
+
+func TestRecorder_Run(t *testing.T) {
+	checkedAt := time.Now()
+	ex := &ExtServicesMock{StatusFunc: func() []external.Response {
+		return []external.Response{{Name: "s1", StatusCode: 200, CheckedAt: checkedAt}}
+	}}
+	ev := &EvaluatorMock{EvaluateFunc: func(info *Info) map[string]Record {
+		res := map[string]Record{"cpu": {Time: time.Now(), Status: "UP", Value: float64(info.CPUPercent)}}
+		for name, svc := range info.ExtServices {
+			res["service:"+name] = Record{Time: svc.CheckedAt, Status: "UP"}
+		}
+		return res
+	}}
+	h := NewHistory(100, 10, 4)
+	rec := Recorder{Status: &Service{ExtServices: ex}, Evaluator: ev, History: h, Interval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	rec.Run(ctx)
+
+	calls := len(ev.EvaluateCalls())
+	assert.GreaterOrEqual(t, calls, 3)
+	cpu, ok := h.Report("cpu", time.Now())
+	require.True(t, ok)
+	assert.Len(t, cpu.Records, calls, "cpu recorded on each run")
+	svc, ok := h.Report("service:s1", time.Now())
+	require.True(t, ok)
+	assert.Len(t, svc.Records, 1, fmt.Sprintf("service check recorded once, %+v", svc.Records))
+}
+
+func TestRecorder_RunWithStorage(t *testing.T) {
+	ex := &ExtServicesMock{StatusFunc: func() []external.Response { return nil }}
+	ev := &EvaluatorMock{EvaluateFunc: func(info *Info) map[string]Record { return nil }}
+	st := &StorageMock{SaveFunc: func(info *Info, now time.Time) error { return errors.New("failed") }}
+	rec := Recorder{Status: &Service{ExtServices: ex}, Evaluator: ev, History: NewHistory(100, 10, 4), Storage: st,
+		Interval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	rec.Run(ctx)
+	assert.Len(t, st.SaveCalls(), len(ev.EvaluateCalls()), "saved on each run, errors don't stop recording")
+}
+
+func TestRecorder_SaveResponse(t *testing.T) {
+	checkedAt := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	maint := &MaintenanceMock{ActiveFunc: func(component string, tags []string, at time.Time) (string, bool) {
+		if component == "service:mongo" && at.Equal(checkedAt) {
+			return "upgrade", true
+		}
+		return "", false
+	}}
+	st := &StorageMock{SaveResponseFunc: func(resp external.Response) error { return nil }}
+	rec := Recorder{Status: &Service{Maintenance: maint}, Storage: st}
+
+	rec.SaveResponse(external.Response{Name: "mongo", StatusCode: 500, CheckedAt: checkedAt})
+	rec.SaveResponse(external.Response{Name: "web", StatusCode: 200, CheckedAt: checkedAt})
+	require.Len(t, st.SaveResponseCalls(), 2)
+	assert.Equal(t, "upgrade", st.SaveResponseCalls()[0].Resp.Maintenance, "maintenance at the time of the check")
+	assert.Empty(t, st.SaveResponseCalls()[1].Resp.Maintenance)
+
+	rec.Storage = nil
+	rec.SaveResponse(external.Response{Name: "web", StatusCode: 200, CheckedAt: checkedAt}) // no storage, ignored
+}
@@ -0,0 +1,83 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package status
+
+import (
+	"sync"
+	"time"
+)
+
+// MaintenanceMock is a mock implementation of Maintenance.
+//
+//	func TestSomethingThatUsesMaintenance(t *testing.T) {
+//
+//		// make and configure a mocked Maintenance
+//		mockedMaintenance := &MaintenanceMock{
+//			ActiveFunc: func(component string, tags []string, now time.Time) (string, bool) {
+//				panic("mock out the Active method")
+//			},
+//		}
+//
+//		// use mockedMaintenance in code that requires Maintenance
+//		// and then make assertions.
+//
+//	}
+type MaintenanceMock struct {
+	// ActiveFunc mocks the Active method.
+	ActiveFunc func(component string, tags []string, now time.Time) (string, bool)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Active holds details about calls to the Active method.
+		Active []struct {
+			// Component is the component argument value.
+			Component string
+			// Tags is the tags argument value.
+			Tags []string
+			// Now is the now argument value.
+			Now time.Time
+		}
+	}
+	lockActive sync.RWMutex
+}
+
+// Active calls ActiveFunc.
+func (mock *MaintenanceMock) Active(component string, tags []string, now time.Time) (string, bool) {
+	if mock.ActiveFunc == nil {
+		panic("MaintenanceMock.ActiveFunc: method is nil but Maintenance.Active was just called")
+	}
+	callInfo := struct {
+		Component string
+		Tags      []string
+		Now       time.Time
+	}{
+		Component: component,
+		Tags:      tags,
+		Now:       now,
+	}
+	mock.lockActive.Lock()
+	mock.calls.Active = append(mock.calls.Active, callInfo)
+	mock.lockActive.Unlock()
+	return mock.ActiveFunc(component, tags, now)
+}
+
+// ActiveCalls gets all the calls that were made to Active.
+// Check the length with:
+//
+//	len(mockedMaintenance.ActiveCalls())
+func (mock *MaintenanceMock) ActiveCalls() []struct {
+	Component string
+	Tags      []string
+	Now       time.Time
+} {
+	var calls []struct {
+		Component string
+		Tags      []string
+		Now       time.Time
+	}
+	mock.lockActive.RLock()
+	calls = mock.calls.Active
+	mock.lockActive.RUnlock()
+	return calls
+}
@@ -3,6 +3,8 @@ package status
 import (
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
@@ -11,14 +13,19 @@ import (
 	"github.com/shirou/gopsutil/v3/mem"
 
 	"github.com/umputun/sys-agent/app/status/external"
+	"github.com/umputun/sys-agent/app/tagset"
 )
 
 //go:generate moq -out ext_mock.go -skip-ensure -fmt goimports . ExtServices
+//go:generate moq -out maintenance_mock.go -skip-ensure -fmt goimports . Maintenance
 
 // Service provides disk and cpu utilization
 type Service struct {
 	Volumes     []Volume
 	ExtServices ExtServices
+	Maintenance Maintenance // annotates volumes and services in maintenance, optional
+
+	mu sync.RWMutex // protects Volumes on update
 }
 
 // ExtServices declares interface to get status of all external services
@@ -26,12 +33,18 @@ type ExtServices interface {
 	Status() []external.Response
 }
 
+// Maintenance returns the name of active maintenance window of the actuator component, implemented by maintenance.Schedule
+type Maintenance interface {
+	Active(component string, tags []string, now time.Time) (string, bool)
+}
+
 // Info contains disk and cpu utilization results
 type Info struct {
 	HostName   string            `json:"hostname"`
 	Procs      int               `json:"procs"`
 	HostID     string            `json:"host_id"`
 	CPUPercent int               `json:"cpu_percent"`
+	CPUCores   int               `json:"cpu_cores"`
 	MemPercent int               `json:"mem_percent"`
 	Uptime     uint64            `json:"uptime"`
 	Volumes    map[string]Volume `json:"volumes,omitempty"`
@@ -48,56 +61,107 @@ type Volume struct {
 	Name         string `json:"name"`
 	Path         string `json:"path"`
 	UsagePercent int    `json:"usage_percent"`
+	Total        uint64 `json:"total,omitempty"` // bytes
+	Free         uint64 `json:"free,omitempty"`  // bytes available to unprivileged user
+
+	Tags        []string          `json:"tags,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Maintenance string            `json:"maintenance,omitempty"` // name of active maintenance window
+}
+
+// UpdateVolumes replaces the list of volumes to report
+func (s *Service) UpdateVolumes(vols []Volume) {
+	s.mu.Lock()
+	s.Volumes = vols
+	s.mu.Unlock()
 }
 
 // Get returns the disk and cpu utilization
-func (s Service) Get() (*Info, error) {
-	cpup, err := cpu.Percent(0, false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cpu percent: %w", err)
-	}
+func (s *Service) Get() (*Info, error) {
+	return s.GetFiltered(Filter{})
+}
 
-	memp, err := mem.VirtualMemory()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get memory percent: %w", err)
+// GetFiltered returns the parts of status selected by the filter. Unselected parts are not collected
+// and left empty, services not matching the filter are skipped.
+func (s *Service) GetFiltered(f Filter) (*Info, error) {
+	res := Info{}
+	if f.includes("cpu_percent") {
+		cpup, err := cpu.Percent(0, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cpu percent: %w", err)
+		}
+		res.CPUPercent = int(cpup[0])
 	}
 
-	hostStat, err := host.Info()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get host info: %w", err)
+	if f.includes("cpu_cores") {
+		cores, err := cpu.Counts(true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cpu count: %w", err)
+		}
+		res.CPUCores = cores
 	}
 
-	loads, err := load.Avg()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get load average: %w", err)
+	if f.includes("mem_percent") {
+		memp, err := mem.VirtualMemory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get memory percent: %w", err)
+		}
+		res.MemPercent = int(memp.UsedPercent)
 	}
 
-	res := Info{
-		HostName:   hostStat.Hostname,
-		Procs:      int(hostStat.Procs), //nolint: gosec // overflow is not possible
-		HostID:     hostStat.HostID,
-		CPUPercent: int(cpup[0]),
-		MemPercent: int(memp.UsedPercent),
-		Volumes:    map[string]Volume{},
-		Uptime:     hostStat.Uptime,
+	if f.includes("hostname", "procs", "host_id", "uptime") {
+		hostStat, err := host.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get host info: %w", err)
+		}
+		res.HostName, res.HostID, res.Uptime = hostStat.Hostname, hostStat.HostID, hostStat.Uptime
+		res.Procs = int(hostStat.Procs) //nolint: gosec // overflow is not possible
 	}
-	res.Loads.One, res.Loads.Five, res.Loads.Fifteen = loads.Load1, loads.Load5, loads.Load15
 
-	for _, v := range s.Volumes {
-		usage, err := disk.Usage(v.Path)
+	if f.includes("load_average") {
+		loads, err := load.Avg()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get disk usage for %s: %w", v.Path, err)
+			return nil, fmt.Errorf("failed to get load average: %w", err)
 		}
-		res.Volumes[v.Name] = Volume{
-			Name:         v.Name,
-			Path:         v.Path,
-			UsagePercent: int(usage.UsedPercent),
+		res.Loads.One, res.Loads.Five, res.Loads.Fifteen = loads.Load1, loads.Load5, loads.Load15
+	}
+
+	if f.includes("volumes") {
+		res.Volumes = map[string]Volume{}
+		s.mu.RLock()
+		vols := s.Volumes
+		s.mu.RUnlock()
+		for _, v := range vols {
+			if !f.tagged(v.Tags, v.Labels) {
+				continue
+			}
+			usage, err := disk.Usage(v.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get disk usage for %s: %w", v.Path, err)
+			}
+			res.Volumes[v.Name] = Volume{
+				Name:         v.Name,
+				Path:         v.Path,
+				UsagePercent: int(usage.UsedPercent),
+				Total:        usage.Total,
+				Free:         usage.Free,
+				Tags:         v.Tags,
+				Labels:       v.Labels,
+				Maintenance:  s.maintenance("diskSpace:"+v.Name, v.Tags, v.Labels, time.Now()),
+			}
 		}
 	}
 
-	if s.ExtServices != nil {
+	if s.ExtServices != nil && f.includes("services") {
 		res.ExtServices = map[string]external.Response{}
 		for _, v := range s.ExtServices.Status() {
+			if !f.service(v) {
+				continue
+			}
+			if f.NoBodies {
+				v.Body = nil
+			}
+			v.Maintenance = s.maintenance("service:"+v.Name, v.Tags, v.Labels, v.CheckedAt)
 			res.ExtServices[v.Name] = v
 		}
 	}
@@ -105,3 +169,13 @@ func (s Service) Get() (*Info, error) {
 	log.Printf("[DEBUG] status: %+v", res)
 	return &res, nil
 }
+
+// maintenance returns the name of maintenance window of the actuator component active at the time, if any.
+// Services are checked against the time of their check, so the window is kept in the history of responses.
+func (s *Service) maintenance(component string, tags []string, labels map[string]string, at time.Time) string {
+	if s.Maintenance == nil || at.IsZero() {
+		return ""
+	}
+	w, _ := s.Maintenance.Active(component, tagset.Join(tags, labels), at)
+	return w
+}
@@ -1,7 +1,10 @@
 package status
 
 import (
+	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,13 +43,83 @@ func TestService_Get(t *testing.T) {
 	assert.Equal(t, "root", res.Volumes["root"].Name)
 	assert.Equal(t, "/", res.Volumes["root"].Path)
 	assert.Positive(t, res.Volumes["root"].UsagePercent)
+	assert.Positive(t, res.Volumes["root"].Total)
+	assert.Positive(t, res.Volumes["root"].Free)
 	assert.Positive(t, res.MemPercent)
+	assert.Positive(t, res.CPUCores)
 	assert.Positive(t, res.Loads.One)
 	assert.Positive(t, res.Uptime)
 
 	assert.Len(t, res.ExtServices, 2)
 }
 
+func TestService_GetFiltered(t *testing.T) {
+	ex := &ExtServicesMock{StatusFunc: func() []external.Response {
+		return []external.Response{
+			{Name: "docker", StatusCode: 200, Body: map[string]any{"containers": 5}},
+			{Name: "docker-dev", StatusCode: 200, Body: map[string]any{"containers": 2}},
+			{Name: "mongo", StatusCode: 200, Body: map[string]any{"status": "ok"}},
+		}
+	}}
+	svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}, ExtServices: ex}
+
+	res, err := svc.GetFiltered(Filter{Sections: []string{"services"}, Services: []string{"docker*"}, NoBodies: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]external.Response{"docker": {Name: "docker", StatusCode: 200},
+		"docker-dev": {Name: "docker-dev", StatusCode: 200}}, res.ExtServices)
+	assert.Nil(t, res.Volumes, "volumes not collected")
+	assert.Zero(t, res.CPUCores, "cpu cores not collected")
+	assert.Zero(t, res.Uptime, "host not collected")
+
+	res, err = svc.GetFiltered(Filter{Sections: []string{"volumes", "cpu_cores"}})
+	require.NoError(t, err)
+	assert.Len(t, res.Volumes, 1)
+	assert.Positive(t, res.CPUCores)
+	assert.Nil(t, res.ExtServices)
+	assert.Len(t, ex.StatusCalls(), 1, "services not requested")
+
+	res, err = svc.GetFiltered(Filter{Services: []string{"mongo"}})
+	require.NoError(t, err)
+	assert.Len(t, res.Volumes, 1)
+	assert.Positive(t, res.Uptime)
+	require.Len(t, res.ExtServices, 1)
+	assert.Equal(t, map[string]any{"status": "ok"}, res.ExtServices["mongo"].Body)
+}
+
+func TestService_GetMaintenance(t *testing.T) {
+	checkedAt := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	ex := &ExtServicesMock{StatusFunc: func() []external.Response {
+		return []external.Response{
+			{Name: "mongo", StatusCode: 500, Tags: []string{"db"}, CheckedAt: checkedAt},
+			{Name: "web", StatusCode: 200, CheckedAt: checkedAt},
+			{Name: "new", Tags: []string{"db"}}, // not checked yet
+		}
+	}}
+	maint := &MaintenanceMock{ActiveFunc: func(component string, tags []string, _ time.Time) (string, bool) {
+		if component == "diskSpace:root" || slices.Contains(tags, "db") {
+			return "upgrade", true
+		}
+		return "", false
+	}}
+	svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}, ExtServices: ex, Maintenance: maint}
+
+	res, err := svc.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "upgrade", res.Volumes["root"].Maintenance)
+	assert.Equal(t, "upgrade", res.ExtServices["mongo"].Maintenance)
+	assert.Equal(t, 500, res.ExtServices["mongo"].StatusCode, "raw result is kept")
+	assert.Empty(t, res.ExtServices["web"].Maintenance)
+	assert.Empty(t, res.ExtServices["new"].Maintenance)
+
+	calls := maint.ActiveCalls()
+	require.Len(t, calls, 3, "volume and checked services")
+	for _, c := range calls {
+		if c.Component == "service:mongo" {
+			assert.Equal(t, checkedAt, c.Now, "services checked at the time of check")
+		}
+	}
+}
+
 func TestService_GetNoExt(t *testing.T) {
 
 	svc := Service{
@@ -66,3 +139,13 @@ func TestService_GetNoExt(t *testing.T) {
 
 	assert.Empty(t, res.ExtServices)
 }
+
+func TestService_UpdateVolumes(t *testing.T) {
+	svc := Service{Volumes: []Volume{{Name: "root", Path: "/"}}}
+	svc.UpdateVolumes([]Volume{{Name: "tmp", Path: os.TempDir()}})
+
+	res, err := svc.Get()
+	require.NoError(t, err)
+	assert.Len(t, res.Volumes, 1)
+	assert.Equal(t, os.TempDir(), res.Volumes["tmp"].Path)
+}
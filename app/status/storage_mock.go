@@ -0,0 +1,123 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package status
+
+import (
+	"sync"
+	"time"
+
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+// StorageMock is a mock implementation of Storage.
+//
+//	func TestSomethingThatUsesStorage(t *testing.T) {
+//
+//		// make and configure a mocked Storage
+//		mockedStorage := &StorageMock{
+//			SaveFunc: func(info *Info, now time.Time) error {
+//				panic("mock out the Save method")
+//			},
+//			SaveResponseFunc: func(resp external.Response) error {
+//				panic("mock out the SaveResponse method")
+//			},
+//		}
+//
+//		// use mockedStorage in code that requires Storage
+//		// and then make assertions.
+//
+//	}
+type StorageMock struct {
+	// SaveFunc mocks the Save method.
+	SaveFunc func(info *Info, now time.Time) error
+
+	// SaveResponseFunc mocks the SaveResponse method.
+	SaveResponseFunc func(resp external.Response) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Save holds details about calls to the Save method.
+		Save []struct {
+			// Info is the info argument value.
+			Info *Info
+			// Now is the now argument value.
+			Now time.Time
+		}
+		// SaveResponse holds details about calls to the SaveResponse method.
+		SaveResponse []struct {
+			// Resp is the resp argument value.
+			Resp external.Response
+		}
+	}
+	lockSave         sync.RWMutex
+	lockSaveResponse sync.RWMutex
+}
+
+// Save calls SaveFunc.
+func (mock *StorageMock) Save(info *Info, now time.Time) error {
+	if mock.SaveFunc == nil {
+		panic("StorageMock.SaveFunc: method is nil but Storage.Save was just called")
+	}
+	callInfo := struct {
+		Info *Info
+		Now  time.Time
+	}{
+		Info: info,
+		Now:  now,
+	}
+	mock.lockSave.Lock()
+	mock.calls.Save = append(mock.calls.Save, callInfo)
+	mock.lockSave.Unlock()
+	return mock.SaveFunc(info, now)
+}
+
+// SaveCalls gets all the calls that were made to Save.
+// Check the length with:
+//
+//	len(mockedStorage.SaveCalls())
+func (mock *StorageMock) SaveCalls() []struct {
+	Info *Info
+	Now  time.Time
+} {
+	var calls []struct {
+		Info *Info
+		Now  time.Time
+	}
+	mock.lockSave.RLock()
+	calls = mock.calls.Save
+	mock.lockSave.RUnlock()
+	return calls
+}
+
+// SaveResponse calls SaveResponseFunc.
+func (mock *StorageMock) SaveResponse(resp external.Response) error {
+	if mock.SaveResponseFunc == nil {
+		panic("StorageMock.SaveResponseFunc: method is nil but Storage.SaveResponse was just called")
+	}
+	callInfo := struct {
+		Resp external.Response
+	}{
+		Resp: resp,
+	}
+	mock.lockSaveResponse.Lock()
+	mock.calls.SaveResponse = append(mock.calls.SaveResponse, callInfo)
+	mock.lockSaveResponse.Unlock()
+	return mock.SaveResponseFunc(resp)
+}
+
+// SaveResponseCalls gets all the calls that were made to SaveResponse.
+// Check the length with:
+//
+//	len(mockedStorage.SaveResponseCalls())
+func (mock *StorageMock) SaveResponseCalls() []struct {
+	Resp external.Response
+} {
+	var calls []struct {
+		Resp external.Response
+	}
+	mock.lockSaveResponse.RLock()
+	calls = mock.calls.SaveResponse
+	mock.lockSaveResponse.RUnlock()
+	return calls
+}
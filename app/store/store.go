@@ -0,0 +1,271 @@
+// Package store keeps history of service responses and status samples in an embedded on-disk db,
+// and reports uptime and response time statistics (SLA) of services.
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+// ErrNotFound is returned if there are no records for the requested service and period
+var ErrNotFound = errors.New("no records found")
+
+// buckets of the db. Services bucket has a nested bucket per service, all keyed by time of the record
+var (
+	servicesBucket = []byte("services")
+	statusBucket   = []byte("status")
+)
+
+// Store keeps service responses and status info samples in a bolt db file, records older than retention are removed
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+// SLA is an availability report of a service for the period
+type SLA struct {
+	Name         string      `json:"name"`
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	Checks       int         `json:"checks"`        // number of checks recorded in the period
+	Uptime       float64     `json:"uptime"`        // percentage of time the service was not DOWN
+	Outages      int         `json:"outages"`       // number of times the service went DOWN
+	MTTR         int64       `json:"mttr"`          // mean time to recovery from outages, milliseconds
+	ResponseTime Percentiles `json:"response_time"` // response time percentiles, milliseconds
+	Maintenance  int64       `json:"maintenance"`   // time excluded as maintenance, milliseconds
+}
+
+// Percentiles of response time, milliseconds
+type Percentiles struct {
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+	P99 int64 `json:"p99"`
+}
+
+// New opens or creates the db file. Zero retention keeps records forever.
+func New(fname string, retention time.Duration) (*Store, error) {
+	db, err := bolt.Open(fname, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", fname, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{servicesBucket, statusBucket} {
+			if _, e := tx.CreateBucketIfNotExists(b); e != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", b, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Close closes the db
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save records status info sample, without services, at the given time. Implements status.Storage.
+func (s *Store) Save(info *status.Info, now time.Time) error {
+	sample := *info
+	sample.ExtServices = nil
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err = tx.Bucket(statusBucket).Put(timeKey(now), data); err != nil {
+			return fmt.Errorf("failed to save status: %w", err)
+		}
+		return nil
+	})
+}
+
+// SaveResponse records response of the service at the time of its check, without body.
+// The same check saved multiple times is kept once. Responses not checked yet are ignored. Implements status.Storage.
+func (s *Store) SaveResponse(resp external.Response) error {
+	if resp.CheckedAt.IsZero() {
+		return nil
+	}
+	resp.Body = nil
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response of %s: %w", resp.Name, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, e := tx.Bucket(servicesBucket).CreateBucketIfNotExists([]byte(resp.Name))
+		if e != nil {
+			return fmt.Errorf("failed to create bucket for %s: %w", resp.Name, e)
+		}
+		if e = b.Put(timeKey(resp.CheckedAt), data); e != nil {
+			return fmt.Errorf("failed to save response of %s: %w", resp.Name, e)
+		}
+		return nil
+	})
+}
+
+// Responses returns recorded responses of the service checked within [from, to), ordered by time of the check
+func (s *Store) Responses(name string, from, to time.Time) ([]external.Response, error) {
+	res := []external.Response{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(servicesBucket).Bucket([]byte(name))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(timeKey(from)); k != nil && string(k) < string(timeKey(to)); k, v = c.Next() {
+			var resp external.Response
+			if err := json.Unmarshal(v, &resp); err != nil {
+				return fmt.Errorf("failed to unmarshal response of %s: %w", name, err)
+			}
+			res = append(res, resp)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// SLA returns availability report of the service for the period ending at now.
+// Time between consecutive checks is counted in the state of the earlier check, WARN is counted as available.
+// Checks made during maintenance windows and the time after them are excluded, outages continue through maintenance.
+// Returns ErrNotFound if the service has no checks in the period.
+func (s *Store) SLA(name string, period time.Duration, now time.Time) (SLA, error) {
+	from := now.Add(-period)
+	resps, err := s.Responses(name, from, now)
+	if err != nil {
+		return SLA{}, err
+	}
+	if len(resps) == 0 {
+		return SLA{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	res := SLA{Name: name, From: from, To: now}
+	var total, down, recovery, maintenance time.Duration
+	var downSince time.Time
+	recovered := 0
+	times := make([]int64, 0, len(resps))
+	for i, resp := range resps {
+		end := now
+		if i < len(resps)-1 {
+			end = resps[i+1].CheckedAt
+		}
+		if resp.Maintenance != "" {
+			maintenance += end.Sub(resp.CheckedAt)
+			continue
+		}
+		res.Checks++
+		times = append(times, resp.ResponseTime)
+		total += end.Sub(resp.CheckedAt)
+
+		isDown := resp.Health.Status == external.HealthDown
+		if isDown {
+			down += end.Sub(resp.CheckedAt)
+		}
+		switch {
+		case isDown && downSince.IsZero():
+			res.Outages++
+			downSince = resp.CheckedAt
+		case !isDown && !downSince.IsZero():
+			recovery += resp.CheckedAt.Sub(downSince)
+			recovered++
+			downSince = time.Time{}
+		}
+	}
+
+	res.Uptime = 100
+	if total > 0 {
+		res.Uptime = math.Round(float64(total-down)/float64(total)*100000) / 1000
+	}
+	if recovered > 0 {
+		res.MTTR = (recovery / time.Duration(recovered)).Milliseconds()
+	}
+	res.Maintenance = maintenance.Milliseconds()
+	slices.Sort(times)
+	res.ResponseTime = Percentiles{P50: percentile(times, 50), P95: percentile(times, 95), P99: percentile(times, 99)}
+	return res, nil
+}
+
+// Cleanup removes records older than retention, returns the number of removed records
+func (s *Store) Cleanup(now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := timeKey(now.Add(-s.retention))
+	removed := 0
+	cleanup := func(b *bolt.Bucket) error {
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && string(k) < string(cutoff); k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := cleanup(tx.Bucket(statusBucket)); err != nil {
+			return fmt.Errorf("failed to cleanup status: %w", err)
+		}
+		services := tx.Bucket(servicesBucket)
+		return services.ForEachBucket(func(name []byte) error {
+			if err := cleanup(services.Bucket(name)); err != nil {
+				return fmt.Errorf("failed to cleanup %s: %w", name, err)
+			}
+			return nil
+		})
+	})
+	return removed, err
+}
+
+// RunCleanup removes records older than retention each interval, until context is canceled
+func (s *Store) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		removed, err := s.Cleanup(time.Now())
+		if err != nil {
+			log.Printf("[WARN] store cleanup failed, %v", err)
+		}
+		if removed > 0 {
+			log.Printf("[DEBUG] store cleanup removed %d records", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// timeKey makes a key sorted by time
+func timeKey(t time.Time) []byte {
+	res := make([]byte, 8)
+	binary.BigEndian.PutUint64(res, uint64(max(t.UnixNano(), 0))) //nolint:gosec // negative times are not stored
+	return res
+}
+
+// percentile returns nearest-rank percentile of sorted values
+func percentile(sorted []int64, p int) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+	return sorted[min(max(idx, 0), len(sorted)-1)]
+}
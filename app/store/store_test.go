@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/umputun/sys-agent/app/status"
+	"github.com/umputun/sys-agent/app/status/external"
+)
+
+func TestStore_SaveAndResponses(t *testing.T) {
+	s := prepStore(t, 0)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		info := &status.Info{CPUPercent: 10 + i, ExtServices: map[string]external.Response{
+			"s1": {Name: "s1", StatusCode: 200, ResponseTime: 10, Body: map[string]any{"k": "v"}, CheckedAt: ts},
+			"s2": {Name: "s2", StatusCode: 500, ResponseTime: int64(i), CheckedAt: ts.Add(time.Duration(i) * time.Second)},
+			"s3": {Name: "s3"}, // not checked yet
+		}}
+		require.NoError(t, s.Save(info, ts.Add(time.Duration(i)*time.Second)))
+		for _, resp := range info.ExtServices {
+			require.NoError(t, s.SaveResponse(resp))
+		}
+	}
+
+	var samples []status.Info
+	require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(statusBucket).ForEach(func(_, v []byte) error {
+			var info status.Info
+			if err := json.Unmarshal(v, &info); err != nil {
+				return err
+			}
+			samples = append(samples, info)
+			return nil
+		})
+	}))
+	require.Len(t, samples, 3, "sample of each save")
+	assert.Equal(t, 12, samples[2].CPUPercent)
+	assert.Empty(t, samples[2].ExtServices, "services saved separately")
+
+	resps, err := s.Responses("s1", ts, ts.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, resps, 1, "same check saved once")
+	assert.Equal(t, 200, resps[0].StatusCode)
+	assert.Nil(t, resps[0].Body, "body not saved")
+	assert.True(t, ts.Equal(resps[0].CheckedAt))
+
+	resps, err = s.Responses("s2", ts, ts.Add(2*time.Second))
+	require.NoError(t, err)
+	require.Len(t, resps, 2, "to is excluded")
+	assert.Equal(t, int64(0), resps[0].ResponseTime)
+	assert.Equal(t, int64(1), resps[1].ResponseTime)
+
+	resps, err = s.Responses("s3", ts, ts.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, resps)
+}
+
+func TestStore_SLA(t *testing.T) {
+	s := prepStore(t, 0)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	checks := []struct {
+		offset time.Duration
+		status string
+		rt     int64
+	}{
+		{0, external.HealthUp, 10},
+		{10 * time.Minute, external.HealthDown, 20},
+		{20 * time.Minute, external.HealthDown, 30},
+		{30 * time.Minute, external.HealthWarn, 40},
+		{40 * time.Minute, external.HealthUp, 50},
+		{50 * time.Minute, external.HealthDown, 60},
+		{55 * time.Minute, external.HealthUp, 70},
+		{60 * time.Minute, external.HealthDown, 80},
+		{65 * time.Minute, external.HealthUp, 90},
+		{70 * time.Minute, external.HealthUp, 100},
+	}
+	for _, c := range checks {
+		resp := external.Response{Name: "s1", ResponseTime: c.rt, CheckedAt: ts.Add(c.offset), Health: external.Health{Status: c.status}}
+		require.NoError(t, s.SaveResponse(resp))
+	}
+
+	sla, err := s.SLA("s1", 2*time.Hour, ts.Add(80*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "s1", sla.Name)
+	assert.Equal(t, ts.Add(-40*time.Minute), sla.From)
+	assert.Equal(t, ts.Add(80*time.Minute), sla.To)
+	assert.Equal(t, 10, sla.Checks)
+	assert.InDelta(t, 62.5, sla.Uptime, 0.001, "30m down of 80m")
+	assert.Equal(t, 3, sla.Outages)
+	assert.Equal(t, (10 * time.Minute).Milliseconds(), sla.MTTR, "(20m + 5m + 5m) / 3")
+	assert.Equal(t, Percentiles{P50: 50, P95: 100, P99: 100}, sla.ResponseTime)
+
+	sla, err = s.SLA("s1", 25*time.Minute, ts.Add(80*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 4, sla.Checks, "only checks within the period")
+	assert.InDelta(t, 80, sla.Uptime, 0.001, "5m down of 25m")
+	assert.Equal(t, 1, sla.Outages, "outage at 60m")
+	assert.Equal(t, (5 * time.Minute).Milliseconds(), sla.MTTR)
+
+	_, err = s.SLA("s1", time.Hour, ts.Add(24*time.Hour))
+	require.ErrorIs(t, err, ErrNotFound, "no checks in the period")
+	_, err = s.SLA("unknown", time.Hour, ts.Add(time.Hour))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SLAOngoingOutage(t *testing.T) {
+	s := prepStore(t, 0)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i, st := range []string{external.HealthUp, external.HealthDown} {
+		resp := external.Response{Name: "s1", CheckedAt: ts.Add(time.Duration(i) * time.Minute), Health: external.Health{Status: st}}
+		require.NoError(t, s.SaveResponse(resp))
+	}
+
+	sla, err := s.SLA("s1", time.Hour, ts.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.InDelta(t, 25, sla.Uptime, 0.001)
+	assert.Equal(t, 1, sla.Outages)
+	assert.Equal(t, int64(0), sla.MTTR, "not recovered yet")
+}
+
+func TestStore_SLAMaintenance(t *testing.T) {
+	s := prepStore(t, 0)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	checks := []struct {
+		status, maintenance string
+	}{
+		{external.HealthUp, ""},
+		{external.HealthDown, "upgrade"},
+		{external.HealthDown, "upgrade"},
+		{external.HealthUp, ""},
+		{external.HealthDown, ""},
+	}
+	for i, c := range checks {
+		resp := external.Response{Name: "s1", ResponseTime: 10, CheckedAt: ts.Add(time.Duration(i) * 10 * time.Minute),
+			Health: external.Health{Status: c.status}, Maintenance: c.maintenance}
+		require.NoError(t, s.SaveResponse(resp))
+	}
+
+	sla, err := s.SLA("s1", time.Hour, ts.Add(50*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, sla.Checks, "checks in maintenance are not counted")
+	assert.InDelta(t, 66.667, sla.Uptime, 0.001, "10m down of 30m, downtime in maintenance excluded")
+	assert.Equal(t, 1, sla.Outages)
+	assert.Equal(t, (20 * time.Minute).Milliseconds(), sla.Maintenance)
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	s := prepStore(t, time.Hour)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		tm := ts.Add(time.Duration(i) * 30 * time.Minute)
+		resp := external.Response{Name: "s1", CheckedAt: tm, Health: external.Health{Status: external.HealthUp}}
+		require.NoError(t, s.SaveResponse(resp))
+		require.NoError(t, s.Save(&status.Info{CPUPercent: i}, tm))
+	}
+
+	removed, err := s.Cleanup(ts.Add(90 * time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed, "status sample and response older than 1h")
+	resps, err := s.Responses("s1", ts, ts.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, resps, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.RunCleanup(ctx, 10*time.Millisecond)
+	resps, err = s.Responses("s1", ts, ts.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, resps, "all records older than 1h from now")
+}
+
+func TestStore_CleanupNoRetention(t *testing.T) {
+	s := prepStore(t, 0)
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	resp := external.Response{Name: "s1", CheckedAt: ts}
+	require.NoError(t, s.SaveResponse(resp))
+	removed, err := s.Cleanup(ts.Add(24 * 365 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestStore_Reopen(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.db")
+	ts := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	s, err := New(fname, 0)
+	require.NoError(t, err)
+	resp := external.Response{Name: "s1", CheckedAt: ts}
+	require.NoError(t, s.SaveResponse(resp))
+	require.NoError(t, s.Close())
+
+	s, err = New(fname, 0)
+	require.NoError(t, err)
+	defer s.Close()
+	resps, err := s.Responses("s1", ts, ts.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, resps, 1)
+
+	_, err = New(filepath.Join(t.TempDir(), "no-such-dir", "test.db"), 0)
+	require.Error(t, err)
+}
+
+func prepStore(t *testing.T, retention time.Duration) *Store {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "test.db"), retention)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+	return s
+}
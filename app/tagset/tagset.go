@@ -0,0 +1,67 @@
+// Package tagset validates and joins tags and labels of volumes and services, shared by config, service providers,
+// actuator and metrics.
+package tagset
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// labelName is a valid label name, the same as prometheus label names
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are set by metrics of services and volumes, labels can't override them
+var reservedLabels = []string{"service", "volume", "path", "field", "period"}
+
+// Validate checks tags are not empty and have no commas, and label names are valid and not reserved
+func Validate(tags []string, labels map[string]string) error {
+	for _, t := range tags {
+		if t == "" || strings.Contains(t, ",") {
+			return fmt.Errorf("invalid tag %q, should be non-empty and without commas", t)
+		}
+	}
+	for name := range labels {
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name %q, should be letters, digits and underscores", name)
+		}
+		if slices.Contains(reservedLabels, name) {
+			return fmt.Errorf("label name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// Join returns tags with labels added as "name:value" pairs, so both are matched by a single value,
+// i.e. "payments" or "team:billing". Returns nil if there are no tags and labels.
+func Join(tags []string, labels map[string]string) []string {
+	if len(tags) == 0 && len(labels) == 0 {
+		return nil
+	}
+	res := slices.Clone(tags)
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		res = append(res, name+":"+labels[name])
+	}
+	return res
+}
+
+// Pairs returns key, value pairs followed by labels of the volume or service, sorted by name,
+// i.e. "service", "mongo", "team", "billing" for metric samples
+func Pairs(labels map[string]string, pairs ...string) []string {
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k, labels[k])
+	}
+	return pairs
+}
+
+// Map returns key, value pairs and labels of the volume or service as a map
+func Map(labels map[string]string, pairs ...string) map[string]string {
+	pairs = Pairs(labels, pairs...)
+	res := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		res[pairs[i]] = pairs[i+1]
+	}
+	return res
+}
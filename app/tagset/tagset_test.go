@@ -0,0 +1,52 @@
+package tagset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tbl := []struct {
+		name   string
+		tags   []string
+		labels map[string]string
+		err    string
+	}{
+		{name: "empty"},
+		{name: "valid", tags: []string{"payments", "critical"}, labels: map[string]string{"team": "billing", "env_1": ""}},
+		{name: "empty tag", tags: []string{"payments", ""}, err: `invalid tag "", should be non-empty and without commas`},
+		{name: "tag with comma", tags: []string{"a,b"}, err: `invalid tag "a,b", should be non-empty and without commas`},
+		{name: "invalid label", labels: map[string]string{"team-1": "x"}, err: `invalid label name "team-1"`},
+		{name: "internal label", labels: map[string]string{"__name__": "x"}, err: `invalid label name "__name__"`},
+		{name: "reserved label", labels: map[string]string{"volume": "x"}, err: `label name "volume" is reserved`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.tags, tt.labels)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	assert.Nil(t, Join(nil, nil))
+	assert.Equal(t, []string{"payments"}, Join([]string{"payments"}, nil))
+	assert.Equal(t, []string{"payments", "env:prod", "team:billing"},
+		Join([]string{"payments"}, map[string]string{"team": "billing", "env": "prod"}))
+}
+
+func TestPairs(t *testing.T) {
+	assert.Equal(t, []string{"service", "s1"}, Pairs(nil, "service", "s1"))
+	assert.Equal(t, []string{"volume", "root", "env", "prod", "team", "billing"},
+		Pairs(map[string]string{"team": "billing", "env": "prod"}, "volume", "root"))
+}
+
+func TestMap(t *testing.T) {
+	assert.Equal(t, map[string]string{}, Map(nil))
+	assert.Equal(t, map[string]string{"service": "s1", "team": "billing"}, Map(map[string]string{"team": "billing"}, "service", "s1"))
+}